@@ -38,7 +38,7 @@ const defaultClientTimeout = 5 * time.Second
 
 func (a *S3Accelerated) BucketWithDefaultConfig(name string) (b *Bucket) {
 	config := &Config{
-		Concurrency: 10,
+		Concurrency: defaultConcurrency,
 		PartSize:    20 * mb,
 		NTry:        10,
 		Md5Check:    true,
@@ -0,0 +1,127 @@
+package s3gof3r
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	aclHeader         = "X-Amz-Acl"
+	grantHeaderPrefix = "X-Amz-Grant-"
+)
+
+// Canned ACLs of objects
+// See https://docs.aws.amazon.com/AmazonS3/latest/userguide/acl-overview.html#canned-acl
+const (
+	ACLPrivate                = "private"
+	ACLPublicRead             = "public-read"
+	ACLPublicReadWrite        = "public-read-write"
+	ACLAuthenticatedRead      = "authenticated-read"
+	ACLAWSExecRead            = "aws-exec-read"
+	ACLBucketOwnerRead        = "bucket-owner-read"
+	ACLBucketOwnerFullControl = "bucket-owner-full-control"
+)
+
+var cannedACLs = map[string]bool{
+	ACLPrivate:                true,
+	ACLPublicRead:             true,
+	ACLPublicReadWrite:        true,
+	ACLAuthenticatedRead:      true,
+	ACLAWSExecRead:            true,
+	ACLBucketOwnerRead:        true,
+	ACLBucketOwnerFullControl: true,
+}
+
+// setACL sets the canned ACL header of h to acl, unless h already has an ACL or
+// grant headers, and validates the resulting ACL.
+func setACL(h http.Header, acl string) error {
+	var grants bool
+	for k := range h {
+		if strings.HasPrefix(http.CanonicalHeaderKey(k), grantHeaderPrefix) {
+			grants = true
+		}
+	}
+	if h.Get(aclHeader) == "" && !grants && acl != "" {
+		h.Set(aclHeader, acl)
+	}
+	if a := h.Get(aclHeader); a != "" {
+		if !cannedACLs[a] {
+			return fmt.Errorf("invalid canned ACL: %q", a)
+		}
+		if grants {
+			return fmt.Errorf("canned ACL %q can't be combined with x-amz-grant-* headers", a)
+		}
+	}
+	return nil
+}
+
+// ObjectACL is the access control list of an object: its owner, and the permissions
+// granted, e.g. FULL_CONTROL or READ.
+type ObjectACL struct {
+	Owner  Grantee
+	Grants []Grant `xml:"AccessControlList>Grant"`
+}
+
+// Grant is a permission of an ObjectACL
+type Grant struct {
+	Grantee    Grantee
+	Permission string
+}
+
+// Grantee is the grantee of a Grant: a user identified by its canonical ID or email
+// address, or a group identified by its URI.
+type Grantee struct {
+	Type         string `xml:"http://www.w3.org/2001/XMLSchema-instance type,attr"` // CanonicalUser, AmazonCustomerByEmail or Group
+	ID           string
+	DisplayName  string
+	EmailAddress string
+	URI          string
+}
+
+// GetObjectACL returns the access control list of the object at path.
+// As with GetReader, a version ID may be included in the path as a url parameter.
+func (b *Bucket) GetObjectACL(path string) (acl *ObjectACL, err error) {
+	u, err := b.subresourceURL(path, "acl")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.retryRequest("GET", u, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer checkClose(resp.Body, err)
+	if resp.StatusCode != 200 {
+		return nil, newRespError(resp)
+	}
+	acl = new(ObjectACL)
+	if err = xml.NewDecoder(resp.Body).Decode(acl); err != nil {
+		return nil, err
+	}
+	return acl, nil
+}
+
+// PutObjectACL replaces the access control list of the object at path with the canned
+// ACL acl, e.g. ACLBucketOwnerFullControl.
+// As with GetReader, a version ID may be included in the path as a url parameter.
+func (b *Bucket) PutObjectACL(path, acl string) (err error) {
+	if !cannedACLs[acl] {
+		return fmt.Errorf("invalid canned ACL: %q", acl)
+	}
+	u, err := b.subresourceURL(path, "acl")
+	if err != nil {
+		return err
+	}
+	h := http.Header{}
+	h.Set(aclHeader, acl)
+	resp, err := b.retryRequest("PUT", u, nil, h)
+	if err != nil {
+		return err
+	}
+	defer checkClose(resp.Body, err)
+	if resp.StatusCode != 200 {
+		return newRespError(resp)
+	}
+	return nil
+}
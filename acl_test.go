@@ -0,0 +1,115 @@
+package s3gof3r
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+var aclTests = []struct {
+	config string
+	header http.Header
+	expect string
+	valid  bool
+}{
+	{"", nil, "", true},
+	{ACLBucketOwnerFullControl, nil, "bucket-owner-full-control", true},
+	{ACLPrivate, http.Header{"X-Amz-Acl": {"public-read"}}, "public-read", true},
+	{ACLPublicRead, http.Header{"X-Amz-Grant-Read": {`id="abc"`}}, "", true},
+	{"everyone", nil, "", false},
+	{"", http.Header{"X-Amz-Acl": {"private"}, "X-Amz-Grant-Read": {`id="abc"`}}, "", false},
+}
+
+func TestPutWriterACL(t *testing.T) {
+	s := newFakeS3()
+	b := newTestBucket(t, s)
+	for _, tt := range aclTests {
+		b.Config.ACL = tt.config
+		h := http.Header{}
+		for k, v := range tt.header {
+			h[k] = v
+		}
+		w, err := b.PutWriter("key", h)
+		if !tt.valid {
+			if err == nil {
+				t.Errorf("expected error for ACL %q %v", tt.config, tt.header)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		s.mu.Lock()
+		got := s.headers["/bucket/key"].Get(aclHeader)
+		grant := s.headers["/bucket/key"].Get("X-Amz-Grant-Read")
+		s.mu.Unlock()
+		if got != tt.expect {
+			t.Errorf("Expected ACL: %q. Actual: %q", tt.expect, got)
+		}
+		if grant != tt.header.Get("X-Amz-Grant-Read") {
+			t.Errorf("Expected grant: %q. Actual: %q", tt.header.Get("X-Amz-Grant-Read"), grant)
+		}
+	}
+}
+
+func TestObjectACL(t *testing.T) {
+	var put string
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bucket/key" || !r.URL.Query().Has("acl") {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			return
+		}
+		switch r.Method {
+		case "PUT":
+			put = r.Header.Get(aclHeader)
+		case "GET":
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<AccessControlPolicy xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+	<Owner><ID>owner-id</ID><DisplayName>owner</DisplayName></Owner>
+	<AccessControlList>
+		<Grant>
+			<Grantee xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="CanonicalUser"><ID>owner-id</ID><DisplayName>owner</DisplayName></Grantee>
+			<Permission>FULL_CONTROL</Permission>
+		</Grant>
+		<Grant>
+			<Grantee xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="Group"><URI>http://acs.amazonaws.com/groups/global/AllUsers</URI></Grantee>
+			<Permission>READ</Permission>
+		</Grant>
+	</AccessControlList>
+</AccessControlPolicy>`)
+		}
+	}))
+
+	if err := b.PutObjectACL("key", ACLPublicRead); err != nil {
+		t.Fatal(err)
+	}
+	if put != "public-read" {
+		t.Errorf("Expected ACL: public-read. Actual: %q", put)
+	}
+	if err := b.PutObjectACL("key", "everyone"); err == nil {
+		t.Error("expected error for invalid canned ACL")
+	}
+
+	acl, err := b.GetObjectACL("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acl.Owner != (Grantee{ID: "owner-id", DisplayName: "owner"}) {
+		t.Errorf("unexpected owner: %+v", acl.Owner)
+	}
+	expect := []Grant{
+		{Grantee{Type: "CanonicalUser", ID: "owner-id", DisplayName: "owner"}, "FULL_CONTROL"},
+		{Grantee{Type: "Group", URI: "http://acs.amazonaws.com/groups/global/AllUsers"}, "READ"},
+	}
+	if len(acl.Grants) != len(expect) {
+		t.Fatalf("expected %d grants, got %d: %+v", len(expect), len(acl.Grants), acl.Grants)
+	}
+	for i, g := range acl.Grants {
+		if g != expect[i] {
+			t.Errorf("grant %d: expected %+v, got %+v", i, expect[i], g)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package s3gof3r
+
+import (
+	"errors"
+	"sync"
+)
+
+// adaptive limits the number of concurrent part requests of a get or put with
+// additive increase / multiplicative decrease (AIMD): the limit grows by one
+// after each round of limit successful requests and halves when S3 throttles
+// a request. A nil *adaptive does not limit.
+type adaptive struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	limit     int
+	min, max  int
+	active    int
+	successes int
+	logger    *internalLogger
+}
+
+// newAdaptive returns the adaptive concurrency of c, or nil if it is not enabled
+func newAdaptive(c *Config) *adaptive {
+	if c.MaxConcurrency <= 0 {
+		return nil
+	}
+	a := &adaptive{max: c.MaxConcurrency, logger: c.logger()}
+	a.min = min(max(c.MinConcurrency, 1), a.max)
+	a.limit = a.min
+	a.cond = sync.NewCond(&a.mu)
+	return a
+}
+
+// workers returns the number of workers for concurrency c, or the maximum of a
+func (a *adaptive) workers(c int) int {
+	if a == nil {
+		return max(c, 1)
+	}
+	return a.max
+}
+
+// acquire waits until a request may be made
+func (a *adaptive) acquire() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for a.active >= a.limit {
+		a.cond.Wait()
+	}
+	a.active++
+}
+
+// release ends a request acquired with acquire, adapting the limit to its error
+func (a *adaptive) release(err error) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.active--
+	switch {
+	case throttled(err):
+		a.limit = max(a.limit/2, a.min)
+		a.successes = 0
+		a.logger.debugPrintf("throttled, concurrency reduced to %d", a.limit)
+	case err == nil:
+		if a.successes++; a.successes >= a.limit && a.limit < a.max {
+			a.limit++
+			a.successes = 0
+		}
+	}
+	a.cond.Broadcast()
+}
+
+// throttled reports whether err is a 503 SlowDown response
+func throttled(err error) bool {
+	var rerr *RespError
+	return errors.As(err, &rerr) && (rerr.StatusCode == 503 || rerr.Code == "SlowDown")
+}
@@ -0,0 +1,100 @@
+package s3gof3r
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestAdaptive(t *testing.T) {
+	a := newAdaptive(&Config{MinConcurrency: 1, MaxConcurrency: 4})
+	slowDown := &RespError{Code: "SlowDown", StatusCode: 503}
+	var adaptiveTests = []struct {
+		err    error
+		expect int
+	}{
+		{nil, 2},
+		{nil, 2},
+		{nil, 3},
+		{nil, 3},
+		{nil, 3},
+		{nil, 4},
+		{nil, 4},
+		{slowDown, 2},
+		{&RespError{Code: "InternalError", StatusCode: 500}, 2},
+		{slowDown, 1},
+		{slowDown, 1},
+	}
+	for i, tt := range adaptiveTests {
+		a.acquire()
+		a.release(tt.err)
+		if a.limit != tt.expect {
+			t.Errorf("%d: Expected limit: %d. Actual: %d", i, tt.expect, a.limit)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		a.acquire()
+		a.release(nil)
+	}
+	if a.limit != 4 {
+		t.Errorf("expected limit capped at 4, got %d", a.limit)
+	}
+
+	if newAdaptive(&Config{Concurrency: 10}) != nil {
+		t.Error("expected adaptive concurrency disabled without MaxConcurrency")
+	}
+}
+
+func TestAdaptiveConcurrency(t *testing.T) {
+	s := newFakeS3()
+	var mu sync.Mutex
+	var active, maxActive, parts int
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("partNumber") == "" {
+			s.ServeHTTP(w, r)
+			return
+		}
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		parts++
+		throttle := parts%4 == 0
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			active--
+			mu.Unlock()
+		}()
+		if throttle {
+			http.Error(w, "SlowDown", 503)
+			return
+		}
+		s.ServeHTTP(w, r)
+	}))
+	b.Config.MaxConcurrency = 3
+	b.Config.NTry = 5
+	b.Config.Backoff = &Backoff{}
+
+	data := bytes.Repeat([]byte("a"), int(40*mb))
+	w, err := b.PutWriter("key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.object("/bucket/key"); !bytes.Equal(got, data) {
+		t.Error("put data differs")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if maxActive > 3 {
+		t.Errorf("expected at most 3 concurrent part requests, got %d", maxActive)
+	}
+}
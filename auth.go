@@ -3,23 +3,95 @@ package s3gof3r
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"os"
+	"sync"
 	"time"
 )
 
+// refreshWindow is how long before their expiration refreshable keys are renewed.
+const refreshWindow = 5 * time.Minute
+
 // Keys for an Amazon Web Services account.
 // Used for signing http requests.
+// Keys are safe for concurrent use.
 type Keys struct {
+	mu              sync.RWMutex
 	accessKeyID     string
 	secretAccessKey string
 	sessionToken    string
+
+	expiration time.Time             // zero if the keys do not expire
+	refresh    func() (*Keys, error) // nil if the keys can not be renewed
+	provider   Provider              // of keys returned by ProviderKeys, which expire when it reports
+}
+
+func (k *Keys) AccessKeyID() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.accessKeyID
+}
+
+func (k *Keys) SecretAccessKey() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.secretAccessKey
+}
+
+func (k *Keys) SessionToken() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.sessionToken
+}
+
+// Expiration returns the time temporary keys expire. It is zero for keys that do not expire.
+func (k *Keys) Expiration() time.Time {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.expiration
+}
+
+// Credentials returns a consistent snapshot of the keys for signing a request.
+//
+// Refreshable keys, such as those returned by InstanceKeys, are renewed first if they
+// expire within a few minutes. If renewal fails the current keys are returned.
+func (k *Keys) Credentials() (accessKeyID, secretAccessKey, sessionToken string) {
+	k.refreshIfExpiring()
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.accessKeyID, k.secretAccessKey, k.sessionToken
 }
 
-func (k *Keys) AccessKeyID() string     { return k.accessKeyID }
-func (k *Keys) SecretAccessKey() string { return k.secretAccessKey }
-func (k *Keys) SessionToken() string    { return k.sessionToken }
+func (k *Keys) expiring() bool {
+	if k.provider != nil {
+		return k.provider.IsExpired()
+	}
+	return k.refresh != nil && !k.expiration.IsZero() && time.Until(k.expiration) < refreshWindow
+}
+
+func (k *Keys) refreshIfExpiring() {
+	k.mu.RLock()
+	expiring := k.expiring()
+	k.mu.RUnlock()
+	if !expiring {
+		return
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if !k.expiring() { // renewed by another goroutine
+		return
+	}
+	nk, err := k.refresh()
+	if err != nil {
+		logger.Printf("Error refreshing keys: %v\n", err)
+		return
+	}
+	k.accessKeyID = nk.accessKeyID
+	k.secretAccessKey = nk.secretAccessKey
+	k.sessionToken = nk.sessionToken
+	k.expiration = nk.expiration
+	logger.debugPrintf("keys refreshed, expiration: %v", k.expiration)
+}
 
 type mdCreds struct {
 	Code            string
@@ -31,46 +103,50 @@ type mdCreds struct {
 	Expiration      string
 }
 
+const instanceCredentialsPath = "/latest/meta-data/iam/security-credentials/"
+
 // InstanceKeys Requests the AWS keys from the instance-based metadata on EC2
 // Assumes only one IAM role.
+//
+// IMDSv2 session tokens are used when available, falling back to IMDSv1.
+// The keys are temporary and are automatically renewed from the instance metadata
+// shortly before they expire.
 func InstanceKeys() (keys *Keys, err error) {
-
-	rolePath := "http://169.254.169.254/latest/meta-data/iam/security-credentials/"
-	var creds mdCreds
-
-	// request the role name for the instance
-	// assumes there is only one
-	resp, err := ClientWithTimeout(2 * time.Second).Get(rolePath)
+	keys, err = instanceKeys()
 	if err != nil {
 		return
 	}
-	defer checkClose(resp.Body, err)
-	if resp.StatusCode != 200 {
-		err = newRespError(resp)
-		return
-	}
-	role, err := ioutil.ReadAll(resp.Body)
+	keys.refresh = instanceKeys
+	return
+}
 
+func instanceKeys() (keys *Keys, err error) {
+	md, err := newMetadataClient()
 	if err != nil {
 		return
 	}
 
-	// request the credential metadata for the role
-	resp, err = http.Get(rolePath + string(role))
+	// request the role name for the instance
+	// assumes there is only one
+	role, err := md.get(instanceCredentialsPath)
 	if err != nil {
 		return
 	}
-	defer checkClose(resp.Body, err)
-	if resp.StatusCode != 200 {
-		err = newRespError(resp)
-		return
-	}
-	metadata, err := ioutil.ReadAll(resp.Body)
+
+	// request the credential metadata for the role
+	metadata, err := md.get(instanceCredentialsPath + string(role))
 	if err != nil {
 		return
 	}
 
-	if err = json.Unmarshal([]byte(metadata), &creds); err != nil {
+	return credentialKeys(metadata)
+}
+
+// credentialKeys parses keys from a json credential document as served by
+// the EC2 instance metadata and ECS container credential endpoints.
+func credentialKeys(b []byte) (keys *Keys, err error) {
+	var creds mdCreds
+	if err = json.Unmarshal(b, &creds); err != nil {
 		return
 	}
 	keys = &Keys{
@@ -78,7 +154,11 @@ func InstanceKeys() (keys *Keys, err error) {
 		secretAccessKey: creds.SecretAccessKey,
 		sessionToken:    creds.Token,
 	}
-
+	if creds.Expiration != "" {
+		if keys.expiration, err = time.Parse(time.RFC3339, creds.Expiration); err != nil {
+			return nil, err
+		}
+	}
 	return
 }
 
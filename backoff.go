@@ -0,0 +1,124 @@
+package s3gof3r
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxRetryAfter caps the delays requested by Retry-After headers
+const maxRetryAfter = 30 * time.Second
+
+// Backoff is the exponential back-off between retries of failed requests.
+type Backoff struct {
+	Initial    time.Duration // delay after the first failed attempt
+	Max        time.Duration // maximum delay, ignored if zero
+	Multiplier float64       // factor the delay grows by after each failed attempt
+	Jitter     bool          // "full jitter": wait a random duration up to the delay
+}
+
+// DefaultBackoff is used if Config.Backoff is nil
+var DefaultBackoff = &Backoff{
+	Initial:    100 * time.Millisecond,
+	Max:        5 * time.Second,
+	Multiplier: 2,
+	Jitter:     true,
+}
+
+// Delay returns the delay after the failed attempt, starting from 0. Without a Max,
+// it's capped at the longest time.Duration.
+func (b *Backoff) Delay(attempt int) time.Duration {
+	d := float64(b.Initial) * math.Pow(math.Max(b.Multiplier, 1), float64(attempt))
+	if b.Max > 0 && d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+	n := int64(math.MaxInt64)
+	if d < float64(math.MaxInt64) { // not converted past int64, where it would wrap
+		n = int64(d)
+	}
+	if b.Jitter && n >= 1 {
+		n = rand.Int63n(n)
+	}
+	return time.Duration(n)
+}
+
+// retry makes up to ntry attempts, at least one, calling attempt with the number of
+// each attempt from 0, until one succeeds or fails with an error it doesn't report as
+// retryable. The delay given by delay for the failed attempt is waited before the next
+// one, but not after the last. It stops with the error of ctx once it's done.
+func retry(ctx context.Context, ntry int, delay func(attempt int, err error) time.Duration, attempt func(attempt int) (retryable bool, err error)) error {
+	for i := 0; ; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		retryable, err := attempt(i)
+		if err == nil || !retryable || i >= ntry-1 {
+			return err
+		}
+		sleep(ctx, delay(i, err))
+	}
+}
+
+// retryableStatus reports whether requests failed with status are retried by gets,
+// puts and RetryTransport: 500 InternalError and 503 SlowDown
+func retryableStatus(status int) bool {
+	return status == 500 || status == 503
+}
+
+// backoff returns the delay after the failed attempt of c's Backoff
+func (c *Config) backoff(attempt int) time.Duration {
+	return backoffDelay(c.Backoff, attempt, 0)
+}
+
+// retryDelay returns the delay after the failed attempt with err. The Retry-After
+// of error responses, such as 503 SlowDown, is honored, up to maxRetryAfter.
+func (c *Config) retryDelay(attempt int, err error) time.Duration {
+	return retryDelay(c.Backoff, attempt, err)
+}
+
+// retryDelay returns the delay of b after the failed attempt with err, as for
+// Config.retryDelay
+func retryDelay(b *Backoff, attempt int, err error) time.Duration {
+	var rerr *RespError
+	if errors.As(err, &rerr) {
+		return backoffDelay(b, attempt, rerr.retryAfter)
+	}
+	return backoffDelay(b, attempt, 0)
+}
+
+// backoffDelay returns the delay after the failed attempt of b, or DefaultBackoff if
+// b is nil, or the delay of retryAfter, if set, up to maxRetryAfter.
+func backoffDelay(b *Backoff, attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		if retryAfter > maxRetryAfter {
+			return maxRetryAfter
+		}
+		return retryAfter
+	}
+	if b == nil {
+		b = DefaultBackoff
+	}
+	return b.Delay(attempt)
+}
+
+// parseRetryAfter returns the delay of a Retry-After header value v of
+// seconds or an HTTP-date, or 0 if v is empty or invalid.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(v); err == nil {
+		if s < 0 {
+			return 0
+		}
+		return time.Duration(s) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,151 @@
+package s3gof3r
+
+import (
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var backoffTests = []struct {
+	backoff Backoff
+	attempt int
+	expect  time.Duration
+}{
+	{Backoff{Initial: 100 * time.Millisecond, Multiplier: 2}, 0, 100 * time.Millisecond},
+	{Backoff{Initial: 100 * time.Millisecond, Multiplier: 2}, 3, 800 * time.Millisecond},
+	{Backoff{Initial: 100 * time.Millisecond, Multiplier: 2, Max: time.Second}, 5, time.Second},
+	{Backoff{Initial: time.Second, Multiplier: 0}, 4, time.Second},
+	{Backoff{}, 2, 0},
+	{Backoff{Initial: 100 * time.Millisecond, Multiplier: 2}, 40, time.Duration(math.MaxInt64)},
+	{Backoff{Initial: 100 * time.Millisecond, Multiplier: 2}, 5000, time.Duration(math.MaxInt64)},
+}
+
+func TestBackoffDelay(t *testing.T) {
+	for _, tt := range backoffTests {
+		if d := tt.backoff.Delay(tt.attempt); d != tt.expect {
+			t.Errorf("%+v attempt %d: Expected delay: %s. Actual: %s", tt.backoff, tt.attempt, tt.expect, d)
+		}
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	b := Backoff{Initial: 100 * time.Millisecond, Multiplier: 2, Max: time.Second, Jitter: true}
+	var differ bool
+	for i := 0; i < 100; i++ {
+		d := b.Delay(10)
+		if d < 0 || d >= time.Second {
+			t.Fatalf("jittered delay %s out of range [0, 1s)", d)
+		}
+		differ = differ || d != b.Delay(10)
+	}
+	if !differ {
+		t.Error("expected jittered delays to differ")
+	}
+
+	// without a Max, the delays of late attempts don't overflow
+	b = Backoff{Initial: 100 * time.Millisecond, Multiplier: 2, Jitter: true}
+	for _, attempt := range []int{37, 40, 64, 5000} {
+		if d := b.Delay(attempt); d < 0 {
+			t.Errorf("attempt %d: Expected delay: >= 0. Actual: %s", attempt, d)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var retryAfterTests = []struct {
+		value  string
+		expect time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"-1", 0},
+		{"soon", 0},
+		{now.Add(10 * time.Second).Format(http.TimeFormat), 10 * time.Second},
+		{now.Add(-10 * time.Second).Format(http.TimeFormat), 0},
+	}
+	for _, tt := range retryAfterTests {
+		if d := parseRetryAfter(tt.value, now); d != tt.expect {
+			t.Errorf("%q: Expected delay: %s. Actual: %s", tt.value, tt.expect, d)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	s := newFakeS3()
+	var mu sync.Mutex
+	var slowed []time.Time
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			mu.Lock()
+			slowed = append(slowed, time.Now())
+			n := len(slowed)
+			mu.Unlock()
+			if n == 1 {
+				io.Copy(ioutil.Discard, r.Body)
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, "SlowDown", 503)
+				return
+			}
+		}
+		s.ServeHTTP(w, r)
+	}))
+	b.Config.Backoff = &Backoff{Initial: time.Millisecond}
+
+	w, err := b.PutWriter("key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(slowed) != 2 {
+		t.Fatalf("expected 2 put attempts, got %d", len(slowed))
+	}
+	if d := slowed[1].Sub(slowed[0]); d < time.Second {
+		t.Errorf("expected retry after 1s, retried after %s", d)
+	}
+}
+
+// TestRetryAfterLastAttempt checks that the requests of buckets, gets and puts are
+// not delayed by the Retry-After of their last attempt before failing.
+func TestRetryAfterLastAttempt(t *testing.T) {
+	s := newFakeS3()
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" || r.Method == "GET" || strings.HasPrefix(r.URL.Path, "/bucket/.md5/") {
+			io.Copy(ioutil.Discard, r.Body)
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "SlowDown", 503)
+			return
+		}
+		s.ServeHTTP(w, r)
+	}))
+	b.Config.Md5Check = true
+	for _, tt := range []struct {
+		name string
+		do   func() error
+	}{
+		{"head", func() error { _, err := b.Head("key"); return err }},
+		{"get", func() error { _, _, err := b.GetReader("key"); return err }},
+		{"md5 put", func() error { return b.PutBytes("key", []byte("data"), nil) }},
+	} {
+		start := time.Now()
+		if err := tt.do(); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+		// one delay between the 2 attempts
+		if d := time.Since(start); d < time.Second || d > 1900*time.Millisecond {
+			t.Errorf("%s: expected failure after 1s. Actual: %s", tt.name, d)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package s3gof3r
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -8,6 +10,7 @@ import (
 	"net/url"
 	"path"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -25,22 +28,182 @@ type S3ConfigSource interface {
 type Config struct {
 	Client      *http.Client // http client to use for requests
 	Concurrency int          // number of parts to get or put concurrently
-	PartSize    int64        // initial  part size in bytes to use for multipart gets or puts
+	PartSize    int64        // initial  part size in bytes to use for multipart gets or puts, from 5 MB to 5 GB for puts
 	NTry        int          // maximum attempts for each part
 	Md5Check    bool         // The md5 hash of the object is stored in <bucket>/.md5/<object_key>.md5
-	// When true, it is stored on puts and verified on gets
+	// When true, it is stored on puts, once the object is, and verified on gets, or verified
+	// against the ETag of objects without an md5 file if the ETag is an md5, as for single
+	// part puts. Puts whose md5 file fails to be stored return ErrMd5NotStored
 	Scheme    string // url scheme, defaults to 'https'
 	PathStyle bool   // use path style bucket addressing instead of virtual host style
+	DualStack bool   // use the dual-stack (IPv4 and IPv6) endpoints of AWS S3 domains
+
+	// SSECustomerKey is the 256-bit key for server-side encryption with a customer-provided
+	// key (SSE-C) of objects put and got. It is sent with every part and ranged get request.
+	SSECustomerKey []byte
+
+	// SSEAlgorithm is the server-side encryption of objects put with keys managed by S3
+	// or KMS, e.g. SSEAlgorithmKMS, with the KMS key of SSEKMSKeyID, or the default KMS
+	// key of the account if empty. SSEBucketKeyEnabled uses an S3 Bucket Key, reducing
+	// the requests to KMS. They are sent on initiation of multipart uploads, and the
+	// x-amz-server-side-encryption headers passed to PutWriter take precedence.
+	SSEAlgorithm        string
+	SSEKMSKeyID         string
+	SSEBucketKeyEnabled bool
+
+	// StorageClass of objects put, e.g. StorageClassStandardIA. Defaults to STANDARD.
+	// An x-amz-storage-class header passed to PutWriter takes precedence.
+	StorageClass string
+
+	// DryRun makes Delete, DeleteVersion, DeleteMultiple, DeleteAll and DeletePrefix send
+	// no delete requests, to preview what they would delete: DeleteMultiple and DeleteAll
+	// return every key in the Deleted of their result, quiet or not, including the md5
+	// files of the keys with Md5Check, and DeletePrefix lists the keys and returns their
+	// number. Other requests are sent as usual.
+	DryRun bool
+
+	// SigningRegion and SigningService, if set, are the region and service requests are
+	// signed for, instead of the region of the bucket and "s3", for S3-compatible
+	// services and gateways expecting a fixed signing region, such as us-east-1, or
+	// another service name. They also apply to the urls of PresignGet and PresignPut,
+	// which are only valid for the service they are signed for.
+	SigningRegion  string
+	SigningService string
+
+	// Endpoint, if set, is the url of the S3-compatible service requests are sent to,
+	// including its scheme and any path prefix, e.g. https://gateway.example.com/s3.
+	// Objects are addressed in path style under it, as <Endpoint>/<bucket>/<key>,
+	// regardless of Scheme, PathStyle, DualStack and the domain of the S3 service, and
+	// requests are not sent to the endpoints S3 redirects buckets to.
+	Endpoint string
+
+	// ExpectedBucketOwner, if set, is the id of the AWS account expected to own the
+	// bucket. It's sent, and signed, with each request as x-amz-expected-bucket-owner,
+	// and as x-amz-source-expected-bucket-owner of copies from the bucket, such as by
+	// CopyFrom, so that S3 rejects requests to buckets owned by another account with
+	// ErrBucketOwnerMismatch. Headers passed to PutWriter or Copy take precedence. It's
+	// not added to presigned urls.
+	ExpectedBucketOwner string
+
+	// ACL is the canned ACL of objects put, e.g. ACLBucketOwnerFullControl. An x-amz-acl
+	// header passed to PutWriter takes precedence, as do x-amz-grant-* headers such as
+	// x-amz-grant-read, for explicit grants. Objects are private to the bucket owner by default.
+	ACL string
+
+	// DetectContentType sets the Content-Type of puts without one from the extension of the
+	// key or, if the extension is unknown, by sniffing the first 512 bytes of the data.
+	// Sniffing delays initiating the upload, and its errors, until the first part is written.
+	DetectContentType bool
+
+	// Puts double their part size every PartSizeGrowthParts parts (2000 if zero), while
+	// needed to fit a 5 TB object in the 10000 parts allowed by S3, up to MaxPartSize
+	// (5 GB if zero). The largest object that can be put is given by MaxObjectSize, and
+	// writes of larger objects fail at the 10000th part.
+	MaxPartSize         int64
+	PartSizeGrowthParts int
+
+	// DecompressGzip decompresses the data read from gets of whole objects with a
+	// Content-Encoding of gzip. The md5 and checksums are verified for the stored bytes.
+	DecompressGzip bool
+
+	// CompressGzip compresses the data written to puts with gzip, and sets their
+	// Content-Encoding to gzip. The md5 and checksums are of the compressed bytes stored.
+	// Puts with a Content-Encoding header, such as of data already compressed, are not
+	// compressed. The Content-Type of compressed puts is not sniffed.
+	CompressGzip bool
+
+	// UserAgent, if set, is appended to the User-Agent of requests, "S3Gof3r", such
+	// as "myapp/1.2" for "S3Gof3r myapp/1.2".
+	UserAgent string
+
+	// DefaultHeaders are added to each request, and signed, unless the request has the
+	// header already, e.g. from the headers passed to PutWriter. They are not added to
+	// presigned urls.
+	DefaultHeaders http.Header
+
+	// Progress, if set, is called as each part of a get or put completes.
+	Progress ProgressFunc
+
+	// Logger, if set, logs the output of the bucket instead of the global logger of
+	// SetLogger, with debug output through its Debugf.
+	Logger Logger
+
+	// RequestObserver, if set, is notified of each request to S3.
+	RequestObserver RequestObserver
+
+	// PartObserver, if set, is notified as each part of a get or put starts, completes
+	// or fails.
+	PartObserver PartObserver
+
+	// MaxBytesPerSec limits the aggregate throughput of the part requests of each get or put.
+	// Zero means unlimited.
+	MaxBytesPerSec int64
+
+	// ChecksumAlgorithm, if set, is the algorithm of checksums sent with each part of puts
+	// and validated by S3, one of ChecksumCRC32, ChecksumCRC32C, ChecksumSHA1 or ChecksumSHA256.
+	// Gets of whole objects verify the checksums of each part. Unlike Md5Check, no extra
+	// objects are stored, so Md5Check may be disabled.
+	ChecksumAlgorithm string
+
+	// MaxBufferedParts, if set, is the most parts of a get held in memory at a time,
+	// being got or waiting to be read, bounding how far ahead of the reader the get
+	// reads. As Read returns the data in order, parts got before earlier parts are held
+	// until those are read, so a get holds up to about 2 × Concurrency × PartSize bytes
+	// for a slow reader, and at most MaxBufferedParts × PartSize if set. Fewer parts than
+	// Concurrency also limit the concurrent part requests. The parts of gets verifying the
+	// checksums of each part are the parts of the object, of up to 8 × PartSize.
+	MaxBufferedParts int
+
+	// StreamingSignature signs the data of each part of puts chunk by chunk as it's sent,
+	// with STREAMING-AWS4-HMAC-SHA256-PAYLOAD and the aws-chunked encoding, instead of
+	// hashing the whole part with SHA-256 before sending it. It saves a pass over each
+	// part before its request starts, for a slightly larger request body.
+	StreamingSignature bool
+
+	// UnsignedPayload signs requests over HTTPS, whose body is already protected by TLS,
+	// with UNSIGNED-PAYLOAD instead of the SHA-256 of their body, so that parts are not
+	// hashed with SHA-256 before they are sent; their integrity is still checked with
+	// their MD5 (and checksum, if any). It takes precedence over StreamingSignature for
+	// these requests. Requests over HTTP are always signed with the SHA-256 of their body.
+	UnsignedPayload bool
+
+	// Backoff between retries of failed requests. DefaultBackoff is used if nil.
+	Backoff *Backoff
+
+	// MaxConcurrency, if set, enables adaptive concurrency instead of Concurrency: gets
+	// and puts start with MinConcurrency part requests at a time, adding one after each
+	// round of successful requests and halving on 503 SlowDown, up to MaxConcurrency.
+	MinConcurrency int
+	MaxConcurrency int
+
+	// AutoRegion corrects the signing region of requests rejected by S3 with a 400
+	// AuthorizationHeaderMalformed error for the wrong region, like the 301 redirects
+	// to the region of the bucket which are always followed. The request is retried
+	// once, and later requests are signed for the correct region.
+	AutoRegion bool
 }
 
 // A Bucket for an S3 service.
+//
+// A Bucket is safe for concurrent use by multiple goroutines, as long as its Config is
+// not changed while it's in use. Buckets sharing a Config, as given to NewBucket, share
+// its changes: use a Config for each bucket configured differently.
 type Bucket struct {
 	S3     S3ConfigSource
 	Name   string
 	Config *Config
+
+	regionMu sync.RWMutex
+	region   string // region found by Region or redirects, used to sign requests
+	endpoint string // host S3 redirected requests to, if any
 }
 
+// NewBucket returns the bucket name of s3 with config, or a copy of DefaultConfig if
+// config is nil.
 func NewBucket(s3 S3ConfigSource, name string, config *Config) (bucket *Bucket, err error) {
+	if config == nil {
+		config = DefaultConfig.clone()
+	}
 	bucket = &Bucket{
 		S3:     s3,
 		Name:   name,
@@ -50,9 +213,35 @@ func NewBucket(s3 S3ConfigSource, name string, config *Config) (bucket *Bucket,
 	return
 }
 
+// userAgent is the User-Agent of requests, including of the requests for credentials
+const userAgent = "S3Gof3r"
+
+func (c *Config) userAgent() string {
+	if c.UserAgent == "" {
+		return userAgent
+	}
+	return userAgent + " " + c.UserAgent
+}
+
 // Do conveniently proxies through to the configured http client.
+// ErrNoRegion is returned, without sending req, if the region of the bucket is unknown,
+// and ErrNoCredentials if its keys are empty.
 func (b *Bucket) Do(req *http.Request) (*http.Response, error) {
-	return b.Config.Client.Do(req)
+	if err := b.checkRequest(); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	var resp *http.Response
+	var err error
+	if o := b.Config.RequestObserver; o != nil {
+		resp, err = b.observe(o, req)
+	} else {
+		resp, err = b.Config.Client.Do(req)
+	}
+	if l := b.Config.logger(); l.debug {
+		logRequest(l, req, resp, err, time.Since(start))
+	}
+	return resp, err
 }
 
 // GetReader provides a reader and downloads data using parallel ranged get requests.
@@ -60,11 +249,36 @@ func (b *Bucket) Do(req *http.Request) (*http.Response, error) {
 //
 // Data integrity is verified via the option specified in c.
 // Header data from the downloaded object is also returned, useful for reading object metadata.
+// Its Content-Length is the size of the whole object, not of a ranged part.
 // DefaultConfig is used if c is nil
 // Callers should call Close on r to ensure that all resources are released.
 //
 // To specify an object version in a versioned bucket, the version ID may be included in the path as a url parameter. See http://docs.aws.amazon.com/AmazonS3/latest/dev/RetrievingObjectVersions.html
 func (b *Bucket) GetReader(path string) (r io.ReadCloser, h http.Header, err error) {
+	return b.GetReaderContext(context.Background(), path)
+}
+
+// GetReaderContext is like GetReader but binds every request made by the reader to ctx.
+//
+// If ctx is cancelled, outstanding part requests are aborted, no further retries are
+// attempted and Read and Close return ctx.Err().
+func (b *Bucket) GetReaderContext(ctx context.Context, path string) (r io.ReadCloser, h http.Header, err error) {
+	if path == "" {
+		return nil, nil, errors.New("empty path requested")
+	}
+	u, err := b.url(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newGetter(ctx, *u, b, getOptions{length: -1})
+}
+
+// GetReaderWithHeader is like GetReader but adds each header in h to the requests.
+//
+// Conditional headers such as If-None-Match and If-Modified-Since are checked by the
+// first request, before any parts are downloaded. If the condition is not met,
+// ErrNotModified is returned with the response headers.
+func (b *Bucket) GetReaderWithHeader(path string, h http.Header) (r io.ReadCloser, rh http.Header, err error) {
 	if path == "" {
 		return nil, nil, errors.New("empty path requested")
 	}
@@ -72,21 +286,176 @@ func (b *Bucket) GetReader(path string) (r io.ReadCloser, h http.Header, err err
 	if err != nil {
 		return nil, nil, err
 	}
-	return newGetter(*u, b)
+	return newGetter(context.Background(), *u, b, getOptions{length: -1, header: h})
+}
+
+// GetRangeReader is like GetReader but gets only length bytes of the object starting
+// at offset, using parallel ranged get requests within that range. A length of -1
+// gets the object from offset to its end.
+//
+// The md5 hash of the object is not verified for ranges. The Content-Length of h is
+// the length of the range, and the size of the object is in its Content-Range.
+func (b *Bucket) GetRangeReader(path string, offset, length int64) (r io.ReadCloser, h http.Header, err error) {
+	if path == "" {
+		return nil, nil, errors.New("empty path requested")
+	}
+	if offset < 0 || length == 0 || length < -1 {
+		return nil, nil, fmt.Errorf("invalid range: offset %d, length %d", offset, length)
+	}
+	u, err := b.url(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newGetter(context.Background(), *u, b, getOptions{offset: offset, length: length})
 }
 
 // PutWriter provides a writer to upload data as multipart upload requests.
+// Data smaller than PartSize is uploaded with a single PUT request on Close instead.
+// As no request is sent until the first part is full, errors such as a missing bucket
+// are returned by Write or Close.
 //
 // Each header in h is added to the HTTP request header. This is useful for specifying
 // options such as server-side encryption in metadata as well as custom user metadata.
 // Callers should call Close on w to ensure that all resources are released.
+// w is a MultipartWriter.
+//
+// An If-Match header with the ETag of the object, or If-None-Match of "*", makes the
+// put conditional on the object being unchanged, or absent. The precondition is checked
+// by S3 when the object is put, and Close returns ErrPreconditionFailed if it is not met.
 func (b *Bucket) PutWriter(path string, h http.Header) (w io.WriteCloser, err error) {
+	return b.PutWriterContext(context.Background(), path, h)
+}
+
+// PutWriterContext is like PutWriter but binds every request made by the writer to ctx.
+//
+// If ctx is cancelled, outstanding part uploads are aborted, no further retries are
+// attempted, the multipart upload is aborted and Write and Close return ctx.Err().
+func (b *Bucket) PutWriterContext(ctx context.Context, path string, h http.Header) (w io.WriteCloser, err error) {
+	u, err := b.url(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPutter(ctx, *u, h, b)
+}
+
+// PutOptions are the content headers of an object put with PutWriterOptions, such as
+// a Content-Disposition of `attachment; filename="report.csv"` for browsers to save
+// it as report.csv, and a Cache-Control of "max-age=3600". They are stored with the
+// object and returned with its gets. Options that are empty are not set.
+type PutOptions struct {
+	ContentType        string
+	ContentDisposition string
+	CacheControl       string
+	ContentEncoding    string // of data already encoded, e.g. gzip, which is not compressed by CompressGzip
+	Expires            time.Time
+}
+
+// setHeaders sets the headers of the options set in h
+func (o PutOptions) setHeaders(h http.Header) {
+	for k, v := range map[string]string{
+		"Content-Type":        o.ContentType,
+		"Content-Disposition": o.ContentDisposition,
+		"Cache-Control":       o.CacheControl,
+		"Content-Encoding":    o.ContentEncoding,
+	} {
+		if v != "" {
+			h.Set(k, v)
+		}
+	}
+	if !o.Expires.IsZero() {
+		h.Set("Expires", o.Expires.UTC().Format(http.TimeFormat))
+	}
+}
+
+// PutWriterOptions is like PutWriter but sets the content headers of the object from
+// o, which take precedence over the same headers in h.
+func (b *Bucket) PutWriterOptions(path string, h http.Header, o PutOptions) (w io.WriteCloser, err error) {
+	h = cloneHeader(h)
+	o.setHeaders(h)
+	return b.PutWriter(path, h)
+}
+
+// A MultipartWriter is the writer returned by PutWriter and ResumePutWriter.
+// The UploadID of an interrupted upload can be persisted to resume it later.
+//
+// Callers must call exactly one of Close, to complete the upload, or Abort, to discard it.
+// The Result of a completed upload identifies the object put.
+type MultipartWriter interface {
+	io.WriteCloser
+	UploadID() string
+	Abort() error
+	Result() PutResult
+}
+
+// ResumePutWriter is like PutWriter but continues the multipart upload with uploadID,
+// such as the UploadID of the MultipartWriter of an interrupted upload, instead of
+// initiating a new one. The headers of the upload were set when it was initiated.
+//
+// All of the data must be written again, with the same PartSize, for the md5 of the
+// object. Parts that were uploaded with the same size and md5 are not uploaded again,
+// per ListParts. Parts of SSE-C uploads cannot be matched, so they are all uploaded.
+func (b *Bucket) ResumePutWriter(path string, h http.Header, uploadID string) (w io.WriteCloser, err error) {
+	if uploadID == "" {
+		return nil, errors.New("empty upload ID")
+	}
 	u, err := b.url(path)
 	if err != nil {
 		return nil, err
 	}
+	parts, err := b.listParts(u, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := newPutter(context.Background(), *u, h, b)
+	if err != nil {
+		return nil, err
+	}
+	p.resume(uploadID, parts)
+	return p, nil
+}
+
+// pathStyle reports whether the bucket name is in the path of urls instead of the host
+func (b *Bucket) pathStyle() bool {
+	// handling for bucket names containing periods / explicit PathStyle addressing
+	// http://docs.aws.amazon.com/AmazonS3/latest/dev/BucketRestrictions.html for details
+	return strings.Contains(b.Name, ".") || b.Config.PathStyle
+}
+
+// objectKey returns the key of the object at u, a url returned by b.url
+func (b *Bucket) objectKey(u url.URL) string {
+	return strings.TrimPrefix(strings.TrimPrefix(u.Path, b.pathPrefix()), "/")
+}
+
+// pathPrefix returns the path of the urls of the bucket before the keys of objects:
+// the path of the Endpoint, if any, then the bucket name, for path style urls
+func (b *Bucket) pathPrefix() string {
+	if b.Config.Endpoint != "" {
+		e, _ := b.endpointURL()
+		return path.Clean(fmt.Sprintf("/%s/%s", e.Path, b.Name))
+	}
+	if b.pathStyle() {
+		return "/" + b.Name
+	}
+	return ""
+}
+
+// endpointURL returns the parsed Endpoint of the config
+func (b *Bucket) endpointURL() (*url.URL, error) {
+	e, err := url.Parse(b.Config.Endpoint)
+	if err != nil {
+		return &url.URL{}, err
+	}
+	if e.Scheme == "" || e.Host == "" {
+		return e, fmt.Errorf("invalid endpoint %q: a scheme and host are required", b.Config.Endpoint)
+	}
+	return e, nil
+}
 
-	return newPutter(*u, h, b)
+// md5Key returns the key of the md5 file of the object with key
+// e.g. the md5 of "dir/file" is stored at ".md5/dir/file.md5"
+func md5Key(key string) string {
+	return fmt.Sprintf(".md5/%s.md5", strings.TrimPrefix(key, "/"))
 }
 
 // url returns a parsed url to the given path. c must not be nil
@@ -105,61 +474,211 @@ func (b *Bucket) url(bPath string) (*url.URL, error) {
 		bPath = strings.Split(bPath, "?")[0] // remove versionID from path
 	}
 
-	// handling for bucket names containing periods / explicit PathStyle addressing
-	// http://docs.aws.amazon.com/AmazonS3/latest/dev/BucketRestrictions.html for details
-	if strings.Contains(b.Name, ".") || b.Config.PathStyle {
-		return &url.URL{
-			Host:     b.S3.Domain(),
-			Scheme:   b.Config.Scheme,
-			Path:     path.Clean(fmt.Sprintf("/%s/%s", b.Name, bPath)),
-			RawQuery: vals.Encode(),
-		}, nil
-	} else {
-		return &url.URL{
-			Scheme:   b.Config.Scheme,
-			Path:     path.Clean(fmt.Sprintf("/%s", bPath)),
-			Host:     path.Clean(b.S3.DomainForBucket(b.Name)),
-			RawQuery: vals.Encode(),
-		}, nil
+	scheme := b.Config.Scheme
+	if b.Config.Endpoint != "" {
+		e, err := b.endpointURL()
+		if err != nil {
+			return nil, err
+		}
+		scheme = e.Scheme
 	}
+	p := path.Clean(fmt.Sprintf("%s/%s", b.pathPrefix(), bPath))
+	return &url.URL{
+		Scheme:   scheme,
+		Host:     b.host(),
+		Path:     p,
+		RawPath:  escapePath(p), // send the path as it is signed
+		RawQuery: vals.Encode(),
+	}, nil
+}
+
+// subresourceURL returns the url of the subresource of the object at path
+func (b *Bucket) subresourceURL(path, subresource string) (*url.URL, error) {
+	u, err := b.url(path)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set(subresource, "")
+	u.RawQuery = q.Encode()
+	return u, nil
 }
 
 // Delete deletes the key at path
 // If the path does not exist, Delete returns nil (no error).
+//
+// In a versioned bucket, a delete marker is added as the latest version of the key
+// instead. A version of the key included in the path as a url parameter, as with
+// GetReader, is deleted as with DeleteVersion.
 func (b *Bucket) Delete(path string) error {
-	if err := b.delete(path); err != nil {
+	if _, err := b.delete(path); err != nil {
 		return err
 	}
-	// try to delete md5 file
-	if b.Config.Md5Check {
-		if err := b.delete(fmt.Sprintf("/.md5/%s.md5", path)); err != nil {
+	// try to delete md5 file, which is not versioned with the object
+	if b.Config.Md5Check && !strings.Contains(path, versionParam+"=") {
+		if _, err := b.delete(md5Key(path)); err != nil {
 			return err
 		}
 	}
 
-	logger.Printf("%s deleted from %s\n", path, b.Name)
+	if !b.Config.DryRun {
+		b.Config.logger().Printf("%s deleted from %s\n", path, b.Name)
+	}
 	return nil
 }
 
-func (b *Bucket) delete(path string) error {
-	u, err := b.url(path)
+// DeleteVersion permanently deletes the version of the object at path with versionID, which
+// may be a delete marker, e.g. to restore the previous version of a deleted object. As the md5
+// file of the object does not have the same versions, it is not deleted.
+//
+// The result reports the deleted VersionId, and whether it was a DeleteMarker.
+func (b *Bucket) DeleteVersion(path, versionID string) (DeletedObject, error) {
+	if versionID == "" {
+		return DeletedObject{}, errors.New("empty version ID")
+	}
+	key := strings.Split(path, "?")[0]
+	if b.Config.DryRun {
+		return DeletedObject{Key: key, VersionId: versionID}, nil
+	}
+	h, err := b.delete(key + "?" + versionParam + "=" + url.QueryEscape(versionID))
 	if err != nil {
-		return err
+		return DeletedObject{}, err
 	}
-	r := http.Request{
-		Method: "DELETE",
-		URL:    u,
+	return DeletedObject{
+		Key:          key,
+		VersionId:    h.Get(versionIDHeader),
+		DeleteMarker: h.Get("x-amz-delete-marker") == "true",
+	}, nil
+}
+
+// delete deletes the object at path and returns the headers of the response
+func (b *Bucket) delete(path string) (http.Header, error) {
+	if b.Config.DryRun {
+		b.Config.logger().Printf("dry run: %s not deleted from %s\n", path, b.Name)
+		return http.Header{}, nil
 	}
-	b.Sign(&r)
-	resp, err := b.Do(&r)
+	u, err := b.url(path)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	resp, err := b.retryRequest("DELETE", u, nil, nil)
+	if err != nil {
+		return nil, err
 	}
 	defer checkClose(resp.Body, err)
 	if resp.StatusCode != 204 {
-		return newRespError(resp)
+		return nil, newRespError(resp)
 	}
-	return nil
+	return resp.Header, nil
+}
+
+// Head returns the headers of the object at path without downloading it. These include
+// Content-Length, Content-Type, ETag, Last-Modified and any x-amz-meta-* metadata.
+//
+// If the object does not exist, the error is a *RespError with StatusCode 404.
+// As with GetReader, a version ID may be included in the path as a url parameter.
+// Network errors and 5xx responses are retried up to NTry times.
+func (b *Bucket) Head(path string) (http.Header, error) {
+	return b.headWithHeader(path, nil)
+}
+
+// headWithHeader is like Head but adds each header in h to the request
+func (b *Bucket) headWithHeader(path string, h http.Header) (http.Header, error) {
+	resp, err := b.head(path, h)
+	if err != nil {
+		return nil, err
+	}
+	defer checkClose(resp.Body, err)
+	if resp.StatusCode != 200 {
+		return nil, newRespError(resp)
+	}
+	return resp.Header, nil
+}
+
+// Exists reports whether an object exists at path.
+//
+// A 404 response is not an error. Any other failure, such as 403, is returned as an error.
+func (b *Bucket) Exists(path string) (bool, error) {
+	_, err := b.Head(path)
+	if err == nil {
+		return true, nil
+	}
+	if rerr, ok := err.(*RespError); ok && rerr.StatusCode == 404 {
+		return false, nil
+	}
+	return false, err
+}
+
+func (b *Bucket) head(path string, h http.Header) (resp *http.Response, err error) {
+	u, err := b.url(path)
+	if err != nil {
+		return nil, err
+	}
+	return b.retryRequest("HEAD", u, nil, h)
+}
+
+// retryRequest sends a signed request with body and the headers in h,
+// retrying network errors and 5xx responses up to NTry times.
+func (b *Bucket) retryRequest(method string, u *url.URL, body []byte, h http.Header) (resp *http.Response, err error) {
+	if err := b.checkRequest(); err != nil {
+		return nil, err
+	}
+	var rb io.ReadSeeker
+	if body != nil {
+		rb = bytes.NewReader(body)
+	}
+	return b.sendRetry(context.Background(), b.Config.NTry, method, u.String(), rb, h, func(status int) bool {
+		return status >= 500
+	})
+}
+
+// sendRetry sends a signed request with body and the headers in h, retrying network
+// errors and the responses of the statuses reported by retryable, up to ntry attempts
+// in all, as with retry. A redirect to the endpoint of the bucket is followed once,
+// without counting as an attempt. The last retryable response is returned as a *RespError.
+func (b *Bucket) sendRetry(ctx context.Context, ntry int, method, urlStr string, body io.ReadSeeker, h http.Header, retryable func(status int) bool) (resp *http.Response, err error) {
+	send := func(attempt int) (*http.Response, error) {
+		if body != nil {
+			if _, err := body.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
+		req, err := http.NewRequest(method, urlStr, body)
+		if err != nil {
+			return nil, err
+		}
+		req = withAttempt(req.WithContext(ctx), attempt)
+		for k := range h {
+			for _, v := range h[k] {
+				req.Header.Add(k, v)
+			}
+		}
+		if body != nil && !b.unsignedPayload(req.URL) {
+			req.Header.Set(sha256Header, shaReader(body))
+		}
+		b.Sign(req)
+		return b.Do(req)
+	}
+	var redirected bool
+	err = retry(ctx, ntry, b.Config.retryDelay, func(attempt int) (bool, error) {
+		resp, err = send(attempt)
+		if err == nil && !redirected && b.redirect(resp) {
+			redirected = true
+			resp, err = send(attempt)
+		}
+		if err == nil && retryable(resp.StatusCode) {
+			err = newRespError(resp)
+		}
+		if err != nil {
+			b.Config.logger().debugPrintf("error on attempt %d: %s %s: %s", attempt, method, urlStr, err)
+			return true, err
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
 }
 
 // ListObjects returns a list of objects under the given prefixes using parallel
@@ -167,19 +686,79 @@ func (b *Bucket) delete(path string) error {
 //
 // maxKeys indicates how many keys should be returned per request
 func (b *Bucket) ListObjects(prefixes []string, maxKeys int) (*ObjectLister, error) {
-	return newObjectLister(b.Config, b, prefixes, maxKeys)
+	return newObjectLister(context.Background(), b.Config, b, prefixes, "", "", maxKeys)
+}
+
+// ListObjectsContext is like ListObjects, with the requests made with ctx. When ctx is
+// cancelled, the lister stops with the error of ctx.
+func (b *Bucket) ListObjectsContext(ctx context.Context, prefixes []string, maxKeys int) (*ObjectLister, error) {
+	return newObjectLister(ctx, b.Config, b, prefixes, "", "", maxKeys)
+}
+
+// ListObjectsAfter is like ListObjects, but only lists the keys under each prefix that
+// sort after startAfter, with the start-after parameter of ListObjectsV2. Unlike the
+// opaque continuation tokens, startAfter is any key, whether or not it exists, such as
+// the last key listed to resume a listing, or the start of a range of keys for listing
+// a bucket in shards.
+func (b *Bucket) ListObjectsAfter(prefixes []string, startAfter string, maxKeys int) (*ObjectLister, error) {
+	return newObjectLister(context.Background(), b.Config, b, prefixes, "", startAfter, maxKeys)
+}
+
+// ListObjectsAfterContext is like ListObjectsAfter, with the requests made with ctx.
+func (b *Bucket) ListObjectsAfterContext(ctx context.Context, prefixes []string, startAfter string, maxKeys int) (*ObjectLister, error) {
+	return newObjectLister(ctx, b.Config, b, prefixes, "", startAfter, maxKeys)
+}
+
+// ListObjectsPage lists a single page of at most maxKeys objects with keys beginning
+// with prefix, starting after the page of continuationToken, or from the first key if
+// it is "". It returns the objects and the continuation token of the next page, or ""
+// if it's the last page. The tokens are opaque, and may be stored to resume listing
+// later, as for the cursors of paginated APIs.
+//
+// maxKeys defaults to 1000, the maximum of S3, if zero.
+func (b *Bucket) ListObjectsPage(prefix, continuationToken string, maxKeys int) (objects []Object, next string, err error) {
+	if err := b.checkRequest(); err != nil {
+		return nil, "", err
+	}
+	opts := listObjectsOptions{MaxKeys: maxKeys, Prefix: prefix, ContinuationToken: continuationToken}
+	res, err := retryListObjects(context.Background(), b.Config, b, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	objects = make([]Object, 0, len(res.Contents))
+	for _, c := range res.Contents {
+		objects = append(objects, c.object())
+	}
+	return objects, res.NextContinuationToken, nil
+}
+
+// ListObjectsWithDelimiter is like ListObjects, but keys containing delimiter after
+// the prefix are grouped and returned once as a common prefix, available from the
+// CommonPrefixes method of the lister. With a delimiter of "/", this lists the
+// "files" and "directories" directly under each prefix.
+func (b *Bucket) ListObjectsWithDelimiter(prefixes []string, delimiter string, maxKeys int) (*ObjectLister, error) {
+	return newObjectLister(context.Background(), b.Config, b, prefixes, delimiter, "", maxKeys)
+}
+
+// ListObjectsWithDelimiterContext is like ListObjectsWithDelimiter, with the requests
+// made with ctx.
+func (b *Bucket) ListObjectsWithDelimiterContext(ctx context.Context, prefixes []string, delimiter string, maxKeys int) (*ObjectLister, error) {
+	return newObjectLister(ctx, b.Config, b, prefixes, delimiter, "", maxKeys)
 }
 
 // DeleteMultiple deletes multiple keys in a single request.
+// S3 deletes at most 1000 keys per request, including the md5 files of keys when
+// Md5Check is set; use DeleteAll for more.
 //
 // If 'quiet' is false, the result includes the requested paths and whether they
-// were deleted.
+// were deleted. The keys that failed to be deleted are always included, and the request
+// succeeds if some keys fail: use the Err of the result to check for them.
 func (b *Bucket) DeleteMultiple(quiet bool, keys ...string) (DeleteResult, error) {
 	// We also want to try to delete the corresponding md5 files
 	if b.Config.Md5Check {
 		md5Keys := make([]string, 0, len(keys))
 		for _, key := range keys {
-			md5Keys = append(md5Keys, fmt.Sprintf("/.md5/%s.md", key))
+			md5Keys = append(md5Keys, md5Key(key))
 		}
 		keys = append(keys, md5Keys...)
 	}
@@ -187,16 +766,118 @@ func (b *Bucket) DeleteMultiple(quiet bool, keys ...string) (DeleteResult, error
 	return deleteMultiple(b, quiet, keys)
 }
 
+// DeleteAll is like DeleteMultiple for any number of keys. They are deleted in requests
+// of 1000 keys, up to Concurrency at a time, and the results of the requests are combined.
+// When Md5Check is set, the md5 file of each key is deleted in the same request as the key.
+//
+// If a request fails, the error is returned with the results of the other requests.
+// Keys that S3 failed to delete are in the Errors of the result, as with DeleteMultiple.
+func (b *Bucket) DeleteAll(keys []string, quiet bool) (DeleteResult, error) {
+	if b.Config.Md5Check {
+		withMd5 := make([]string, 0, 2*len(keys))
+		for _, key := range keys {
+			withMd5 = append(withMd5, key, md5Key(key)) // batches are even in size, so pairs are not split
+		}
+		keys = withMd5
+	}
+	return deleteAll(b, quiet, keys, b.Config.Concurrency)
+}
+
+// DeletePrefix deletes every object with a key starting with prefix, as listed by
+// ListObjects, and returns the number of objects deleted. The keys are deleted as they
+// are listed, in requests of 1000 keys, up to Concurrency at a time. When Md5Check is set,
+// the md5 files of the objects are deleted with them, and are not counted or listed.
+//
+// If S3 fails to delete some of the keys, the error is their DeleteErrors.
+func (b *Bucket) DeletePrefix(prefix string) (n int, err error) {
+	l, err := b.ListObjects([]string{prefix}, maxDeleteKeys)
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+
+	var keys []string
+	deleteKeys := func() error {
+		res, err := b.DeleteAll(keys, true)
+		failed := make(map[string]bool, len(res.Errors))
+		for _, e := range res.Errors {
+			failed[e.Key] = true
+		}
+		for _, key := range keys {
+			if !failed[key] {
+				n++
+			}
+		}
+		keys = keys[:0]
+		if err == nil {
+			err = res.Err()
+		}
+		return err
+	}
+	for l.Next() {
+		for _, key := range l.Value() {
+			if b.Config.Md5Check && strings.HasPrefix(key, ".md5/") {
+				continue
+			}
+			keys = append(keys, key)
+		}
+		if len(keys) >= maxDeleteKeys*max(b.Config.Concurrency, 1) {
+			if err := deleteKeys(); err != nil {
+				return n, err
+			}
+		}
+	}
+	if err := l.Error(); err != nil {
+		return n, err
+	}
+	if err := deleteKeys(); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
 // Sign signs the http.Request
 func (b *Bucket) Sign(req *http.Request) {
+	b.prepare(req)
+	if b.unsignedPayload(req.URL) {
+		req.Header.Set(sha256Header, unsignedPayload)
+	}
+	b.signer(req).sign()
+}
+
+// unsignedPayload reports whether requests to u are signed with UNSIGNED-PAYLOAD, as
+// they are over HTTPS if Config.UnsignedPayload is set
+func (b *Bucket) unsignedPayload(u *url.URL) bool {
+	return b.Config.UnsignedPayload && u.Scheme == "https"
+}
+
+// prepare sets the User-Agent, default headers, expected bucket owner and host of req
+// for the bucket, before it's signed
+func (b *Bucket) prepare(req *http.Request) {
 	if req.Header == nil {
 		req.Header = http.Header{}
 	}
-	req.Header.Set("User-Agent", "S3Gof3r")
-	s := &signer{
+	req.Header.Set("User-Agent", b.Config.userAgent())
+	for k, v := range b.Config.DefaultHeaders {
+		if _, ok := req.Header[http.CanonicalHeaderKey(k)]; !ok {
+			req.Header[http.CanonicalHeaderKey(k)] = v
+		}
+	}
+	b.setExpectedBucketOwner(req)
+	if host := b.host(); req.URL.Host == b.defaultHost() && host != req.URL.Host {
+		u := *req.URL // send requests for urls made before a redirect to its endpoint
+		u.Host = host
+		req.URL, req.Host = &u, host
+	}
+}
+
+// signer returns the signer of req for the bucket
+func (b *Bucket) signer(req *http.Request) *signer {
+	return &signer{
 		Time:     time.Now(),
 		Request:  req,
 		S3Config: b.S3,
+		Region:   b.signingRegion(),
+		Service:  b.Config.SigningService,
 	}
-	s.sign()
 }
@@ -0,0 +1,18 @@
+package s3gof3r
+
+import "net/http"
+
+const (
+	expectedBucketOwnerHeader       = "X-Amz-Expected-Bucket-Owner"
+	sourceExpectedBucketOwnerHeader = "X-Amz-Source-Expected-Bucket-Owner"
+)
+
+// setExpectedBucketOwner sets the expected bucket owner header of req to the
+// ExpectedBucketOwner of the bucket, if any, unless req has it already. The owner
+// of the source of copies is set by setCopySource, from the source bucket.
+func (b *Bucket) setExpectedBucketOwner(req *http.Request) {
+	owner := b.Config.ExpectedBucketOwner
+	if owner != "" && req.Header.Get(expectedBucketOwnerHeader) == "" {
+		req.Header.Set(expectedBucketOwnerHeader, owner)
+	}
+}
@@ -0,0 +1,82 @@
+package s3gof3r
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestExpectedBucketOwner(t *testing.T) {
+	s := newFakeS3()
+	var copies int
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		owner := r.Header.Get(expectedBucketOwnerHeader)
+		if !strings.Contains(r.Header.Get("Authorization"), "x-amz-expected-bucket-owner") {
+			t.Errorf("Expected signed x-amz-expected-bucket-owner of %s %s", r.Method, r.URL)
+		}
+		if r.Header.Get(copySourceHeader) != "" {
+			copies++
+			if so := r.Header.Get(sourceExpectedBucketOwnerHeader); so != owner {
+				t.Errorf("Expected source bucket owner: %s. Actual: %s", owner, so)
+			}
+		}
+		if owner != "111122223333" {
+			w.WriteHeader(403)
+			w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+			return
+		}
+		s.ServeHTTP(w, r)
+	}))
+	b.Config.ExpectedBucketOwner = "111122223333"
+	if err := b.PutBytes("key", []byte("data"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := b.GetBytes("key"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Head("key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Copy("key", "copy", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := b.ListObjectsPage("", "", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Delete("copy"); err != nil {
+		t.Fatal(err)
+	}
+	if copies != 1 {
+		t.Errorf("Expected copies: 1. Actual: %d", copies)
+	}
+
+	b.Config.ExpectedBucketOwner = "444455556666"
+	_, _, err := b.GetBytes("key")
+	if !errors.Is(err, ErrBucketOwnerMismatch) || !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Expected error: %v. Actual: %v", ErrBucketOwnerMismatch, err)
+	}
+	if _, err := b.Head("key"); !errors.Is(err, ErrBucketOwnerMismatch) {
+		t.Errorf("Expected head error: %v. Actual: %v", ErrBucketOwnerMismatch, err)
+	}
+
+	h := http.Header{}
+	h.Set(expectedBucketOwnerHeader, "111122223333")
+	if err := b.PutBytes("key", []byte("data"), h); err != nil {
+		t.Errorf("Expected header passed to take precedence. Actual: %v", err)
+	}
+}
+
+func TestAccessDeniedWithoutBucketOwner(t *testing.T) {
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(expectedBucketOwnerHeader) != "" {
+			t.Errorf("unexpected bucket owner of %s %s", r.Method, r.URL)
+		}
+		w.WriteHeader(403)
+		w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+	}))
+	_, _, err := b.GetBytes("key")
+	if !errors.Is(err, ErrAccessDenied) || errors.Is(err, ErrBucketOwnerMismatch) {
+		t.Errorf("Expected access denied error without bucket owner mismatch. Actual: %v", err)
+	}
+}
@@ -0,0 +1,383 @@
+package s3gof3r
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHead(t *testing.T) {
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "HEAD" {
+			t.Errorf("unexpected method %s", r.Method)
+		}
+		switch r.URL.Path {
+		case "/bucket/key":
+			if v := r.URL.Query().Get(versionParam); v != "" && v != "v1" {
+				t.Errorf("unexpected version %s", v)
+			}
+			w.Header().Set("ETag", `"abc"`)
+			w.Header().Set("X-Amz-Meta-Foo", "bar")
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+
+	var headTests = []struct {
+		path   string
+		status int
+	}{
+		{"key", 0},
+		{"key?versionId=v1", 0},
+		{"missing", 404},
+	}
+	for _, tt := range headTests {
+		h, err := b.Head(tt.path)
+		if tt.status != 0 {
+			if rerr, ok := err.(*RespError); !ok || rerr.StatusCode != tt.status {
+				t.Errorf("%s: expected status %d, got %v", tt.path, tt.status, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.path, err)
+			continue
+		}
+		if h.Get("ETag") != `"abc"` || h.Get("x-amz-meta-foo") != "bar" {
+			t.Errorf("%s: unexpected headers %v", tt.path, h)
+		}
+	}
+}
+
+func TestExists(t *testing.T) {
+	var n int
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bucket/key":
+		case "/bucket/flaky":
+			if n++; n == 1 {
+				w.WriteHeader(503)
+			}
+		case "/bucket/denied":
+			w.WriteHeader(403)
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+
+	var existsTests = []struct {
+		path   string
+		exists bool
+		err    bool
+	}{
+		{"key", true, false},
+		{"flaky", true, false},
+		{"missing", false, false},
+		{"denied", false, true},
+	}
+	for _, tt := range existsTests {
+		exists, err := b.Exists(tt.path)
+		if (err != nil) != tt.err || exists != tt.exists {
+			t.Errorf("%s: expected %t, error %t, got %t, %v", tt.path, tt.exists, tt.err, exists, err)
+		}
+	}
+}
+
+func TestDeleteMultipleMd5(t *testing.T) {
+	s := newFakeS3()
+	b := newTestBucket(t, s)
+	b.Config.Md5Check = true
+	keys := []string{"a", "dir/b"}
+	for _, key := range keys {
+		w, err := b.PutWriter(key, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(key)); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := s.object("/bucket/.md5/" + key + ".md5"); !ok {
+			t.Fatalf("md5 file of %s not put", key)
+		}
+	}
+
+	if _, err := b.DeleteMultiple(true, keys...); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if _, ok := s.object("/bucket/" + key); ok {
+			t.Errorf("%s not deleted", key)
+		}
+		if _, ok := s.object("/bucket/.md5/" + key + ".md5"); ok {
+			t.Errorf("md5 file of %s not deleted", key)
+		}
+	}
+}
+
+func TestDeleteResultErr(t *testing.T) {
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<DeleteResult><Deleted><Key>a</Key></Deleted>`+
+			`<Error><Key>b</Key><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`+
+			`<Error><Key>c</Key><Code>InternalError</Code><Message>We encountered an internal error.</Message></Error></DeleteResult>`)
+	}))
+	res, err := b.DeleteMultiple(false, "a", "b", "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Deleted) != 1 || res.Deleted[0].Key != "a" {
+		t.Errorf("Unexpected deleted objects: %+v", res.Deleted)
+	}
+	var derr DeleteErrors
+	if !errors.As(res.Err(), &derr) {
+		t.Fatalf("Expected DeleteErrors. Actual: %v", res.Err())
+	}
+	if fmt.Sprint(derr.Keys()) != "[b c]" || derr[0].Code != "AccessDenied" {
+		t.Errorf("Unexpected errors: %+v", derr)
+	}
+	if expErr := "2 keys not deleted, first b: AccessDenied: Access Denied"; derr.Error() != expErr {
+		t.Errorf("Expected error: %s. Actual: %s", expErr, derr.Error())
+	}
+	if err := (DeleteResult{Deleted: res.Deleted}).Err(); err != nil {
+		t.Errorf("Expected no error. Actual: %v", err)
+	}
+}
+
+func TestDeleteAll(t *testing.T) {
+	s := newFakeS3()
+	var mu sync.Mutex
+	var requests int
+	s.check = func(r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+	}
+	b := newTestBucket(t, s)
+	b.Config.Md5Check = true
+	keys := make([]string, 1200)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("dir/%d", i)
+		s.objects["/bucket/"+keys[i]] = []byte("data")
+		s.objects["/bucket/"+md5Key(keys[i])] = []byte("md5")
+	}
+
+	res, err := b.DeleteAll(keys, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 {
+		t.Errorf("Expected delete requests: 3. Actual: %d", requests)
+	}
+	if len(res.Deleted) != 2*len(keys) || res.Deleted[0].Key != "dir/0" || res.Deleted[1].Key != ".md5/dir/0.md5" {
+		t.Errorf("Unexpected deleted objects: %d, starting %v", len(res.Deleted), res.Deleted[:2])
+	}
+	if len(s.objects) != 0 {
+		t.Errorf("Expected all objects deleted. Actual: %d remaining", len(s.objects))
+	}
+}
+
+func TestDeletePrefix(t *testing.T) {
+	s := newFakeS3()
+	b := newTestBucket(t, s)
+	b.Config.Md5Check = true
+	for i := 0; i < 2500; i++ {
+		s.objects[fmt.Sprintf("/bucket/dir/%d", i)] = []byte("data")
+		s.objects[fmt.Sprintf("/bucket/.md5/dir/%d.md5", i)] = []byte("md5")
+	}
+	s.objects["/bucket/other"] = []byte("data")
+
+	n, err := b.DeletePrefix("dir/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2500 {
+		t.Errorf("Expected deleted objects: 2500. Actual: %d", n)
+	}
+	if len(s.objects) != 1 {
+		t.Errorf("Expected only the object outside the prefix to remain. Actual: %d objects", len(s.objects))
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	s := newFakeS3()
+	s.check = func(r *http.Request) {
+		if r.Method != "GET" {
+			t.Errorf("unexpected request in dry run: %s %s", r.Method, r.URL)
+		}
+	}
+	b := newTestBucket(t, s)
+	b.Config.Md5Check = true
+	b.Config.DryRun = true
+	for i := 0; i < 3; i++ {
+		s.objects[fmt.Sprintf("/bucket/dir/%d", i)] = []byte("data")
+		s.objects[fmt.Sprintf("/bucket/.md5/dir/%d.md5", i)] = []byte("md5")
+	}
+
+	if err := b.Delete("dir/0"); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := b.DeleteVersion("dir/0", "v1"); err != nil || d != (DeletedObject{Key: "dir/0", VersionId: "v1"}) {
+		t.Errorf("unexpected result: %+v %v", d, err)
+	}
+	res, err := b.DeleteMultiple(true, "dir/0", "dir/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, d := range res.Deleted {
+		keys = append(keys, d.Key)
+	}
+	if expect := []string{"dir/0", "dir/1", ".md5/dir/0.md5", ".md5/dir/1.md5"}; fmt.Sprint(keys) != fmt.Sprint(expect) {
+		t.Errorf("Expected keys: %v. Actual: %v", expect, keys)
+	}
+	if res, err := b.DeleteAll([]string{"dir/0", "dir/1", "dir/2"}, false); err != nil || len(res.Deleted) != 6 {
+		t.Errorf("Expected 6 keys. Actual: %+v %v", res, err)
+	}
+	n, err := b.DeletePrefix("dir/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("Expected objects: 3. Actual: %d", n)
+	}
+	if len(s.objects) != 6 {
+		t.Errorf("Expected no objects deleted. Actual: %d objects", len(s.objects))
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	for _, tt := range []struct{ config, header string }{
+		{"", "S3Gof3r"},
+		{"myapp/1.2", "S3Gof3r myapp/1.2"},
+	} {
+		s := newFakeS3()
+		s.objects["/bucket/key"] = []byte("data")
+		var mu sync.Mutex
+		agents := make(map[string]bool)
+		s.check = func(r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			agents[r.Header.Get("User-Agent")] = true
+		}
+		b := newTestBucket(t, s)
+		b.Config.UserAgent = tt.config
+		r, _, err := b.GetReader("key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Close()
+		if err := b.Delete("key"); err != nil {
+			t.Fatal(err)
+		}
+		if len(agents) != 1 || !agents[tt.header] {
+			t.Errorf("Expected User-Agent: %s. Actual: %v", tt.header, agents)
+		}
+	}
+}
+
+func TestDefaultHeaders(t *testing.T) {
+	s := newFakeS3()
+	var mu sync.Mutex
+	var requests []*http.Request
+	s.check = func(r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r)
+	}
+	b := newTestBucket(t, s)
+	b.Config.Md5Check = false
+	b.Config.DefaultHeaders = http.Header{"X-Cost-Center": {"42"}, "Cache-Control": {"no-cache"}}
+
+	w, err := b.PutWriter("key", http.Header{"Cache-Control": {"max-age=60"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Head("key"); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests. Actual: %d", len(requests))
+	}
+	for i, cc := range []string{"max-age=60", "no-cache"} {
+		r := requests[i]
+		if v := r.Header.Get("X-Cost-Center"); v != "42" {
+			t.Errorf("Expected X-Cost-Center: 42. Actual: %s", v)
+		}
+		if v := r.Header.Get("Cache-Control"); v != cc {
+			t.Errorf("Expected Cache-Control: %s. Actual: %s", cc, v)
+		}
+		if a := r.Header.Get("Authorization"); !strings.Contains(a, "cache-control;") || !strings.Contains(a, "x-cost-center") {
+			t.Errorf("Expected default headers to be signed. Actual: %s", a)
+		}
+	}
+}
+
+// TestBucketConfig gets objects of two buckets of an S3 at once, after changing the
+// Concurrency of each, checking the buckets don't share their Config.
+func TestBucketConfig(t *testing.T) {
+	s := newFakeS3()
+	data := make([]byte, 40*kb)
+	s.objects["/one/key"] = data
+	s.objects["/two/key"] = data
+	var mu sync.Mutex
+	active, most := make(map[string]int), make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.Split(r.URL.Path, "/")[1]
+		mu.Lock()
+		active[name]++
+		if active[name] > most[name] {
+			most[name] = active[name]
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		s.ServeHTTP(w, r)
+		mu.Lock()
+		active[name]--
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	t.Setenv("AWS_REGION", "us-east-1")
+	u, _ := url.Parse(srv.URL)
+	s3 := New(u.Host, &Keys{accessKeyID: "AKIDEXAMPLE", secretAccessKey: "secret"})
+
+	buckets := map[string]int{"one": 1, "two": 4}
+	var wg sync.WaitGroup
+	for name, concurrency := range buckets {
+		b := s3.Bucket(name)
+		b.Config.Concurrency = concurrency
+		b.Config.PartSize = 4 * kb
+		b.Config.Md5Check = false
+		b.Config.Scheme = "http"
+		b.Config.PathStyle = true
+		wg.Add(1)
+		go func(b *Bucket) {
+			defer wg.Done()
+			if _, _, err := b.GetBytes("key"); err != nil {
+				t.Error(err)
+			}
+		}(b)
+	}
+	wg.Wait()
+	for name, concurrency := range buckets {
+		if most[name] > concurrency {
+			t.Errorf("Expected at most %d requests at once to %s. Actual: %d", concurrency, name, most[name])
+		}
+	}
+	if most["two"] < 2 {
+		t.Errorf("Expected concurrent requests to two. Actual: %d", most["two"])
+	}
+	if DefaultConfig.Concurrency != defaultConcurrency || DefaultConfig.PathStyle {
+		t.Errorf("Expected DefaultConfig unchanged. Actual: %+v", DefaultConfig)
+	}
+}
@@ -0,0 +1,200 @@
+package s3gof3r
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Checksum algorithms supported by S3, validated by S3 on each part of a put
+// See https://docs.aws.amazon.com/AmazonS3/latest/userguide/checking-object-integrity.html
+const (
+	ChecksumCRC32  = "CRC32"
+	ChecksumCRC32C = "CRC32C"
+	ChecksumSHA1   = "SHA1"
+	ChecksumSHA256 = "SHA256"
+)
+
+const (
+	checksumAlgorithmHeader = "X-Amz-Checksum-Algorithm"
+	checksumModeHeader      = "X-Amz-Checksum-Mode"
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// newChecksumHash returns a hash of algorithm alg
+func newChecksumHash(alg string) (hash.Hash, error) {
+	switch alg {
+	case ChecksumCRC32:
+		return crc32.NewIEEE(), nil
+	case ChecksumCRC32C:
+		return crc32.New(crc32cTable), nil
+	case ChecksumSHA1:
+		return sha1.New(), nil
+	case ChecksumSHA256:
+		return sha256.New(), nil
+	}
+	return nil, fmt.Errorf("invalid checksum algorithm: %q", alg)
+}
+
+// checksumHeader returns the header of checksums of algorithm alg, e.g. x-amz-checksum-sha256
+func checksumHeader(alg string) string {
+	return http.CanonicalHeaderKey("x-amz-checksum-" + strings.ToLower(alg))
+}
+
+// checksumPart is the expected size and checksum of a part of an object
+type checksumPart struct {
+	size     int64
+	checksum string
+}
+
+// checksumVerifier verifies the checksum of each part of the data written to it.
+type checksumVerifier struct {
+	alg   string
+	h     hash.Hash
+	parts []checksumPart
+	i     int   // index of the current part
+	n     int64 // bytes written of the current part
+	err   error // first mismatch
+}
+
+func newChecksumVerifier(alg string, parts []checksumPart) (*checksumVerifier, error) {
+	h, err := newChecksumHash(alg)
+	if err != nil {
+		return nil, err
+	}
+	return &checksumVerifier{alg: alg, h: h, parts: parts}, nil
+}
+
+func (v *checksumVerifier) Write(b []byte) (int, error) {
+	nw := len(b)
+	for len(b) > 0 {
+		if v.i >= len(v.parts) {
+			return 0, fmt.Errorf("%s checksum check failed: more data than the checksummed parts", v.alg)
+		}
+		p := v.parts[v.i]
+		n := int(min64(int64(len(b)), p.size-v.n))
+		v.h.Write(b[:n])
+		v.n += int64(n)
+		b = b[n:]
+		if v.n == p.size {
+			v.endPart()
+		}
+	}
+	return nw, nil
+}
+
+// endPart compares the checksum of the current part and starts the next
+func (v *checksumVerifier) endPart() {
+	sum := base64.StdEncoding.EncodeToString(v.h.Sum(nil))
+	if sum != v.parts[v.i].checksum && v.err == nil {
+		v.err = fmt.Errorf("%s checksum mismatch of part %d. given:%s calculated:%s",
+			v.alg, v.i+1, v.parts[v.i].checksum, sum)
+	}
+	v.h.Reset()
+	v.i++
+	v.n = 0
+}
+
+// verify returns an error unless all parts were written with matching checksums
+func (v *checksumVerifier) verify() error {
+	if v.i < len(v.parts) && v.parts[v.i].size == 0 { // empty object
+		v.endPart()
+	}
+	if v.err != nil {
+		return v.err
+	}
+	if v.i != len(v.parts) {
+		return fmt.Errorf("%s checksum check failed: %d of %d parts read", v.alg, v.i, len(v.parts))
+	}
+	return nil
+}
+
+type objectPart struct {
+	PartNumber     int
+	Size           int64
+	ChecksumCRC32  string
+	ChecksumCRC32C string
+	ChecksumSHA1   string
+	ChecksumSHA256 string
+}
+
+func (p objectPart) checksum(alg string) string {
+	switch alg {
+	case ChecksumCRC32:
+		return p.ChecksumCRC32
+	case ChecksumCRC32C:
+		return p.ChecksumCRC32C
+	case ChecksumSHA1:
+		return p.ChecksumSHA1
+	}
+	return p.ChecksumSHA256
+}
+
+type objectAttributes struct {
+	ObjectParts struct {
+		IsTruncated          bool
+		NextPartNumberMarker string
+		Parts                []objectPart `xml:"Part"`
+	}
+}
+
+// checksumParts returns the expected parts of the object at u of contentLen bytes and
+// the checksum of algorithm alg got from its headers h. Multipart objects have composite
+// checksums of their parts, e.g. "<checksum of part checksums>-3", so the parts and
+// their checksums are got from the object attributes.
+func (b *Bucket) checksumParts(u url.URL, alg string, h http.Header, contentLen int64) ([]checksumPart, error) {
+	v := h.Get(checksumHeader(alg))
+	if v == "" {
+		return nil, fmt.Errorf("%s checksum check failed: the object has no %s checksum", alg, alg)
+	}
+	if !strings.Contains(v, "-") {
+		return []checksumPart{{contentLen, v}}, nil
+	}
+	var parts []checksumPart
+	q := u.Query()
+	q.Set("attributes", "")
+	u.RawQuery = q.Encode()
+	ah := http.Header{}
+	ah.Set("X-Amz-Object-Attributes", "ObjectParts")
+	for {
+		resp, err := b.retryRequest("GET", &u, nil, ah)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != 200 {
+			err = newRespError(resp)
+			checkClose(resp.Body, err)
+			return nil, err
+		}
+		var attrs objectAttributes
+		err = xml.NewDecoder(resp.Body).Decode(&attrs)
+		checkClose(resp.Body, err)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range attrs.ObjectParts.Parts {
+			parts = append(parts, checksumPart{p.Size, p.checksum(alg)})
+		}
+		if !attrs.ObjectParts.IsTruncated || attrs.ObjectParts.NextPartNumberMarker == "" {
+			break
+		}
+		ah.Set("X-Amz-Part-Number-Marker", attrs.ObjectParts.NextPartNumberMarker)
+	}
+	var size int64
+	for _, p := range parts {
+		size += p.size
+	}
+	if n := v[strings.LastIndex(v, "-")+1:]; n != strconv.Itoa(len(parts)) || size != contentLen {
+		return nil, fmt.Errorf("%s checksum check failed: %d parts of %d bytes do not match the object", alg, len(parts), size)
+	}
+	return parts, nil
+}
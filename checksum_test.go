@@ -0,0 +1,169 @@
+package s3gof3r
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChecksumAlgorithm(t *testing.T) {
+	s := newFakeS3()
+	b := newTestBucket(t, s)
+	data := make([]byte, 12*mb)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	for _, alg := range []string{ChecksumCRC32, ChecksumCRC32C, ChecksumSHA1, ChecksumSHA256} {
+		for _, size := range []int64{0, 1 * kb, 12 * mb} {
+			name := fmt.Sprintf("%s/%d", alg, size)
+			b.Config.ChecksumAlgorithm = alg
+			w, err := b.PutWriter("key", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Write(data[:size]); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if size == 12*mb {
+				s.mu.Lock()
+				got := s.checksums["/bucket/key"].value
+				s.mu.Unlock()
+				if !strings.HasSuffix(got, "-3") {
+					t.Errorf("%s: expected composite checksum of 3 parts, got %q", name, got)
+				}
+			}
+
+			if err := getAll(b); err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+
+			// corrupt the last byte
+			if size == 0 {
+				continue
+			}
+			s.mu.Lock()
+			s.objects["/bucket/key"][size-1]++
+			s.mu.Unlock()
+			if err := getAll(b); err == nil || !strings.Contains(err.Error(), "mismatch") {
+				t.Errorf("%s: expected checksum mismatch, got %v", name, err)
+			}
+		}
+	}
+
+	b.Config.ChecksumAlgorithm = "MD4"
+	if _, err := b.PutWriter("key", nil); err == nil {
+		t.Error("expected error for invalid checksum algorithm")
+	}
+}
+
+func TestChecksumMissing(t *testing.T) {
+	s := newFakeS3()
+	s.objects["/bucket/key"] = []byte("data")
+	b := newTestBucket(t, s)
+	b.Config.ChecksumAlgorithm = ChecksumSHA256
+	if _, _, err := b.GetReader("key"); err == nil {
+		t.Error("expected error for object without checksum")
+	}
+}
+
+func TestChecksumPartMismatch(t *testing.T) {
+	s := newFakeS3()
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			r.Header.Set(checksumHeader(ChecksumSHA256), "bad") // as if corrupted in transit
+		}
+		s.ServeHTTP(w, r)
+	}))
+	b.Config.ChecksumAlgorithm = ChecksumSHA256
+	w, err := b.PutWriter("key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err == nil {
+		t.Error("expected error for rejected checksum")
+	}
+}
+
+// TestChecksumPartRetry gets an object whose second part is corrupted in transit once,
+// checking only that part is got again.
+func TestChecksumPartRetry(t *testing.T) {
+	s := newFakeS3()
+	var corrupted bool
+	gets := make(map[string]int)
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rng := r.Header.Get("Range")
+		if r.Method != "GET" || rng == "" {
+			s.ServeHTTP(w, r)
+			return
+		}
+		s.mu.Lock()
+		gets[rng]++
+		corrupt := strings.HasPrefix(rng, fmt.Sprintf("bytes=%d-", 5*mb)) && !corrupted
+		corrupted = corrupted || corrupt
+		s.mu.Unlock()
+		if !corrupt {
+			s.ServeHTTP(w, r)
+			return
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, r)
+		body := rec.Body.Bytes()
+		body[0]++
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(body)
+	}))
+	b.Config.ChecksumAlgorithm = ChecksumCRC32C
+	w, err := b.PutWriter("key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(make([]byte, 12*mb)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := getAll(b); err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(gets) != 3 {
+		t.Errorf("Expected 3 parts got. Actual: %v", gets)
+	}
+	for rng, n := range gets {
+		expect := 1
+		if strings.HasPrefix(rng, fmt.Sprintf("bytes=%d-", 5*mb)) {
+			expect = 2
+		}
+		if n != expect {
+			t.Errorf("Expected %s got %d times. Actual: %d", rng, expect, n)
+		}
+	}
+}
+
+// getAll gets the object "key" of b, returning any error
+func getAll(b *Bucket) error {
+	r, _, err := b.GetReader("key")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		r.Close()
+		return err
+	}
+	return r.Close()
+}
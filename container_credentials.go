@@ -0,0 +1,62 @@
+package s3gof3r
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+)
+
+// containerCredentialsURL is the base url of AWS_CONTAINER_CREDENTIALS_RELATIVE_URI.
+var containerCredentialsURL = "http://169.254.170.2"
+
+// ContainerKeys requests the AWS keys for the task role of an ECS or Fargate task.
+//
+// The keys are read from the endpoint in AWS_CONTAINER_CREDENTIALS_RELATIVE_URI or,
+// if that is not set, AWS_CONTAINER_CREDENTIALS_FULL_URI. For the full uri,
+// AWS_CONTAINER_AUTHORIZATION_TOKEN is sent as the Authorization header when set.
+// The keys are temporary and are automatically renewed shortly before they expire.
+func ContainerKeys() (keys *Keys, err error) {
+	keys, err = containerKeys()
+	if err != nil {
+		return
+	}
+	keys.refresh = containerKeys
+	return
+}
+
+func containerKeys() (keys *Keys, err error) {
+	var u, token string
+	if rel := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); rel != "" {
+		u = containerCredentialsURL + rel
+	} else if full := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI"); full != "" {
+		u = full
+		token = os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	} else {
+		return nil, fmt.Errorf("container credentials not set in environment: " +
+			"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI, AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	}
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	resp, err := ClientWithTimeout(2 * time.Second).Do(req)
+	if err != nil {
+		return
+	}
+	defer checkClose(resp.Body, err)
+	if resp.StatusCode != 200 {
+		return nil, newRespError(resp)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+	return credentialKeys(b)
+}
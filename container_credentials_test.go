@@ -0,0 +1,52 @@
+package s3gof3r
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContainerKeys(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/full" && r.Header.Get("Authorization") != "auth-token" {
+			w.WriteHeader(401)
+			return
+		}
+		w.Write([]byte(testInstanceCreds))
+	}))
+	defer srv.Close()
+	orig := containerCredentialsURL
+	containerCredentialsURL = srv.URL
+	defer func() { containerCredentialsURL = orig }()
+
+	var containerTests = []struct {
+		relative string
+		full     string
+		token    string
+		ok       bool
+	}{
+		{"/v2/credentials/id", "", "", true},
+		{"", srv.URL + "/full", "auth-token", true},
+		{"", srv.URL + "/full", "", false},
+		{"", "", "", false},
+	}
+	for _, tt := range containerTests {
+		t.Setenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", tt.relative)
+		t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", tt.full)
+		t.Setenv("AWS_CONTAINER_AUTHORIZATION_TOKEN", tt.token)
+		k, err := ContainerKeys()
+		if !tt.ok {
+			if err == nil {
+				t.Errorf("%+v: expected error", tt)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%+v: %v", tt, err)
+			continue
+		}
+		if k.AccessKeyID() != "AKIDEXAMPLE" || k.SessionToken() != "token" {
+			t.Errorf("%+v: unexpected keys %s %s", tt, k.AccessKeyID(), k.SessionToken())
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package s3gof3r
+
+import (
+	"net/http"
+	"testing"
+)
+
+var contentTypeTests = []struct {
+	path   string
+	header string
+	data   string
+	expect string
+}{
+	{"/index.html", "", "", "text/html; charset=utf-8"},
+	{"/doc.pdf", "", "", "application/pdf"},
+	{"/noext", "", "<html><body>hi</body></html>", "text/html; charset=utf-8"},
+	{"/noext", "", "\x89PNG\x0D\x0A\x1A\x0A", "image/png"},
+	{"/noext", "", "", "text/plain; charset=utf-8"},
+	{"/index.html", "text/plain", "", "text/plain"},
+}
+
+func TestDetectContentType(t *testing.T) {
+	s := newFakeS3()
+	b := newTestBucket(t, s)
+	b.Config.DetectContentType = true
+	for _, tt := range contentTypeTests {
+		h := http.Header{}
+		if tt.header != "" {
+			h.Set("Content-Type", tt.header)
+		}
+		w, err := b.PutWriter(tt.path, h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(tt.data)); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		s.mu.Lock()
+		got := s.headers["/bucket"+tt.path].Get("Content-Type")
+		s.mu.Unlock()
+		if got != tt.expect {
+			t.Errorf("%s %q: Expected Content-Type: %q. Actual: %q", tt.path, tt.data, tt.expect, got)
+		}
+	}
+}
@@ -0,0 +1,401 @@
+package s3gof3r
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	copySourceHeader        = "x-amz-copy-source"
+	copySourceRangeHeader   = "x-amz-copy-source-range"
+	metadataDirectiveHeader = "x-amz-metadata-directive"
+
+	copySourceIfMatchHeader           = "x-amz-copy-source-if-match"
+	copySourceIfNoneMatchHeader       = "x-amz-copy-source-if-none-match"
+	copySourceIfModifiedSinceHeader   = "x-amz-copy-source-if-modified-since"
+	copySourceIfUnmodifiedSinceHeader = "x-amz-copy-source-if-unmodified-since"
+)
+
+// CopyConditions are the preconditions of the source of a copy, for copies that
+// only proceed if the source is still the object expected, e.g. the version read
+// before: the source must have the ETag of IfMatch, not have the ETag of IfNoneMatch,
+// and have been modified since IfModifiedSince and not since IfUnmodifiedSince. As
+// evaluated by S3, IfUnmodifiedSince is ignored if IfMatch is met.
+type CopyConditions struct {
+	IfMatch           string // ETag, with or without surrounding quotes
+	IfNoneMatch       string
+	IfModifiedSince   time.Time
+	IfUnmodifiedSince time.Time
+}
+
+// setHeaders sets the x-amz-copy-source-if-* headers of the conditions set in h
+func (c CopyConditions) setHeaders(h http.Header) {
+	quote := func(etag string) string {
+		if strings.HasPrefix(etag, `"`) || etag == "*" {
+			return etag
+		}
+		return `"` + etag + `"`
+	}
+	if c.IfMatch != "" {
+		h.Set(copySourceIfMatchHeader, quote(c.IfMatch))
+	}
+	if c.IfNoneMatch != "" {
+		h.Set(copySourceIfNoneMatchHeader, quote(c.IfNoneMatch))
+	}
+	if !c.IfModifiedSince.IsZero() {
+		h.Set(copySourceIfModifiedSinceHeader, c.IfModifiedSince.UTC().Format(http.TimeFormat))
+	}
+	if !c.IfUnmodifiedSince.IsZero() {
+		h.Set(copySourceIfUnmodifiedSinceHeader, c.IfUnmodifiedSince.UTC().Format(http.TimeFormat))
+	}
+}
+
+// Copy copies the object at srcPath to dstPath. The data is copied within S3
+// and is not transferred through the client.
+//
+// srcPath is a key in b. To copy from another bucket, srcPath may be given in
+// "/bucket/key" form, as in the x-amz-copy-source header. As with GetReader, a
+// version ID may be included in srcPath as a url parameter.
+//
+// Each header in h is added to the copy request. This is useful for specifying
+// x-amz-metadata-directive (COPY or REPLACE) with replacement metadata, or options
+// such as storage class and server-side encryption.
+//
+// Objects larger than 5 GB are copied in parts using a multipart upload. Precondition
+// headers apply to the whole copy: x-amz-copy-source-if-* headers of the source are
+// sent with each part, and If-Match or If-None-Match of the destination on completion.
+// A copy whose preconditions are not met fails with ErrPreconditionFailed, whether S3
+// responds with a 412 or, as it may for copies, with the error under a 200.
+func (b *Bucket) Copy(srcPath, dstPath string, h http.Header) error {
+	srcBucket, srcKey, err := b.copySource(srcPath)
+	if err != nil {
+		return err
+	}
+	return b.copyFrom(srcBucket, srcKey, dstPath, h)
+}
+
+// copyFrom copies the object at srcKey in srcBucket to dstPath within S3. The source
+// is read with the Config of srcBucket: it's found with a HEAD request of srcBucket,
+// and its expected owner and SSE-C key are those of srcBucket.
+func (b *Bucket) copyFrom(srcBucket *Bucket, srcKey, dstPath string, h http.Header) error {
+	sse, err := sseCustomerHeader(srcBucket.Config.SSECustomerKey, nil)
+	if err != nil {
+		return err
+	}
+	sh, err := srcBucket.headWithHeader(srcKey, sse)
+	if err != nil {
+		return err
+	}
+	source, err := copySourceValue(srcBucket.Name, srcKey)
+	if err != nil {
+		return err
+	}
+	h = cloneHeader(h)
+	if err := srcBucket.setCopySource(h); err != nil {
+		return err
+	}
+	return b.copy(source, sh, dstPath, h)
+}
+
+// setCopySource sets the headers of the source of copies from b in h, unless h has
+// them already: its ExpectedBucketOwner and SSE-C key.
+func (b *Bucket) setCopySource(h http.Header) error {
+	if owner := b.Config.ExpectedBucketOwner; owner != "" && h.Get(sourceExpectedBucketOwnerHeader) == "" {
+		h.Set(sourceExpectedBucketOwnerHeader, owner)
+	}
+	sse, err := copySourceSSECustomerHeader(b.Config.SSECustomerKey)
+	if err != nil {
+		return err
+	}
+	for k, v := range sse {
+		if _, ok := h[k]; !ok {
+			h[k] = v
+		}
+	}
+	return nil
+}
+
+// CopyIf is like Copy but only copies the object at srcPath if it meets the conditions
+// of c, failing with ErrPreconditionFailed otherwise. The conditions take precedence
+// over any x-amz-copy-source-if-* headers in h.
+func (b *Bucket) CopyIf(srcPath, dstPath string, h http.Header, c CopyConditions) error {
+	h = cloneHeader(h)
+	c.setHeaders(h)
+	return b.Copy(srcPath, dstPath, h)
+}
+
+// copy copies the object of the x-amz-copy-source source, with the headers sh of its
+// HEAD, to dstPath.
+func (b *Bucket) copy(source string, sh http.Header, dstPath string, h http.Header) error {
+	size, err := strconv.ParseInt(sh.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("copy %s: invalid content-length: %s", source, err)
+	}
+	if size > maxPartSize {
+		return b.multipartCopy(source, sh, size, dstPath, h)
+	}
+
+	u, err := b.url(dstPath)
+	if err != nil {
+		return err
+	}
+	ch := cloneHeader(h)
+	ch.Set(copySourceHeader, source)
+	resp, err := b.retryRequest("PUT", u, nil, ch)
+	if err != nil {
+		return err
+	}
+	defer checkClose(resp.Body, err)
+	if resp.StatusCode != 200 {
+		return newRespError(resp)
+	}
+	_, err = decodeResult(resp)
+	return err
+}
+
+// CopyFrom copies the object at srcKey in srcBucket to dstKey in b, with its metadata.
+//
+// If both buckets are of the same S3 service and keys, and in the same region, the object
+// is copied within S3 as with Copy, with the source found by a HEAD request of srcBucket
+// and the expected owner and SSE-C key of the source of srcBucket's Config. Otherwise, such as for buckets of other accounts or
+// S3-compatible services, the object is streamed from a GetReader of srcBucket to a
+// PutWriter of b, and is not buffered locally beyond the parts in flight.
+func (b *Bucket) CopyFrom(srcBucket *Bucket, srcKey, dstKey string) error {
+	if b.sameService(srcBucket) {
+		return b.copyFrom(srcBucket, strings.TrimPrefix(srcKey, "/"), dstKey, nil)
+	}
+	c := *srcBucket.Config
+	c.DecompressGzip = false // copy the stored bytes
+	r, sh, err := srcBucket.withConfig(&c).GetReader(srcKey)
+	if err != nil {
+		return err
+	}
+	h := http.Header{}
+	copyMetadata(h, sh)
+	w, err := b.PutWriter(dstKey, h)
+	if err != nil {
+		r.Close()
+		return err
+	}
+	if _, err = io.Copy(w, r); err == nil {
+		err = r.Close() // verifies the object read before completing the put
+	} else {
+		r.Close()
+	}
+	if err != nil {
+		w.(MultipartWriter).Abort()
+		return err
+	}
+	return w.Close()
+}
+
+// UpdateMetadata replaces the metadata of the object at path by copying the object
+// onto itself with an x-amz-metadata-directive of REPLACE, without transferring its
+// data, e.g. to fix its Content-Type or Cache-Control.
+//
+// The content headers and x-amz-meta-* metadata in h are set, and the others of the
+// object are kept. The storage class and server-side encryption of the object, which a
+// copy would reset, are kept unless set in h. Other headers in h, such as x-amz-acl,
+// are added to the copy request: the ACL of the object is reset to private otherwise.
+func (b *Bucket) UpdateMetadata(path string, h http.Header) error {
+	sh, err := b.Head(path)
+	if err != nil {
+		return err
+	}
+	source, err := copySourceValue(b.Name, path)
+	if err != nil {
+		return err
+	}
+	ch := http.Header{}
+	copyMetadata(ch, sh)
+	for _, k := range []string{storageClassHeader, sseHeader, sseKMSKeyIDHeader, sseBucketKeyEnabledHeader} {
+		if v := sh.Get(k); v != "" {
+			ch.Set(k, v)
+		}
+	}
+	for k, v := range h {
+		ch[http.CanonicalHeaderKey(k)] = v
+	}
+	if err := b.setCopySource(ch); err != nil {
+		return err
+	}
+	if ch.Get(sseHeader) != sh.Get(sseHeader) && h.Get(sseKMSKeyIDHeader) == "" {
+		ch.Del(sseKMSKeyIDHeader) // of the encryption replaced
+	}
+	ch.Set(metadataDirectiveHeader, "REPLACE")
+	return b.copy(source, sh, strings.Split(path, "?")[0], ch) // to the latest version
+}
+
+// sameService reports whether objects of src can be copied to b within S3: they are
+// buckets of the same domain and keys, and signed for the same region. The keys are
+// not taken to mean the same account: the copies check the ExpectedBucketOwner of src.
+func (b *Bucket) sameService(src *Bucket) bool {
+	return b.S3.Domain() == src.S3.Domain() &&
+		b.S3.AccessKeyID() == src.S3.AccessKeyID() &&
+		b.signingRegion() == src.signingRegion()
+}
+
+// copyMetadata sets the headers of an object in dst to those of the object headers in
+// src that are copied with it: its content headers and user metadata.
+func copyMetadata(dst, src http.Header) {
+	for k := range src {
+		switch {
+		case k == "Content-Type", k == "Content-Encoding", k == "Content-Language",
+			k == "Content-Disposition", k == "Cache-Control", k == "Expires",
+			strings.HasPrefix(strings.ToLower(k), "x-amz-meta-"):
+			dst[k] = src[k]
+		}
+	}
+}
+
+// copySource returns the bucket and key named by a Copy source path.
+func (b *Bucket) copySource(srcPath string) (*Bucket, string, error) {
+	if !strings.HasPrefix(srcPath, "/") {
+		return b, srcPath, nil
+	}
+	s := strings.SplitN(srcPath[1:], "/", 2)
+	if len(s) != 2 || s[0] == "" || s[1] == "" {
+		return nil, "", fmt.Errorf("invalid copy source %q: expected /bucket/key", srcPath)
+	}
+	// the source bucket may be in another region, so its region is not copied
+	sb := &Bucket{S3: b.S3, Name: s[0], Config: b.Config}
+	return sb, s[1], nil
+}
+
+// copySourceValue returns the url-encoded x-amz-copy-source header value for bPath in bucket.
+func copySourceValue(bucket, bPath string) (string, error) {
+	purl, err := url.Parse(bPath)
+	if err != nil {
+		return "", err
+	}
+	v := purl.Query().Get(versionParam)
+	bPath = strings.Split(bPath, "?")[0]
+	segments := strings.Split(strings.TrimPrefix(bPath, "/"), "/")
+	for i, s := range segments {
+		segments[i] = strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+	}
+	source := "/" + bucket + "/" + strings.Join(segments, "/")
+	if v != "" {
+		source += "?" + versionParam + "=" + url.QueryEscape(v)
+	}
+	return source, nil
+}
+
+// multipartCopy copies an object of the given size with UploadPartCopy requests.
+// Unless x-amz-metadata-directive is REPLACE, the metadata of the source, whose
+// headers are in sh, is applied to the new object.
+func (b *Bucket) multipartCopy(source string, sh http.Header, size int64, dstPath string, h http.Header) (err error) {
+	u, err := b.url(dstPath)
+	if err != nil {
+		return err
+	}
+	ih := cloneHeader(h)
+	if !strings.EqualFold(ih.Get(metadataDirectiveHeader), "REPLACE") {
+		copyMetadata(ih, sh)
+	}
+	ih.Del(metadataDirectiveHeader)
+	ch := takePreconditions(ih)
+	sourceConds := http.Header{}
+	for k, v := range ih {
+		if strings.HasPrefix(k, "X-Amz-Copy-Source-") || k == sourceExpectedBucketOwnerHeader {
+			sourceConds[k] = v
+			ih.Del(k)
+		}
+	}
+	uploadID, err := b.initiateMultipart(u, ih)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if aerr := b.abortMultipart(u, uploadID); aerr != nil {
+				b.Config.logger().Printf("Error aborting multipart upload: %v\n", aerr)
+			}
+		}
+	}()
+
+	partSize := copyPartSize(size, b.Config.PartSize)
+	parts := make([]completedPart, (size+partSize-1)/partSize)
+	sem := make(chan struct{}, max(b.Config.Concurrency, 1))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for i := range parts {
+		start := int64(i) * partSize
+		end := min64(start+partSize, size) - 1
+		parts[i].PartNumber = i + 1
+		sem <- struct{}{}
+		mu.Lock()
+		failed := err != nil
+		mu.Unlock()
+		if failed {
+			<-sem
+			break // a part failed, do not copy the rest
+		}
+		wg.Add(1)
+		go func(p *completedPart) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			etag, perr := b.copyPart(u, uploadID, p.PartNumber, source, start, end, sourceConds)
+			mu.Lock()
+			defer mu.Unlock()
+			if perr != nil && err == nil {
+				err = perr
+			}
+			p.ETag = etag
+		}(&parts[i])
+	}
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	_, err = b.completeMultipart(u, uploadID, parts, ch)
+	return err
+}
+
+// copyPart copies bytes start-end of source into part n of the upload, with the
+// source preconditions and other headers of the source in conds.
+func (b *Bucket) copyPart(u *url.URL, uploadID string, n int, source string, start, end int64, conds http.Header) (etag string, err error) {
+	v := url.Values{}
+	v.Set("partNumber", strconv.Itoa(n))
+	v.Set("uploadId", uploadID)
+	h := cloneHeader(conds)
+	h.Set(copySourceHeader, source)
+	h.Set(copySourceRangeHeader, fmt.Sprintf("bytes=%d-%d", start, end))
+	resp, err := b.retryRequest("PUT", multipartURL(u, v), nil, h)
+	if err != nil {
+		return "", err
+	}
+	defer checkClose(resp.Body, err)
+	if resp.StatusCode != 200 {
+		return "", newRespError(resp)
+	}
+	res, err := decodeResult(resp)
+	if err != nil {
+		return "", err
+	}
+	return res.ETag, nil
+}
+
+// copyPartSize returns a part size of at least partSize that copies size bytes
+// within the part count and part size limits.
+func copyPartSize(size, partSize int64) int64 {
+	partSize = max64(partSize, minPartSize)
+	for (size+partSize-1)/partSize > maxNPart {
+		partSize *= 2
+	}
+	return min64(partSize, maxPartSize)
+}
+
+func cloneHeader(h http.Header) http.Header {
+	c := make(http.Header, len(h))
+	for k, v := range h {
+		c[http.CanonicalHeaderKey(k)] = append([]string(nil), v...)
+	}
+	return c
+}
@@ -0,0 +1,386 @@
+package s3gof3r
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCopy(t *testing.T) {
+	var mu sync.Mutex
+	var sources []string
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case "HEAD":
+			w.Header().Set("Content-Length", "1024")
+		case "PUT":
+			sources = append(sources, r.Header.Get(copySourceHeader))
+			if r.URL.Path == "/bucket/fail" {
+				// errors may be returned under a 200
+				io.WriteString(w, `<Error><Code>InternalError</Code><Message>We encountered an internal error.</Message></Error>`)
+				return
+			}
+			if r.Header.Get("x-amz-metadata-directive") != "REPLACE" {
+				t.Errorf("metadata directive not sent")
+			}
+			io.WriteString(w, `<CopyObjectResult><ETag>"abc"</ETag></CopyObjectResult>`)
+		}
+	}))
+
+	h := http.Header{}
+	h.Set("x-amz-metadata-directive", "REPLACE")
+	var copyTests = []struct {
+		src    string
+		dst    string
+		source string
+		err    string
+	}{
+		{"src key", "dst", "/bucket/src%20key", ""},
+		{"/other/dir/src+key?versionId=v1", "dst", "/other/dir/src%2Bkey?versionId=v1", ""},
+		{"/other", "dst", "", "invalid copy source"},
+		{"src", "fail", "/bucket/src", "internal error"},
+	}
+	for _, tt := range copyTests {
+		sources = nil
+		err := b.Copy(tt.src, tt.dst, h)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: expected error %q, got %v", tt.src, tt.err, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+		}
+		if tt.source != "" && (len(sources) != 1 || sources[0] != tt.source) {
+			t.Errorf("%s: expected copy source %s, got %v", tt.src, tt.source, sources)
+		}
+	}
+}
+
+func TestMultipartCopy(t *testing.T) {
+	size := 6 * gb
+	var mu sync.Mutex
+	var ranges []string
+	var completed string
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		q := r.URL.Query()
+		switch {
+		case r.Method == "HEAD":
+			w.Header().Set("Content-Length", fmt.Sprint(size))
+			w.Header().Set("X-Amz-Meta-Foo", "bar")
+		case r.Method == "POST" && q.Has("uploads"):
+			if r.Header.Get("X-Amz-Meta-Foo") != "bar" {
+				t.Error("source metadata not copied")
+			}
+			if r.Header.Get("If-None-Match") != "" || r.Header.Get("X-Amz-Copy-Source-If-Match") != "" {
+				t.Error("preconditions sent on initiation")
+			}
+			io.WriteString(w, `<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`)
+		case r.Method == "PUT":
+			if r.Header.Get("X-Amz-Copy-Source-If-Match") != `"src"` {
+				t.Error("source precondition not sent with part")
+			}
+			ranges = append(ranges, r.Header.Get(copySourceRangeHeader))
+			fmt.Fprintf(w, `<CopyPartResult><ETag>"etag%s"</ETag></CopyPartResult>`, q.Get("partNumber"))
+		case r.Method == "POST" && q.Get("uploadId") == "upload-1":
+			body, _ := ioutil.ReadAll(r.Body)
+			completed = string(body)
+			if r.Header.Get("If-None-Match") != "*" {
+				t.Error("precondition not sent on completion")
+			}
+			io.WriteString(w, `<CompleteMultipartUploadResult><ETag>"abc-3"</ETag></CompleteMultipartUploadResult>`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	b.Config.PartSize = 2 * gb
+
+	h := http.Header{}
+	h.Set("x-amz-copy-source-if-match", `"src"`)
+	h.Set("If-None-Match", "*")
+	if err := b.Copy("src", "dst", h); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(ranges)
+	expect := []string{"bytes=0-2147483647", "bytes=2147483648-4294967295", "bytes=4294967296-6442450943"}
+	if strings.Join(ranges, ",") != strings.Join(expect, ",") {
+		t.Errorf("expected ranges %v, got %v", expect, ranges)
+	}
+	if !strings.Contains(completed, `<Part><PartNumber>3</PartNumber><ETag>&#34;etag3&#34;</ETag></Part>`) {
+		t.Errorf("unexpected complete request: %s", completed)
+	}
+}
+
+// TestMultipartCopyFailedPart copies no more parts once a part fails.
+func TestMultipartCopyFailedPart(t *testing.T) {
+	size := 6 * gb
+	var mu sync.Mutex
+	var parts []string
+	var aborted bool
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		q := r.URL.Query()
+		switch {
+		case r.Method == "HEAD":
+			w.Header().Set("Content-Length", fmt.Sprint(size))
+		case r.Method == "POST" && q.Has("uploads"):
+			io.WriteString(w, `<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`)
+		case r.Method == "PUT":
+			parts = append(parts, q.Get("partNumber"))
+			if q.Get("partNumber") == "2" {
+				w.WriteHeader(403)
+				io.WriteString(w, `<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`)
+				return
+			}
+			fmt.Fprintf(w, `<CopyPartResult><ETag>"etag%s"</ETag></CopyPartResult>`, q.Get("partNumber"))
+		case r.Method == "DELETE" && q.Get("uploadId") == "upload-1":
+			aborted = true
+			w.WriteHeader(204)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	b.Config.PartSize = gb
+	b.Config.Concurrency = 1
+
+	if err := b.Copy("src", "dst", nil); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Expected error: %v. Actual: %v", ErrAccessDenied, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(parts, ",") != "1,2" {
+		t.Errorf("Expected parts requested: 1,2. Actual: %v", parts)
+	}
+	if !aborted {
+		t.Error("Expected upload aborted")
+	}
+}
+
+func TestCopyFrom(t *testing.T) {
+	s := newFakeS3()
+	b := newTestBucket(t, s)
+	b.Config.Md5Check = false
+	h := http.Header{}
+	h.Set("Content-Type", "text/csv")
+	h.Set("X-Amz-Meta-Source", "test")
+	w, err := b.PutWriter("src", h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("a,b\n"), int(2*mb))
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var copies int
+	s.check = func(r *http.Request) {
+		if r.Header.Get(copySourceHeader) != "" {
+			copies++
+		}
+	}
+	// buckets of the same service are copied within S3
+	same := &Bucket{S3: b.S3, Name: "other", Config: b.Config}
+	if err := same.CopyFrom(b, "src", "dst"); err != nil {
+		t.Fatal(err)
+	}
+	if copies != 1 {
+		t.Errorf("Expected copy requests: 1. Actual: %d", copies)
+	}
+	// buckets of other services are streamed
+	other := newFakeS3()
+	ob := newTestBucket(t, other)
+	ob.Config.Md5Check = false
+	if err := ob.CopyFrom(b, "src", "dst"); err != nil {
+		t.Fatal(err)
+	}
+	if copies != 1 {
+		t.Errorf("Expected streamed copy. Actual: %d copy requests", copies)
+	}
+	for _, obj := range []struct {
+		s    *fakeS3
+		path string
+	}{{s, "/other/dst"}, {other, "/bucket/dst"}} {
+		got, ok := obj.s.object(obj.path)
+		if !ok || !bytes.Equal(got, data) {
+			t.Errorf("%s: Expected copied data", obj.path)
+		}
+		obj.s.mu.Lock()
+		ch := obj.s.headers[obj.path]
+		obj.s.mu.Unlock()
+		if ch.Get("Content-Type") != "text/csv" || ch.Get("X-Amz-Meta-Source") != "test" {
+			t.Errorf("%s: Expected copied metadata. Actual: %v", obj.path, ch)
+		}
+	}
+}
+
+// TestCopyFromConfig copies within S3 from a bucket of the same service whose Config
+// differs, which is used for the source: its HEAD, expected owner and SSE-C key.
+func TestCopyFromConfig(t *testing.T) {
+	s := newFakeS3()
+	s.objects["/src/key"] = []byte("data")
+	var copies int
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "HEAD":
+			if o := r.Header.Get(expectedBucketOwnerHeader); o != "111122223333" {
+				t.Errorf("Expected source bucket owner of the head: 111122223333. Actual: %q", o)
+			}
+			if r.Header.Get(sseCustomerKeyHeader) == "" {
+				t.Error("Expected SSE-C key of the source with the head")
+			}
+		case r.Header.Get(copySourceHeader) != "":
+			copies++
+			if o := r.Header.Get(expectedBucketOwnerHeader); o != "444455556666" {
+				t.Errorf("Expected bucket owner of the copy: 444455556666. Actual: %q", o)
+			}
+			if o := r.Header.Get(sourceExpectedBucketOwnerHeader); o != "111122223333" {
+				t.Errorf("Expected source bucket owner of the copy: 111122223333. Actual: %q", o)
+			}
+			if r.Header.Get("X-Amz-Copy-Source-Server-Side-Encryption-Customer-Key") == "" || r.Header.Get(sseCustomerKeyHeader) != "" {
+				t.Errorf("Expected SSE-C key of the source only. Actual: %v", r.Header)
+			}
+		}
+		s.ServeHTTP(w, r)
+	}))
+	b.Config.ExpectedBucketOwner = "444455556666"
+	b.Config.Md5Check = false
+	c := *b.Config
+	c.ExpectedBucketOwner = "111122223333"
+	c.SSECustomerKey = bytes.Repeat([]byte("k"), 32)
+	src := &Bucket{S3: b.S3, Name: "src", Config: &c}
+
+	if err := b.CopyFrom(src, "key", "dst"); err != nil {
+		t.Fatal(err)
+	}
+	if copies != 1 {
+		t.Errorf("Expected copy requests: 1. Actual: %d", copies)
+	}
+	if got, ok := s.object("/bucket/dst"); !ok || string(got) != "data" {
+		t.Errorf("Expected copied data. Actual: %q", got)
+	}
+}
+
+func TestUpdateMetadata(t *testing.T) {
+	var put http.Header
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "HEAD":
+			w.Header().Set("Content-Length", "4")
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("X-Amz-Meta-Owner", "me")
+			w.Header().Set(storageClassHeader, StorageClassStandardIA)
+			w.Header().Set(sseHeader, SSEAlgorithmKMS)
+			w.Header().Set(sseKMSKeyIDHeader, "key-id")
+		case "PUT":
+			if r.URL.Path != "/bucket/dir/key" || r.URL.RawQuery != "" {
+				t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			}
+			put = r.Header
+			io.WriteString(w, `<CopyObjectResult><ETag>"abc"</ETag></CopyObjectResult>`)
+		}
+	}))
+
+	var updateTests = []struct {
+		path   string
+		h      http.Header
+		expect map[string]string
+	}{
+		{"dir/key", http.Header{"Content-Type": {"application/json"}, "cache-control": {"max-age=60"}}, map[string]string{
+			copySourceHeader:        "/bucket/dir/key",
+			metadataDirectiveHeader: "REPLACE",
+			"Content-Type":          "application/json",
+			"Cache-Control":         "max-age=60",
+			"X-Amz-Meta-Owner":      "me",
+			storageClassHeader:      StorageClassStandardIA,
+			sseHeader:               SSEAlgorithmKMS,
+			sseKMSKeyIDHeader:       "key-id",
+		}},
+		{"dir/key?versionId=v1", http.Header{sseHeader: {SSEAlgorithmAES256}, storageClassHeader: {StorageClassStandard}}, map[string]string{
+			copySourceHeader:   "/bucket/dir/key?versionId=v1",
+			"Content-Type":     "text/plain",
+			"X-Amz-Meta-Owner": "me",
+			storageClassHeader: StorageClassStandard,
+			sseHeader:          SSEAlgorithmAES256,
+			sseKMSKeyIDHeader:  "",
+		}},
+	}
+	for _, tt := range updateTests {
+		put = nil
+		if err := b.UpdateMetadata(tt.path, tt.h); err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range tt.expect {
+			if got := put.Get(k); got != v {
+				t.Errorf("%s: Expected %s: %q. Actual: %q", tt.path, k, v, got)
+			}
+		}
+	}
+}
+
+func TestCopyIf(t *testing.T) {
+	var conds http.Header
+	var status int
+	var body string
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "HEAD":
+			w.Header().Set("Content-Length", "1024")
+		case "PUT":
+			conds = http.Header{}
+			for k, v := range r.Header {
+				if strings.HasPrefix(k, "X-Amz-Copy-Source-If-") {
+					conds[k] = v
+				}
+			}
+			w.WriteHeader(status)
+			io.WriteString(w, body)
+		}
+	}))
+	c := CopyConditions{
+		IfMatch:           "abc",
+		IfNoneMatch:       `"def"`,
+		IfModifiedSince:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		IfUnmodifiedSince: time.Date(2024, 2, 3, 4, 5, 6, 0, time.FixedZone("CET", 3600)),
+	}
+	precondition := `<Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message></Error>`
+	for _, tt := range []struct {
+		status int
+		body   string
+		err    error
+	}{
+		{200, `<CopyObjectResult><ETag>"abc"</ETag></CopyObjectResult>`, nil},
+		{412, precondition, ErrPreconditionFailed},
+		{200, precondition, ErrPreconditionFailed}, // under a 200
+	} {
+		status, body = tt.status, tt.body
+		err := b.CopyIf("src", "dst", nil, c)
+		if tt.err == nil && err != nil || tt.err != nil && !errors.Is(err, tt.err) {
+			t.Errorf("%d: Expected error: %v. Actual: %v", tt.status, tt.err, err)
+		}
+		expect := http.Header{
+			"X-Amz-Copy-Source-If-Match":            {`"abc"`},
+			"X-Amz-Copy-Source-If-None-Match":       {`"def"`},
+			"X-Amz-Copy-Source-If-Modified-Since":   {"Tue, 02 Jan 2024 03:04:05 GMT"},
+			"X-Amz-Copy-Source-If-Unmodified-Since": {"Sat, 03 Feb 2024 03:05:06 GMT"},
+		}
+		if !reflect.DeepEqual(conds, expect) {
+			t.Errorf("Expected conditions: %v. Actual: %v", expect, conds)
+		}
+	}
+}
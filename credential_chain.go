@@ -0,0 +1,73 @@
+package s3gof3r
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// A KeySource is a named source of AWS keys tried by ChainKeys.
+type KeySource struct {
+	Name string
+	Keys func() (*Keys, error)
+}
+
+// DefaultKeySources are the sources tried by DefaultKeys, in order.
+// Sources may be removed or reordered to skip them.
+var DefaultKeySources = []KeySource{
+	{"environment", EnvKeys},
+	{"web identity", WebIdentityKeys},
+	{"shared credentials file", func() (*Keys, error) { return SharedKeys("") }},
+	{"container", ContainerKeys},
+	{"instance metadata", defaultInstanceKeys},
+}
+
+// DefaultKeys returns the first AWS keys found in the environment, from the web
+// identity token of the environment, the shared credentials file for the active
+// profile, the ECS container credentials or the EC2 instance metadata.
+// This mirrors the credential resolution of the AWS command line tools.
+//
+// Instance metadata is skipped if AWS_EC2_METADATA_DISABLED is set to "true".
+func DefaultKeys() (*Keys, error) {
+	return ChainKeys(DefaultKeySources...)
+}
+
+// ChainKeys returns the keys of the first source that provides them.
+// If every source fails, the error is a *ChainError recording why.
+func ChainKeys(sources ...KeySource) (*Keys, error) {
+	cerr := new(ChainError)
+	for _, s := range sources {
+		k, err := s.Keys()
+		if err == nil {
+			logger.debugPrintf("using keys from %s", s.Name)
+			return k, nil
+		}
+		cerr.Sources = append(cerr.Sources, s.Name)
+		cerr.Errors = append(cerr.Errors, err)
+	}
+	return nil, cerr
+}
+
+// ChainError is returned by ChainKeys when no source provides keys.
+// Errors[i] is the error returned by the source named Sources[i].
+type ChainError struct {
+	Sources []string
+	Errors  []error
+}
+
+func (e *ChainError) Error() string {
+	msgs := make([]string, len(e.Sources))
+	for i, s := range e.Sources {
+		msgs[i] = s + ": " + e.Errors[i].Error()
+	}
+	return "no AWS keys found: " + strings.Join(msgs, "; ")
+}
+
+var errMetadataDisabled = errors.New("disabled by AWS_EC2_METADATA_DISABLED")
+
+func defaultInstanceKeys() (*Keys, error) {
+	if strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		return nil, errMetadataDisabled
+	}
+	return InstanceKeys()
+}
@@ -0,0 +1,56 @@
+package s3gof3r
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestChainKeys(t *testing.T) {
+	want := &Keys{accessKeyID: "AKIDEXAMPLE"}
+	called := false
+	k, err := ChainKeys(
+		KeySource{"first", func() (*Keys, error) { return nil, errors.New("no first") }},
+		KeySource{"second", func() (*Keys, error) { return want, nil }},
+		KeySource{"third", func() (*Keys, error) { called = true; return nil, nil }},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k != want {
+		t.Errorf("expected keys from second source, got %v", k)
+	}
+	if called {
+		t.Error("source tried after keys were found")
+	}
+
+	_, err = ChainKeys(
+		KeySource{"first", func() (*Keys, error) { return nil, errors.New("no first") }},
+		KeySource{"second", func() (*Keys, error) { return nil, errors.New("no second") }},
+	)
+	cerr, ok := err.(*ChainError)
+	if !ok {
+		t.Fatalf("expected *ChainError, got %v", err)
+	}
+	if len(cerr.Errors) != 2 {
+		t.Errorf("expected 2 errors, got %d", len(cerr.Errors))
+	}
+	expErr := "no AWS keys found: first: no first; second: no second"
+	if err.Error() != expErr {
+		t.Errorf("Expected error: %v. Actual: %v", expErr, err)
+	}
+}
+
+func TestDefaultKeysMetadataDisabled(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", "")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", t.TempDir()+"/missing")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", "")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	_, err := DefaultKeys()
+	if err == nil || !strings.Contains(err.Error(), "instance metadata: "+errMetadataDisabled.Error()) {
+		t.Errorf("expected instance metadata to be skipped, got %v", err)
+	}
+}
@@ -1,14 +1,17 @@
 package s3gof3r
 
 import (
-	"bytes"
 	"crypto/md5"
 	"encoding/base64"
 	"encoding/xml"
-	"io/ioutil"
+	"fmt"
 	"net/http"
+	"sync"
 )
 
+// maxDeleteKeys is the most keys S3 deletes in a single multi-object delete request
+const maxDeleteKeys = 1000
+
 type deleteObject struct {
 	Key       string `xml:"Key"`
 	VersionId string `xml:"VersionId,omitempty"`
@@ -20,6 +23,7 @@ type deleteRequest struct {
 	Quiet   bool           `xml:"Quiet"`
 }
 
+// DeletedObject is a key deleted by a multi-object delete
 type DeletedObject struct {
 	Key                   string `xml:"Key"`
 	VersionId             string `xml:"VersionId"`
@@ -27,22 +31,65 @@ type DeletedObject struct {
 	DeleteMarkerVersionId string `xml:"DeleteMarkerVersionId"`
 }
 
+// DeleteError is a key that a multi-object delete failed to delete, with the S3 error
+// code and message of the failure, e.g. AccessDenied.
 type DeleteError struct {
-	Key     string `xml:"Key"`
-	Code    string `xml:"Code"`
-	Message string `xml:"Message"`
+	Key       string `xml:"Key"`
+	VersionId string `xml:"VersionId"`
+	Code      string `xml:"Code"`
+	Message   string `xml:"Message"`
+}
+
+func (e DeleteError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", e.Key, e.Code, e.Message)
+}
+
+// DeleteErrors is the error of a multi-object delete that failed to delete some keys
+type DeleteErrors []DeleteError
+
+func (e DeleteErrors) Error() string {
+	if len(e) == 1 {
+		return "key not deleted: " + e[0].Error()
+	}
+	return fmt.Sprintf("%d keys not deleted, first %s", len(e), e[0])
+}
+
+// Keys returns the keys that were not deleted, e.g. to retry deleting them.
+func (e DeleteErrors) Keys() []string {
+	keys := make([]string, len(e))
+	for i := range e {
+		keys[i] = e[i].Key
+	}
+	return keys
 }
 
+// DeleteResult is the result of a multi-object delete. Deleted has the keys deleted,
+// unless the delete was quiet, and Errors the keys that failed to be deleted.
 type DeleteResult struct {
 	XMLName xml.Name        `xml:"DeleteResult"`
 	Deleted []DeletedObject `xml:"Deleted"`
 	Errors  []DeleteError   `xml:"Error"`
 }
 
+// Err returns the Errors of the result as DeleteErrors, or nil if every key was deleted.
+func (r DeleteResult) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	return DeleteErrors(r.Errors)
+}
+
 func deleteMultiple(bucket *Bucket, quiet bool, keys []string) (DeleteResult, error) {
 	if len(keys) == 0 {
 		return DeleteResult{}, nil
 	}
+	if bucket.Config.DryRun {
+		res := DeleteResult{Deleted: make([]DeletedObject, len(keys))}
+		for i, key := range keys {
+			res.Deleted[i].Key = key
+		}
+		return res, nil
+	}
 
 	u, err := bucket.url("")
 	if err != nil {
@@ -66,17 +113,9 @@ func deleteMultiple(bucket *Bucket, quiet bool, keys []string) (DeleteResult, er
 	}
 
 	md5sum := md5.Sum(body)
-	r := http.Request{
-		Method:        "POST",
-		URL:           u,
-		Body:          ioutil.NopCloser(bytes.NewReader(body)),
-		ContentLength: int64(len(body)),
-		Header:        make(http.Header),
-	}
-	r.Header.Set(md5Header, base64.StdEncoding.EncodeToString(md5sum[:]))
-	bucket.Sign(&r)
-
-	resp, err := bucket.Do(&r)
+	h := make(http.Header)
+	h.Set(md5Header, base64.StdEncoding.EncodeToString(md5sum[:]))
+	resp, err := bucket.retryRequest("POST", u, body, h)
 	if err != nil {
 		return DeleteResult{}, err
 	}
@@ -93,3 +132,38 @@ func deleteMultiple(bucket *Bucket, quiet bool, keys []string) (DeleteResult, er
 
 	return result, nil
 }
+
+// deleteAll deletes keys in batches of at most maxDeleteKeys, up to concurrency at a time.
+// The results of the batches are combined in the order of keys, and the first error of any
+// batch is returned with the results of the others.
+func deleteAll(bucket *Bucket, quiet bool, keys []string, concurrency int) (DeleteResult, error) {
+	results := make([]DeleteResult, (len(keys)+maxDeleteKeys-1)/maxDeleteKeys)
+	sem := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var err error
+	for i := range results {
+		batch := keys[i*maxDeleteKeys : min((i+1)*maxDeleteKeys, len(keys))]
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(res *DeleteResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			r, derr := deleteMultiple(bucket, quiet, batch)
+			mu.Lock()
+			defer mu.Unlock()
+			if derr != nil && err == nil {
+				err = derr
+			}
+			*res = r
+		}(&results[i])
+	}
+	wg.Wait()
+
+	var result DeleteResult
+	for _, r := range results {
+		result.Deleted = append(result.Deleted, r.Deleted...)
+		result.Errors = append(result.Errors, r.Errors...)
+	}
+	return result, err
+}
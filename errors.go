@@ -0,0 +1,89 @@
+package s3gof3r
+
+import "errors"
+
+// ErrNotModified is returned by conditional gets when the object has not been
+// modified, i.e. the response status is 304 Not Modified.
+var ErrNotModified = errors.New("object not modified")
+
+// ErrNoRegion is returned for requests of buckets whose region can't be found from
+// the domain of the S3 service or the AWS_REGION environment variable.
+var ErrNoRegion = errors.New("can't find endpoint region: set AWS_REGION or use NewWithRegion")
+
+// ErrNoCredentials is returned, without sending a request, for buckets whose access key
+// id or secret access key is empty, e.g. as none were found by EnvKeys or InstanceKeys.
+var ErrNoCredentials = errors.New("no credentials: set the access key id and secret access key")
+
+// ErrIntegrity is matched by the errors of puts whose ETag, returned by S3, doesn't
+// match the ETag computed from the data put, i.e. the data stored isn't the data written,
+// and of gets whose parts don't match their checksums.
+var ErrIntegrity = errors.New("integrity check failed")
+
+// ErrMd5Missing is returned by VerifyMd5 for objects without an md5 file, and
+// ErrMd5Orphaned for md5 files whose object doesn't exist.
+var (
+	ErrMd5Missing  = errors.New("md5 file missing")
+	ErrMd5Orphaned = errors.New("md5 file orphaned")
+)
+
+// ErrMd5NotStored is matched by the errors of puts with Md5Check whose object was
+// stored, but whose md5 file couldn't be put after it. The object is complete, and its
+// Result is set; RepairMd5 puts its md5 file.
+var ErrMd5NotStored = errors.New("object stored, but not its md5 file")
+
+// Errors matched by a *RespError with errors.Is, according to the S3 error code of the
+// response, or its status code for responses without a body such as HEAD.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrAccessDenied = errors.New("access denied")
+
+	// ErrBucketOwnerMismatch is matched by the AccessDenied errors of requests sent with
+	// the ExpectedBucketOwner of a Config, which S3 returns for buckets owned by another
+	// account, as for requests denied otherwise.
+	ErrBucketOwnerMismatch = errors.New("access denied: bucket may not be owned by the expected bucket owner")
+
+	// ErrInvalidCredentials is returned for requests whose access key id doesn't exist,
+	// or whose signature doesn't match the one computed by S3 with the secret access key.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+
+	// ErrPreconditionFailed is returned by puts and copies whose If-Match, If-None-Match
+	// or x-amz-copy-source-if-* precondition headers were not met.
+	ErrPreconditionFailed = errors.New("precondition failed")
+
+	// ErrRestoreInProgress is returned by Restore for objects being restored already.
+	ErrRestoreInProgress = errors.New("restore in progress")
+)
+
+// errorCodes are the S3 error codes matching each error
+var errorCodes = map[error][]string{
+	ErrNotFound:           {"NoSuchKey", "NoSuchVersion"},
+	ErrAccessDenied:       {"AccessDenied"},
+	ErrInvalidCredentials: {"SignatureDoesNotMatch", "InvalidAccessKeyId"},
+	ErrPreconditionFailed: {"PreconditionFailed"},
+	ErrRestoreInProgress:  {"RestoreAlreadyInProgress"},
+}
+
+// errorStatus are the status codes matching each error, for responses without an S3 error code
+var errorStatus = map[error]int{
+	ErrNotFound:           404,
+	ErrAccessDenied:       403,
+	ErrPreconditionFailed: 412,
+}
+
+// Is reports whether e matches target, e.g. errors.Is(err, ErrNotFound) for
+// a NoSuchKey error.
+func (e *RespError) Is(target error) bool {
+	if target == ErrBucketOwnerMismatch {
+		return e.expectedBucketOwner && e.Is(ErrAccessDenied)
+	}
+	if e.Code == "" {
+		s, ok := errorStatus[target]
+		return ok && s == e.StatusCode
+	}
+	for _, c := range errorCodes[target] {
+		if c == e.Code {
+			return true
+		}
+	}
+	return false
+}
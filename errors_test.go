@@ -0,0 +1,116 @@
+package s3gof3r
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+var respErrorIsTests = []struct {
+	status   int
+	body     string
+	notFound bool
+	denied   bool
+	invalid  bool
+}{
+	{404, `<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`, true, false, false},
+	{404, `<Error><Code>NoSuchBucket</Code><Message>The specified bucket does not exist</Message></Error>`, false, false, false},
+	{404, ``, true, false, false},
+	{403, `<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`, false, true, false},
+	{403, `<Error><Code>SignatureDoesNotMatch</Code></Error>`, false, false, true},
+	{403, `<Error><Code>InvalidAccessKeyId</Code></Error>`, false, false, true},
+	{403, ``, false, true, false},
+	{503, `<Error><Code>SlowDown</Code></Error>`, false, false, false},
+}
+
+func TestRespErrorIs(t *testing.T) {
+	for _, tt := range respErrorIsTests {
+		b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			fmt.Fprint(w, tt.body)
+		}))
+		b.Config.NTry = 1
+		_, _, err := b.GetReader("key")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if errors.Is(err, ErrNotFound) != tt.notFound {
+			t.Errorf("%d %s: expected ErrNotFound %v, got %v", tt.status, tt.body, tt.notFound, err)
+		}
+		if errors.Is(err, ErrAccessDenied) != tt.denied {
+			t.Errorf("%d %s: expected ErrAccessDenied %v, got %v", tt.status, tt.body, tt.denied, err)
+		}
+		if errors.Is(err, ErrInvalidCredentials) != tt.invalid {
+			t.Errorf("%d %s: expected ErrInvalidCredentials %v, got %v", tt.status, tt.body, tt.invalid, err)
+		}
+	}
+}
+
+func TestRespErrorAs(t *testing.T) {
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Amz-Request-Id", "header-id")
+		if r.Method == "HEAD" {
+			w.Header().Set("X-Amz-Id-2", "host-id")
+			w.WriteHeader(404)
+			return
+		}
+		w.WriteHeader(404)
+		fmt.Fprint(w, `<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message>`+
+			`<Resource>/bucket/key</Resource><RequestId>req-id</RequestId><HostId>host</HostId></Error>`)
+	}))
+
+	_, _, err := b.GetReader("key")
+	var rerr *RespError
+	if !errors.As(fmt.Errorf("wrapped: %w", err), &rerr) {
+		t.Fatalf("expected *RespError, got %T", err)
+	}
+	expect := RespError{
+		Code:       "NoSuchKey",
+		Message:    "The specified key does not exist.",
+		Resource:   "/bucket/key",
+		RequestID:  "req-id",
+		HostID:     "host",
+		StatusCode: 404,
+	}
+	if *rerr != expect {
+		t.Errorf("Expected: %+v. Actual: %+v", expect, *rerr)
+	}
+	if s := rerr.Error(); s != `404 NoSuchKey: "The specified key does not exist." (request id: req-id)` {
+		t.Errorf("unexpected error message: %s", s)
+	}
+
+	_, err = b.Head("key")
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected *RespError, got %T", err)
+	}
+	if rerr.RequestID != "header-id" || rerr.HostID != "host-id" || rerr.Message != "Not Found" {
+		t.Errorf("unexpected error from headers: %+v", *rerr)
+	}
+}
+
+func TestNoCredentials(t *testing.T) {
+	var requests int
+	for _, keys := range []*Keys{{}, {accessKeyID: "AKIDEXAMPLE"}, {secretAccessKey: "secret"}} {
+		b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+		}))
+		b.S3 = New(b.S3.Domain(), keys)
+		if _, _, err := b.GetReader("key"); !errors.Is(err, ErrNoCredentials) {
+			t.Errorf("Expected get error: %v. Actual: %v", ErrNoCredentials, err)
+		}
+		if _, err := b.PutWriter("key", nil); !errors.Is(err, ErrNoCredentials) {
+			t.Errorf("Expected put error: %v. Actual: %v", ErrNoCredentials, err)
+		}
+		if _, err := b.Head("key"); !errors.Is(err, ErrNoCredentials) {
+			t.Errorf("Expected head error: %v. Actual: %v", ErrNoCredentials, err)
+		}
+		if _, err := b.PresignGet("key", time.Minute); !errors.Is(err, ErrNoCredentials) {
+			t.Errorf("Expected presign error: %v. Actual: %v", ErrNoCredentials, err)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("Expected no requests. Actual: %d", requests)
+	}
+}
@@ -0,0 +1,186 @@
+package s3gof3r
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// DownloadFile gets the object at path into the local file at localPath, which is
+// created or truncated. The md5 or checksums of the object are verified as by the
+// reader of GetReader, and the file is removed if the download fails. The
+// modification time of the file is set to the Last-Modified time of the object.
+func (b *Bucket) DownloadFile(path, localPath string) (err error) {
+	r, h, err := b.GetReader(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(localPath)
+	if err != nil {
+		r.Close()
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(localPath)
+		}
+	}()
+	if _, err = io.Copy(f, r); err != nil {
+		r.Close()
+		return err
+	}
+	if err = r.Close(); err != nil { // verifies the md5 or checksums
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if t, terr := http.ParseTime(h.Get("Last-Modified")); terr == nil {
+		os.Chtimes(localPath, t, t)
+	}
+	return nil
+}
+
+// DownloadToWriterAt gets the object at path with concurrent ranged requests, writing
+// each range to w at its offset as soon as it's got. Unlike GetReader, ranges got
+// before earlier ranges are not buffered until those are read, so only Concurrency
+// ranges are held in memory, e.g. to download huge objects to an *os.File.
+//
+// The md5 or checksums of the object are verified once all the ranges are written, by
+// reading the data back from w, so w must also be an io.ReaderAt unless Md5Check and
+// ChecksumAlgorithm are unset. The checksums of the parts are verified as they are got,
+// as by GetReader.
+func (b *Bucket) DownloadToWriterAt(path string, w io.WriterAt) error {
+	if path == "" {
+		return errors.New("empty path requested")
+	}
+	u, err := b.url(path)
+	if err != nil {
+		return err
+	}
+	r, _, err := newGetter(context.Background(), *u, b, getOptions{length: -1, w: w})
+	if err != nil {
+		return err
+	}
+	g := r.(*getter)
+	if err := g.writeAll(); err != nil {
+		g.Close()
+		return err
+	}
+	return g.Close()
+}
+
+// UploadFile puts the local file at localPath to path, with the headers in h as for
+// PutWriter, and returns the ETag of the object. The Content-Type is detected from
+// the extension or content of the file if h has none, as for DetectContentType.
+//
+// As the size of the file is known, the part size is raised if needed to upload the
+// file in at most 10000 parts, instead of growing during the upload. The multipart
+// upload is aborted if the upload fails. The etag of the object is returned with
+// ErrMd5NotStored errors, as the object was stored.
+func (b *Bucket) UploadFile(localPath, path string, h http.Header) (etag string, err error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	c := *b.Config
+	c.DetectContentType = true
+	c.PartSize = max64(c.PartSize, (fi.Size()+maxNPart-1)/maxNPart)
+	c.MaxPartSize = c.PartSize // no growth
+	w, err := b.withConfig(&c).PutWriter(path, h)
+	if err != nil {
+		return "", err
+	}
+	p := w.(*putter)
+	if _, err = io.Copy(p, f); err != nil {
+		p.setErr(err) // abort instead of completing the upload with partial data
+		p.Close()
+		return "", err
+	}
+	if err = p.Close(); err != nil && !errors.Is(err, ErrMd5NotStored) {
+		return "", err
+	}
+	return p.Result().ETag, err
+}
+
+// UploadReaderAt puts size bytes of r, such as an *os.File or a *bytes.Reader, to path,
+// with the headers in h as for PutWriter. Unlike the data written to a PutWriter, which
+// is read one part at a time, the parts are read from r by offset and uploaded
+// concurrently, Concurrency at a time from the start.
+//
+// The part size is that of the config, lowered as needed for the object to be uploaded
+// in at least Concurrency parts, down to the 5 MB minimum, or raised for at most 10000
+// parts. The multipart upload is aborted if the upload fails. With CompressGzip, whose
+// parts can't be read by offset, r is uploaded as with PutWriter instead.
+func (b *Bucket) UploadReaderAt(path string, r io.ReaderAt, size int64, h http.Header) error {
+	c := *b.Config
+	c.PartSize = readerAtPartSize(&c, size)
+	c.MaxPartSize = c.PartSize // no growth
+	w, err := b.withConfig(&c).PutWriter(path, h)
+	if err != nil {
+		return err
+	}
+	p := w.(*putter)
+	if size <= p.bufsz || p.zw != nil {
+		if _, err = io.Copy(p, io.NewSectionReader(r, 0, size)); err != nil {
+			p.setErr(err) // abort instead of completing the upload with partial data
+			p.Close()
+			return err
+		}
+		return p.Close()
+	}
+	return p.putReaderAt(r, size)
+}
+
+// readerAtPartSize returns the part size of an UploadReaderAt of size bytes with c
+func readerAtPartSize(c *Config, size int64) int64 {
+	partSize := min64(c.PartSize, (size+int64(max(c.Concurrency, 1))-1)/int64(max(c.Concurrency, 1)))
+	return max64(max64(partSize, minPartSize), (size+maxNPart-1)/maxNPart)
+}
+
+// GetBytes gets the object at path into memory with the parallel requests of GetReader,
+// verifying its md5 or checksums, and returns its data and headers, e.g. for small
+// configuration objects.
+func (b *Bucket) GetBytes(path string) (data []byte, h http.Header, err error) {
+	r, h, err := b.GetReader(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var buf bytes.Buffer
+	if n, perr := strconv.ParseInt(h.Get("Content-Length"), 10, 64); perr == nil {
+		buf.Grow(int(n))
+	}
+	if _, err = io.Copy(&buf, r); err != nil {
+		r.Close()
+		return nil, nil, err
+	}
+	if err = r.Close(); err != nil { // verifies the md5 or checksums
+		return nil, nil, err
+	}
+	return buf.Bytes(), h, nil
+}
+
+// PutBytes puts data to path, with the headers in h as for PutWriter.
+func (b *Bucket) PutBytes(path string, data []byte, h http.Header) error {
+	w, err := b.PutWriter(path, h)
+	if err != nil {
+		return err
+	}
+	p := w.(*putter)
+	if _, err = p.Write(data); err != nil {
+		p.setErr(err) // abort instead of completing the upload with partial data
+		p.Close()
+		return err
+	}
+	return p.Close()
+}
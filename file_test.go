@@ -0,0 +1,256 @@
+package s3gof3r
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDownloadFile(t *testing.T) {
+	s := newFakeS3()
+	data := bytes.Repeat([]byte("data"), 3*int(mb)+1) // 3 parts
+	s.objects["/bucket/key"] = data
+	lastModified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+		s.ServeHTTP(w, r)
+	}))
+	b.Config.Md5Check = false
+
+	local := filepath.Join(t.TempDir(), "key")
+	if err := b.DownloadFile("key", local); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("Expected downloaded file to match the object")
+	}
+	fi, err := os.Stat(local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.ModTime().Equal(lastModified) {
+		t.Errorf("Expected modification time: %v. Actual: %v", lastModified, fi.ModTime())
+	}
+
+	// a failed md5 check removes the file
+	b.Config.Md5Check = true
+	s.objects["/bucket/.md5/key.md5"] = []byte("d41d8cd98f00b204e9800998ecf8427e")
+	if err := b.DownloadFile("key", local); err == nil {
+		t.Error("Expected md5 check error")
+	}
+	if _, err := os.Stat(local); !os.IsNotExist(err) {
+		t.Errorf("Expected file to be removed. Actual: %v", err)
+	}
+
+	if err := b.DownloadFile("missing", local); err == nil {
+		t.Error("Expected error for missing object")
+	}
+}
+
+// sliceWriterAt writes to a slice of the size of the object, but can't be read back
+type sliceWriterAt []byte
+
+func (s sliceWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	return copy(s[off:], p), nil
+}
+
+func TestDownloadToWriterAt(t *testing.T) {
+	s := newFakeS3()
+	data := bytes.Repeat([]byte("data"), 3*int(mb)+1) // 3 parts
+	s.objects["/bucket/key"] = data
+	b := newTestBucket(t, s)
+	b.Config.Md5Check = true
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := b.DownloadToWriterAt("key", f); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("Expected downloaded file to match the object")
+	}
+
+	s.objects["/bucket/.md5/key.md5"] = []byte("d41d8cd98f00b204e9800998ecf8427e")
+	if err := b.DownloadToWriterAt("key", f); err == nil || !strings.Contains(err.Error(), "MD5 mismatch") {
+		t.Errorf("Expected md5 check error. Actual: %v", err)
+	}
+
+	// the md5 can't be verified without reading the data back
+	w := make(sliceWriterAt, len(data))
+	if err := b.DownloadToWriterAt("key", w); err == nil {
+		t.Error("Expected error for a writer that can't be verified")
+	}
+	b.Config.Md5Check = false
+	if err := b.DownloadToWriterAt("key", w); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(w, data) {
+		t.Error("Expected written data to match the object")
+	}
+
+	if err := b.DownloadToWriterAt("missing", w); err == nil {
+		t.Error("Expected error for missing object")
+	}
+}
+
+func TestUploadFile(t *testing.T) {
+	s := newFakeS3()
+	b := newTestBucket(t, s)
+	b.Config.Md5Check = false
+	dir := t.TempDir()
+
+	for _, size := range []int{100, 11 * int(mb)} {
+		data := bytes.Repeat([]byte("<html>"), size/6)
+		local := filepath.Join(dir, "file")
+		if err := ioutil.WriteFile(local, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		etag, err := b.UploadFile(local, "key", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if etag == "" {
+			t.Error("Expected ETag")
+		}
+		if got, _ := s.object("/bucket/key"); !bytes.Equal(got, data) {
+			t.Errorf("Expected object of %d bytes. Actual: %d bytes", len(data), len(got))
+		}
+		if ct := s.headers["/bucket/key"].Get("Content-Type"); ct != "text/html; charset=utf-8" {
+			t.Errorf("Expected sniffed Content-Type: text/html; charset=utf-8. Actual: %s", ct)
+		}
+	}
+
+	if _, err := b.UploadFile(filepath.Join(dir, "missing"), "key", nil); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error. Actual: %v", err)
+	}
+}
+
+func TestGetPutBytes(t *testing.T) {
+	s := newFakeS3()
+	b := newTestBucket(t, s)
+	b.Config.Md5Check = true
+	for _, data := range [][]byte{nil, []byte(`{"key": "value"}`), bytes.Repeat([]byte("data"), 3*int(mb)+1)} {
+		h := http.Header{"Content-Type": {"application/json"}}
+		if err := b.PutBytes("key", data, h); err != nil {
+			t.Fatal(err)
+		}
+		got, rh, err := b.GetBytes("key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("Expected %d bytes got. Actual: %d", len(data), len(got))
+		}
+		if ct := rh.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Expected Content-Type: application/json. Actual: %s", ct)
+		}
+	}
+
+	s.objects["/bucket/.md5/key.md5"] = []byte("d41d8cd98f00b204e9800998ecf8427e")
+	if _, _, err := b.GetBytes("key"); err == nil {
+		t.Error("Expected md5 check error")
+	}
+	if _, _, err := b.GetBytes("missing"); err == nil {
+		t.Error("Expected error for missing object")
+	}
+}
+
+// failingReaderAt fails the reads of r at or after off
+type failingReaderAt struct {
+	r   io.ReaderAt
+	off int64
+}
+
+func (f failingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off+int64(len(p)) > f.off {
+		return 0, errors.New("read failed")
+	}
+	return f.r.ReadAt(p, off)
+}
+
+func TestUploadReaderAt(t *testing.T) {
+	s := newFakeS3()
+	var mu sync.Mutex
+	var inFlight, maxInFlight int
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" && r.URL.Query().Has("partNumber") {
+			mu.Lock()
+			inFlight++
+			maxInFlight = max(maxInFlight, inFlight)
+			mu.Unlock()
+			// wait for the other parts, which are read and put concurrently
+			for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+				mu.Lock()
+				n := maxInFlight
+				mu.Unlock()
+				if n >= 4 {
+					break
+				}
+			}
+			defer func() {
+				mu.Lock()
+				inFlight--
+				mu.Unlock()
+			}()
+		}
+		s.ServeHTTP(w, r)
+	}))
+	b.Config.Concurrency = 4
+	b.Config.PartSize = 20 * mb
+	b.Config.Md5Check = true
+	b.Config.DetectContentType = true
+	b.Config.ChecksumAlgorithm = ChecksumCRC32C
+
+	for _, size := range []int{100, 23 << 20} {
+		data := bytes.Repeat([]byte("<html>"), size/6)
+		if err := b.UploadReaderAt("key", bytes.NewReader(data), int64(len(data)), nil); err != nil {
+			t.Fatalf("%d bytes: %v", size, err)
+		}
+		got, h, err := b.GetBytes("key") // verifies the md5 file
+		if err != nil {
+			t.Fatalf("%d bytes: %v", size, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("Expected object of %d bytes. Actual: %d bytes", len(data), len(got))
+		}
+		if ct := h.Get("Content-Type"); ct != "text/html; charset=utf-8" {
+			t.Errorf("Expected sniffed Content-Type: text/html; charset=utf-8. Actual: %s", ct)
+		}
+	}
+	if maxInFlight != 4 {
+		t.Errorf("Expected 4 parts put concurrently. Actual: %d", maxInFlight)
+	}
+
+	data := make([]byte, 23<<20)
+	err := b.UploadReaderAt("failed", failingReaderAt{bytes.NewReader(data), 12 << 20}, int64(len(data)), nil)
+	if err == nil || !strings.Contains(err.Error(), "read failed") {
+		t.Errorf("Expected read error. Actual: %v", err)
+	}
+	if _, ok := s.object("/bucket/failed"); ok {
+		t.Error("Expected no object put")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.uploads) != 0 {
+		t.Errorf("Expected upload aborted. Actual: %d uploads", len(s.uploads))
+	}
+}
@@ -1,14 +1,18 @@
 package s3gof3r
 
 import (
+	"context"
 	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"hash"
 	"io"
 	"io/ioutil"
-	"math"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -17,16 +21,24 @@ import (
 const qWaitMax = 2
 
 type getter struct {
-	url    url.URL
-	bucket *Bucket
-	bufsz  int64
-	err    error
+	ctx      context.Context
+	url      url.URL
+	bucket   *Bucket
+	header   http.Header // added to each part request
+	header0  http.Header // of the first response, for the object metadata
+	progress *progress
+	stats    *stats
+	limiter  *limiter  // shared by the workers
+	adaptive *adaptive // shared by the workers
+	bufsz    int64
+	err      error
 
 	ntry        int
 	concurrency int
 
 	chunkID    int
 	rChunk     *chunk
+	offset     int64 // offset of the first byte to get in the object
 	contentLen int64
 	bytesRead  int64
 	chunkTotal int
@@ -34,6 +46,10 @@ type getter struct {
 	readCh   chan *chunk
 	getCh    chan *chunk
 	quit     chan struct{}
+	slots    chan struct{} // of the chunks being got or waiting to be read, if bounded
+	failed   chan struct{} // closed once a chunk fails, after its retries, with chunkErr
+	failOnce sync.Once
+	chunkErr error
 	qWait    map[int]*chunk
 	qWaitLen uint
 	cond     sync.Cond
@@ -42,41 +58,138 @@ type getter struct {
 
 	closed bool
 
-	md5  hash.Hash
-	cIdx int64
+	md5      hash.Hash
+	md5Check bool
+	checksum *checksumVerifier // verifies the checksums of the parts, if configured
+	// parts are the parts of the object got as chunks, each verified against its
+	// checksum as it's got, instead of by checksum
+	parts       []checksumPart
+	checksumAlg string
+	cIdx        int64
+
+	w io.WriterAt // of DownloadToWriterAt
+}
+
+// getOptions are the options of a single get
+type getOptions struct {
+	offset int64       // first byte of the object to get
+	length int64       // number of bytes to get, or -1 to get to the end of the object
+	header http.Header // added to each request. Conditions only apply to the first.
+	w      io.WriterAt // the chunks are written to, at their offsets, instead of read
+}
+
+// firstRequestOnly are conditional headers only added to the first request of a get.
+// Once the object has been found to be modified they would fail the part requests.
+var firstRequestOnly = map[string]bool{
+	"If-None-Match":     true,
+	"If-Modified-Since": true,
+}
+
+// partHeader returns the headers of opts for the part requests of a get
+func (o getOptions) partHeader() http.Header {
+	h := make(http.Header)
+	for k, v := range o.header {
+		if k = http.CanonicalHeaderKey(k); !firstRequestOnly[k] {
+			h[k] = v
+		}
+	}
+	return h
+}
+
+// partial reports whether the options get only part of the object
+func (o getOptions) partial() bool {
+	return o.offset != 0 || o.length != -1
+}
+
+// rangeHeader returns the value of the Range header for the options
+func (o getOptions) rangeHeader() string {
+	if o.length == -1 {
+		return fmt.Sprintf("bytes=%d-", o.offset)
+	}
+	return fmt.Sprintf("bytes=%d-%d", o.offset, o.offset+o.length-1)
 }
 
 type chunk struct {
-	id     int
-	header http.Header
-	start  int64
-	size   int64
-	b      []byte
+	id       int
+	header   http.Header
+	start    int64
+	size     int64
+	b        []byte
+	checksum string    // of the data of the chunk, for chunks of parts
+	began    time.Time // of the first attempt, for the PartObserver
 }
 
-func newGetter(getURL url.URL, bucket *Bucket) (io.ReadCloser, http.Header, error) {
+// maxPartChunk is the most times PartSize of the parts of an object got as chunks,
+// bounding the buffers of the get
+const maxPartChunk = 8
+
+func newGetter(ctx context.Context, getURL url.URL, bucket *Bucket, opts getOptions) (io.ReadCloser, http.Header, error) {
+	if err := bucket.checkRequest(); err != nil {
+		return nil, nil, err
+	}
 	g := new(getter)
+	g.stats = newStats()
+	g.ctx = ctx
 	g.url = getURL
 	g.bucket = bucket
+	g.offset = opts.offset
+	g.md5Check = bucket.Config.Md5Check && !opts.partial() // the md5 is of the whole object
+	g.w = opts.w
 
 	g.bufsz = max64(bucket.Config.PartSize, 1)
 	g.ntry = max(bucket.Config.NTry, 1)
-	g.concurrency = max(bucket.Config.Concurrency, 1)
+	g.adaptive = newAdaptive(bucket.Config)
+	g.concurrency = g.adaptive.workers(bucket.Config.Concurrency)
 
 	g.getCh = make(chan *chunk)
 	g.readCh = make(chan *chunk)
 	g.quit = make(chan struct{})
+	g.failed = make(chan struct{})
+	if n := bucket.Config.MaxBufferedParts; n > 0 {
+		g.slots = make(chan struct{}, n)
+	}
 	g.qWait = make(map[int]*chunk)
 	g.md5 = md5.New()
 	g.cond = sync.Cond{L: &sync.Mutex{}}
 
+	sse, err := sseCustomerHeader(bucket.Config.SSECustomerKey, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts.header = cloneHeader(opts.header)
+	setHeaders(opts.header, sse)
+	g.header = opts.partHeader()
+
 	// use get instead of head for error messaging
-	resp, err := g.retryRequest("GET", g.url.String(), nil)
+	h := cloneHeader(opts.header)
+	status := 200
+	if opts.partial() {
+		h.Set("Range", opts.rangeHeader())
+		status = 206
+	}
+	alg := bucket.Config.ChecksumAlgorithm
+	if opts.partial() {
+		alg = "" // the checksums are of whole parts
+	}
+	if alg != "" {
+		if _, err := newChecksumHash(alg); err != nil {
+			return nil, nil, err
+		}
+		h.Set(checksumModeHeader, "ENABLED")
+	}
+	if h.Get("Accept-Encoding") == "" {
+		// get the stored bytes of gzip encoded objects, instead of letting the transport decompress them
+		h.Set("Accept-Encoding", "identity")
+	}
+	resp, err := g.retryRequest("GET", g.url.String(), nil, h)
 	if err != nil {
 		return nil, nil, err
 	}
 	defer checkClose(resp.Body, err)
-	if resp.StatusCode != 200 {
+	if resp.StatusCode == 304 {
+		return nil, resp.Header, ErrNotModified
+	}
+	if resp.StatusCode != status {
 		return nil, nil, newRespError(resp)
 	}
 
@@ -87,70 +200,121 @@ func newGetter(getURL url.URL, bucket *Bucket) (io.ReadCloser, http.Header, erro
 	}
 
 	g.contentLen = resp.ContentLength
+	g.header0 = resp.Header
+	resp.Header.Set("Content-Length", strconv.FormatInt(g.contentLen, 10)) // of all the data read, as documented
+	if alg != "" {
+		parts, err := bucket.checksumParts(g.url, alg, resp.Header, g.contentLen)
+		if err != nil {
+			return nil, nil, err
+		}
+		if size, ok := partChunkSize(parts, g.bufsz); ok {
+			g.parts, g.checksumAlg, g.bufsz = parts, alg, size
+		} else if g.checksum, err = newChecksumVerifier(alg, parts); err != nil {
+			return nil, nil, err
+		}
+	}
 	g.chunkTotal = int((g.contentLen + g.bufsz - 1) / g.bufsz) // round up, integer division
-	logger.debugPrintf("object size: %3.2g MB", float64(g.contentLen)/float64((1*mb)))
+	if g.parts != nil {
+		g.chunkTotal = len(g.parts)
+	}
+	g.progress = newProgress(bucket.Config.Progress, g.contentLen)
+	g.limiter = newLimiter(bucket.Config.MaxBytesPerSec)
+	g.bucket.Config.logger().debugPrintf("object size: %3.2g MB", float64(g.contentLen)/float64((1*mb)))
 
-	g.sp = bufferPool(g.bufsz)
+	g.sp = bufferPool(g.bufsz, g.bucket.Config.logger())
 
 	for i := 0; i < g.concurrency; i++ {
 		go g.worker()
 	}
 	go g.initChunks()
+	if bucket.Config.DecompressGzip && !opts.partial() && g.w == nil && resp.Header.Get("Content-Encoding") == "gzip" {
+		r, err := newGzipReader(g)
+		return r, resp.Header, err
+	}
 	return g, resp.Header, nil
 }
 
-func (g *getter) retryRequest(method, urlStr string, body io.ReadSeeker) (resp *http.Response, err error) {
-	for i := 0; i < g.ntry; i++ {
-		var req *http.Request
-		req, err = http.NewRequest(method, urlStr, body)
-		if err != nil {
-			return
-		}
-
-		if body != nil {
-			req.Header.Set(sha256Header, shaReader(body))
-		}
+// retryRequest sends a request of the get, retrying network errors and 500 and 503
+// responses up to NTry attempts
+func (g *getter) retryRequest(method, urlStr string, body io.ReadSeeker, h http.Header) (resp *http.Response, err error) {
+	return g.bucket.sendRetry(g.ctx, g.ntry, method, urlStr, body, h, retryableStatus)
+}
 
-		g.bucket.Sign(req)
-		resp, err = g.bucket.Do(req)
-		if err == nil && resp.StatusCode == 500 {
-			time.Sleep(time.Duration(math.Exp2(float64(i))) * 100 * time.Millisecond) // exponential back-off
-			continue
-		}
-		if err == nil {
-			return
-		}
-		logger.debugPrintln(err)
-		if body != nil {
-			if _, err = body.Seek(0, 0); err != nil {
-				return
-			}
-		}
+// partChunkSize reports whether the parts of an object are got as chunks, which are
+// verified as they are got and retried if corrupted, and returns the size of their
+// buffers: the largest part, if at most maxPartChunk times bufsz. Objects put with a
+// single request have a single part, which is got as a chunk if at most bufsz.
+func partChunkSize(parts []checksumPart, bufsz int64) (int64, bool) {
+	var size int64
+	for _, p := range parts {
+		size = max64(size, p.size)
 	}
-	return
+	if len(parts) == 1 {
+		return bufsz, size > 0 && size <= bufsz // empty objects are verified without chunks
+	}
+	return max64(size, 1), size <= maxPartChunk*bufsz
 }
 
 func (g *getter) initChunks() {
 	id := 0
 	for i := int64(0); i < g.contentLen; {
 		size := min64(g.bufsz, g.contentLen-i)
+		var checksum string
+		if g.parts != nil {
+			size, checksum = g.parts[id].size, g.parts[id].checksum
+		}
+		h := cloneHeader(g.header)
+		h.Set("Range", fmt.Sprintf("bytes=%d-%d", g.offset+i, g.offset+i+size-1))
 		c := &chunk{
-			id: id,
-			header: http.Header{
-				"Range": {fmt.Sprintf("bytes=%d-%d",
-					i, i+size-1)},
-			},
-			start: i,
-			size:  size,
-			b:     nil,
+			id:       id,
+			header:   h,
+			start:    i,
+			size:     size,
+			b:        nil,
+			checksum: checksum,
 		}
 		i += size
 		id++
-		g.getCh <- c
+		// chunks take their slots in order, so the next chunk to read always has one
+		if !g.acquireSlot() {
+			close(g.getCh)
+			return
+		}
+		select {
+		case g.getCh <- c:
+		case <-g.quit:
+			close(g.getCh)
+			return
+		case <-g.ctx.Done():
+			close(g.getCh)
+			return
+		}
 	}
 	close(g.getCh)
 }
 
+// acquireSlot waits for a slot for the next chunk of a get with MaxBufferedParts,
+// returning false if the get is closed or cancelled first.
+func (g *getter) acquireSlot() bool {
+	if g.slots == nil {
+		return true
+	}
+	select {
+	case g.slots <- struct{}{}:
+		return true
+	case <-g.quit:
+	case <-g.ctx.Done():
+	}
+	return false
+}
+
+// releaseSlot releases the slot of a chunk once its buffer is given back
+func (g *getter) releaseSlot() {
+	if g.slots != nil {
+		<-g.slots
+	}
+}
+
 func (g *getter) worker() {
 	for c := range g.getCh {
 		g.retryGetChunk(c)
@@ -158,52 +322,77 @@ func (g *getter) worker() {
 }
 
 func (g *getter) retryGetChunk(c *chunk) {
-	var err error
 	c.b = <-g.sp.get
-	for i := 0; i < g.ntry; i++ {
-		err = g.getChunk(c)
-		if err == nil {
-			return
+	o := g.bucket.Config.PartObserver
+	c.began = time.Now()
+	if o != nil {
+		o.PartStarted(c.id + 1)
+	}
+	err := retry(g.ctx, g.ntry, g.bucket.Config.retryDelay, func(i int) (bool, error) {
+		if i > 0 {
+			g.stats.retry(c.id + 1)
 		}
-		logger.debugPrintf("error on attempt %d: retrying chunk: %v, error: %s", i, c.id, err)
-		time.Sleep(time.Duration(math.Exp2(float64(i))) * 100 * time.Millisecond) // exponential back-off
+		err := g.getChunk(c, i)
+		if err != nil {
+			g.bucket.Config.logger().debugPrintf("error on attempt %d: retrying chunk: %v, error: %s", i, c.id, err)
+		}
+		return true, err
+	})
+	if err == nil {
+		return
+	}
+	if o != nil {
+		o.PartFailed(c.id+1, err)
 	}
 	select {
 	case <-g.quit: // check for closed quit channel before setting error
 		return
 	default:
-		g.err = err
+		g.failOnce.Do(func() {
+			g.chunkErr = err
+			close(g.failed) // wake up the reader
+		})
 	}
 }
 
-func (g *getter) getChunk(c *chunk) error {
+func (g *getter) getChunk(c *chunk, attempt int) error {
 	// ensure buffer is empty
 	r, err := http.NewRequest("GET", g.url.String(), nil)
 	if err != nil {
 		return err
 	}
+	r = withAttempt(r.WithContext(g.ctx), attempt)
 	r.Header = c.header
-	g.bucket.Sign(r)
-	resp, err := g.bucket.Do(r)
+	n, err := g.getChunkBody(r, c)
 	if err != nil {
 		return err
 	}
-	defer checkClose(resp.Body, err)
-	if resp.StatusCode != 206 && resp.StatusCode != 200 {
-		return newRespError(resp)
-	}
-	n, err := io.ReadAtLeast(resp.Body, c.b, int(c.size))
-	if err != nil {
-		return err
-	}
-	if err := resp.Body.Close(); err != nil {
-		return err
-	}
 	if int64(n) != c.size {
 		return fmt.Errorf("chunk %d: Expected %d bytes, received %d",
 			c.id, c.size, n)
 	}
-	g.readCh <- c
+	if c.checksum != "" {
+		if err := g.verifyChunk(c); err != nil {
+			return err
+		}
+	}
+	if g.w != nil {
+		if _, err := g.w.WriteAt(c.b[:c.size], c.start); err != nil {
+			return err
+		}
+	}
+	g.progress.add(c.size)
+	g.stats.part(c.size)
+	if o := g.bucket.Config.PartObserver; o != nil {
+		o.PartCompleted(c.id+1, c.size, attempt+1, time.Since(c.began)) // before the reader may return it
+	}
+	select {
+	case g.readCh <- c:
+	case <-g.quit:
+		return nil
+	case <-g.ctx.Done():
+		return nil
+	}
 
 	// wait for qWait to drain before starting next chunk
 	g.cond.L.Lock()
@@ -217,11 +406,52 @@ func (g *getter) getChunk(c *chunk) error {
 	return nil
 }
 
+// verifyChunk compares the checksum of the data of c, a part of the object, with the
+// checksum of the part, so a corrupted part is retried
+func (g *getter) verifyChunk(c *chunk) error {
+	h, err := newChecksumHash(g.checksumAlg)
+	if err != nil {
+		return err
+	}
+	h.Write(c.b[:c.size])
+	if sum := base64.StdEncoding.EncodeToString(h.Sum(nil)); sum != c.checksum {
+		return fmt.Errorf("%w: %s checksum mismatch of part %d. given:%s calculated:%s",
+			ErrIntegrity, g.checksumAlg, c.id+1, c.checksum, sum)
+	}
+	return nil
+}
+
+// getChunkBody reads the response body of the request r for c into c.b.
+// The adaptive concurrency is held only for the request, not while waiting for the reader.
+func (g *getter) getChunkBody(r *http.Request, c *chunk) (n int, err error) {
+	g.adaptive.acquire()
+	defer func() { g.adaptive.release(err) }()
+	g.stats.begin()
+	defer g.stats.done()
+	g.bucket.Sign(r)
+	resp, err := g.bucket.Do(r)
+	if err != nil {
+		return 0, err
+	}
+	defer checkClose(resp.Body, err)
+	if resp.StatusCode != 206 && resp.StatusCode != 200 {
+		return 0, newRespError(resp)
+	}
+	n, err = io.ReadAtLeast(limitReader(g.ctx, resp.Body, g.limiter), c.b, int(c.size))
+	if err != nil {
+		return n, err
+	}
+	return n, resp.Body.Close()
+}
+
 func (g *getter) Read(p []byte) (int, error) {
 	var err error
 	if g.closed {
 		return 0, syscall.EINVAL
 	}
+	if err := g.ctx.Err(); err != nil {
+		return 0, err
+	}
 	if g.err != nil {
 		return 0, g.err
 	}
@@ -257,6 +487,7 @@ func (g *getter) Read(p []byte) (int, error) {
 
 		if g.cIdx >= g.rChunk.size { // chunk complete
 			g.sp.give <- g.rChunk.b
+			g.releaseSlot()
 			g.chunkID++
 			g.rChunk = nil
 		}
@@ -274,11 +505,16 @@ func (g *getter) nextChunk() (*chunk, error) {
 			g.qWaitLen--
 			g.cond.L.Unlock()
 			g.cond.Signal() // wake up waiting worker goroutine
-			if g.bucket.Config.Md5Check {
+			if g.md5Check {
 				if _, err := g.md5.Write(c.b[:c.size]); err != nil {
 					return nil, err
 				}
 			}
+			if g.checksum != nil {
+				if _, err := g.checksum.Write(c.b[:c.size]); err != nil {
+					return nil, err
+				}
+			}
 			return c, nil
 		}
 		// if next chunk not in qWait, read from channel
@@ -290,8 +526,51 @@ func (g *getter) nextChunk() (*chunk, error) {
 			g.cond.L.Unlock()
 		case <-g.quit:
 			return nil, g.err // fatal error, quit.
+		case <-g.failed:
+			g.err = g.chunkErr
+			return nil, g.err
+		case <-g.ctx.Done():
+			g.err = g.ctx.Err()
+			return nil, g.err
+		}
+	}
+}
+
+// writeAll waits for the workers to write all the chunks to g.w, instead of reading
+// them in order, then verifies the md5 or checksums of the object by reading the data
+// written back from g.w, which must be an io.ReaderAt to verify them.
+func (g *getter) writeAll() error {
+	for g.chunkID < g.chunkTotal {
+		select {
+		case c := <-g.readCh:
+			g.sp.give <- c.b
+			g.releaseSlot()
+			g.bytesRead += c.size
+			g.chunkID++
+		case <-g.failed:
+			g.err = g.chunkErr
+			return g.err
+		case <-g.ctx.Done():
+			g.err = g.ctx.Err()
+			return g.err
 		}
 	}
+	var hashes []io.Writer
+	if g.md5Check {
+		hashes = append(hashes, g.md5)
+	}
+	if g.checksum != nil {
+		hashes = append(hashes, g.checksum)
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	r, ok := g.w.(io.ReaderAt)
+	if !ok {
+		return fmt.Errorf("can't verify the object written to a %T: not an io.ReaderAt", g.w)
+	}
+	_, err := io.Copy(io.MultiWriter(hashes...), io.NewSectionReader(r, 0, g.contentLen))
+	return err
 }
 
 func (g *getter) Close() error {
@@ -299,40 +578,88 @@ func (g *getter) Close() error {
 		return syscall.EINVAL
 	}
 	g.closed = true
+	g.stats.close()
 	close(g.sp.quit)
 	close(g.quit)
 	g.cond.Broadcast()
+	if err := g.ctx.Err(); err != nil {
+		return err
+	}
 	if g.err != nil {
 		return g.err
 	}
+	select {
+	case <-g.failed:
+		return g.chunkErr
+	default:
+	}
 	if g.bytesRead != g.contentLen {
 		return fmt.Errorf("read error: %d bytes read. expected: %d", g.bytesRead, g.contentLen)
 	}
-	if g.bucket.Config.Md5Check {
+	if g.md5Check {
 		if err := g.checkMd5(); err != nil {
 			return err
 		}
 	}
+	if g.checksum != nil {
+		if err := g.checksum.verify(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// Stats returns the Stats of the get
+func (g *getter) Stats() Stats {
+	return g.stats.stats()
+}
+
+// checkETag verifies calcMd5 against the ETag of an object without an md5 file.
+// Only the ETags of objects put with a single request and without SSE-C or SSE-KMS
+// encryption are their md5, so other objects are not verified.
+func (g *getter) checkETag(calcMd5 string) error {
+	etag := strings.Trim(g.header0.Get("ETag"), `"`)
+	if !isMd5ETag(etag) || isKMS(g.header0.Get(sseHeader)) ||
+		g.header0.Get(sseCustomerAlgorithmHeader) != "" {
+		g.bucket.Config.logger().debugPrintf("md5 not verified: no md5 file and the etag %s is not an md5", etag)
+		return nil
+	}
+	if etag != calcMd5 {
+		return fmt.Errorf("MD5 mismatch. etag:%s calculated:%s", etag, calcMd5)
+	}
+	return nil
+}
+
+// isMd5ETag reports whether etag, without quotes, is a hex md5 rather than the ETag
+// of a multipart object such as "<hex>-3".
+func isMd5ETag(etag string) bool {
+	if len(etag) != 2*md5.Size {
+		return false
+	}
+	_, err := hex.DecodeString(etag)
+	return err == nil
+}
+
 func (g *getter) checkMd5() (err error) {
 	calcMd5 := fmt.Sprintf("%x", g.md5.Sum(nil))
-	md5Path := fmt.Sprint(".md5", g.url.Path, ".md5")
+	md5Path := md5Key(g.bucket.objectKey(g.url))
 	md5Url, err := g.bucket.url(md5Path)
 	if err != nil {
 		return err
 	}
 
-	logger.debugPrintln("md5: ", calcMd5)
-	logger.debugPrintln("md5Path: ", md5Path)
-	resp, err := g.retryRequest("GET", md5Url.String(), nil)
+	g.bucket.Config.logger().debugPrintln("md5: ", calcMd5)
+	g.bucket.Config.logger().debugPrintln("md5Path: ", md5Path)
+	resp, err := g.retryRequest("GET", md5Url.String(), nil, nil)
 	if err != nil {
 		return
 	}
 	defer checkClose(resp.Body, err)
+	if resp.StatusCode == 404 {
+		return g.checkETag(calcMd5)
+	}
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("MD5 check failed: %s not found: %s", md5Url.String(), newRespError(resp))
+		return fmt.Errorf("MD5 check failed: %s not found: %w", md5Url.String(), newRespError(resp))
 	}
 	givenMd5, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
@@ -0,0 +1,206 @@
+package s3gof3r
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// objectHandler serves data as the object "/bucket/key", honoring Range headers.
+func objectHandler(data []byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bucket/key" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", `"etag"`)
+		http.ServeContent(w, r, "key", time.Time{}, bytes.NewReader(data))
+	})
+}
+
+func TestGetRangeReader(t *testing.T) {
+	data := make([]byte, 12*mb)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	b := newTestBucket(t, objectHandler(data))
+
+	var rangeTests = []struct {
+		offset, length int64
+		expect         []byte
+	}{
+		{0, -1, data},
+		{10, 100, data[10:110]},
+		{1 * mb, -1, data[1*mb:]},
+		{3 * mb, 7 * mb, data[3*mb : 10*mb]},
+		{11 * mb, 2 * mb, data[11*mb:]}, // past the end of the object
+	}
+	for _, tt := range rangeTests {
+		r, _, err := b.GetRangeReader("key", tt.offset, tt.length)
+		if err != nil {
+			t.Errorf("%d-%d: %v", tt.offset, tt.length, err)
+			continue
+		}
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Errorf("%d-%d: %v", tt.offset, tt.length, err)
+		}
+		if err := r.Close(); err != nil {
+			t.Errorf("%d-%d: close: %v", tt.offset, tt.length, err)
+		}
+		if !bytes.Equal(got, tt.expect) {
+			t.Errorf("%d-%d: expected %d bytes, got %d", tt.offset, tt.length, len(tt.expect), len(got))
+		}
+	}
+
+	if _, _, err := b.GetRangeReader("key", 0, 0); err == nil {
+		t.Error("expected error for zero length range")
+	}
+}
+
+func TestGetReaderNotModified(t *testing.T) {
+	b := newTestBucket(t, objectHandler(make([]byte, 6*mb)))
+
+	h := http.Header{}
+	h.Set("If-None-Match", `"etag"`)
+	_, rh, err := b.GetReaderWithHeader("key", h)
+	if err != ErrNotModified {
+		t.Errorf("expected %v, got %v", ErrNotModified, err)
+	}
+	if rh.Get("ETag") != `"etag"` {
+		t.Errorf("expected response headers with not modified, got %v", rh)
+	}
+
+	// a changed object is downloaded, without the condition on part requests
+	h.Set("If-None-Match", `"other"`)
+	r, _, err := b.GetReaderWithHeader("key", h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := io.Copy(ioutil.Discard, r)
+	if err != nil || n != 6*mb {
+		t.Errorf("expected %d bytes, got %d: %v", 6*mb, n, err)
+	}
+	if err := r.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetReaderContentLength(t *testing.T) {
+	s := newFakeS3()
+	s.objects["/bucket/key"] = make([]byte, 11*mb) // 3 parts
+	b := newTestBucket(t, s)
+	b.Config.Md5Check = false
+
+	r, h, err := b.GetReader("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if cl := h.Get("Content-Length"); cl != strconv.FormatInt(11*mb, 10) {
+		t.Errorf("Expected Content-Length: %d. Actual: %s", 11*mb, cl)
+	}
+
+	r, h, err = b.GetRangeReader("key", mb, 6*mb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if cl := h.Get("Content-Length"); cl != strconv.FormatInt(6*mb, 10) {
+		t.Errorf("Expected Content-Length: %d. Actual: %s", 6*mb, cl)
+	}
+	if cr, want := h.Get("Content-Range"), fmt.Sprintf("bytes %d-%d/%d", mb, 7*mb-1, 11*mb); cr != want {
+		t.Errorf("Expected Content-Range: %s. Actual: %s", want, cr)
+	}
+}
+
+var etagMd5Tests = []struct {
+	etag string
+	ok   bool
+}{
+	{fmt.Sprintf(`"%x"`, md5.Sum([]byte("data"))), true},
+	{fmt.Sprintf(`"%x"`, md5.Sum([]byte("other"))), false},
+	{fmt.Sprintf(`"%x-2"`, md5.Sum([]byte("other"))), true}, // multipart, not verified
+}
+
+func TestGetReaderETagMd5(t *testing.T) {
+	for _, tt := range etagMd5Tests {
+		b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/bucket/key" {
+				http.NotFound(w, r) // no md5 file
+				return
+			}
+			w.Header().Set("ETag", tt.etag)
+			http.ServeContent(w, r, "key", time.Time{}, strings.NewReader("data"))
+		}))
+		b.Config.Md5Check = true
+		r, _, err := b.GetReader("key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.Copy(ioutil.Discard, r); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Close(); (err == nil) != tt.ok {
+			t.Errorf("etag %s: Expected ok: %v. Actual error: %v", tt.etag, tt.ok, err)
+		}
+	}
+}
+
+// TestMaxBufferedParts reads an object slowly, checking the get reads no more than
+// MaxBufferedParts parts ahead of the reader.
+func TestMaxBufferedParts(t *testing.T) {
+	data := make([]byte, 40*kb)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	for _, n := range []int{1, 2, 3} {
+		var mu sync.Mutex
+		var parts int
+		h := objectHandler(data)
+		b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if rng := r.Header.Get("Range"); rng != "" {
+				mu.Lock()
+				parts++
+				mu.Unlock()
+			}
+			h.ServeHTTP(w, r)
+		}))
+		b.Config.Md5Check = false
+		b.Config.Concurrency = 4
+		b.Config.PartSize = 4 * kb
+		b.Config.MaxBufferedParts = n
+
+		r, _, err := b.GetReader("key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make([]byte, len(data))
+		if _, err := io.ReadFull(r, got[:1]); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond) // a slow reader
+		mu.Lock()
+		if parts > n {
+			t.Errorf("Expected at most %d parts got ahead of the reader. Actual: %d", n, parts)
+		}
+		mu.Unlock()
+		if _, err := io.ReadFull(r, got[1:]); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("%d: Expected data read to match the object", n)
+		}
+	}
+}
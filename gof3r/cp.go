@@ -34,7 +34,7 @@ func (cp *cpOpts) Execute(args []string) (err error) {
 
 	conf := new(s3gof3r.Config)
 	*conf = *s3gof3r.DefaultConfig
-	s3 := s3gof3r.New(cp.EndPoint, k)
+	s3 := s3gof3r.NewWithRegion(cp.EndPoint, cp.Region, k)
 	conf.Concurrency = cp.Concurrency
 	if cp.NoSSL {
 		conf.Scheme = "http"
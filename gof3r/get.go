@@ -27,7 +27,7 @@ func (get *getOpts) Execute(args []string) (err error) {
 	if err != nil {
 		return
 	}
-	s3 := s3gof3r.New(get.EndPoint, k)
+	s3 := s3gof3r.NewWithRegion(get.EndPoint, get.Region, k)
 	b := s3.Bucket(get.Bucket)
 	conf.Concurrency = get.Concurrency
 	if get.NoSSL {
@@ -11,11 +11,14 @@
 //      $ gof3r cp s3://<bucket>/<s3_path> <local_path>
 //
 //
-// Set AWS keys as environment Variables (required unless using ec2 instance-based credentials):
+// Set AWS keys as environment Variables (required unless using the shared credentials file
+// or ec2 instance-based credentials):
 //
 //  $ export AWS_ACCESS_KEY_ID=<access_key>
 //  $ export AWS_SECRET_ACCESS_KEY=<secret_key>
 //
+// Keys are also read from the AWS_PROFILE profile of ~/.aws/credentials.
+//
 // Examples:
 //  $ tar -cf - /foo_dir/ | gof3r put -b my_s3_bucket -k bar_dir/s3_object -m x-amz-meta-custom-metadata:abc123 -m x-amz-server-side-encryption:AES256
 //  $ gof3r get -b my_s3_bucket -k bar_dir/s3_object | tar -x
@@ -30,7 +33,6 @@
 package main
 
 import (
-	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -84,20 +86,10 @@ func main() {
 	fmt.Fprintf(os.Stderr, "duration: %v\n", time.Since(start))
 }
 
-// getAWSKeys gets the AWS Keys from environment variables or the instance-based metadata on EC2
-// Environment variables are attempted first, followed by the instance-based credentials.
-func getAWSKeys() (keys s3gof3r.Keys, err error) {
-
-	keys, err = s3gof3r.EnvKeys()
-	if err == nil {
-		return
-	}
-	keys, err = s3gof3r.InstanceKeys()
-	if err == nil {
-		return
-	}
-	err = errors.New("no AWS keys found")
-	return
+// getAWSKeys gets the AWS Keys from environment variables, the shared credentials file,
+// ECS container credentials or the instance-based metadata on EC2, in that order.
+func getAWSKeys() (keys *s3gof3r.Keys, err error) {
+	return s3gof3r.DefaultKeys()
 }
 
 func checkClose(c io.Closer, err error) {
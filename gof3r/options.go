@@ -18,6 +18,7 @@ const (
 // CommonOpts are Options common to all commands
 type CommonOpts struct {
 	EndPoint string `long:"endpoint" description:"Amazon S3 endpoint" default:"s3.amazonaws.com" ini-name:"endpoint"`
+	Region   string `long:"region" description:"Region of the endpoint, for endpoints the region can't be inferred from" ini-name:"region"`
 	Debug    bool   `long:"debug" description:"Enable debug logging." ini-name:"debug"`
 }
 
@@ -27,7 +27,7 @@ func (put *putOpts) Execute(args []string) (err error) {
 	if err != nil {
 		return
 	}
-	s3 := s3gof3r.New(put.EndPoint, k)
+	s3 := s3gof3r.NewWithRegion(put.EndPoint, put.Region, k)
 	b := s3.Bucket(put.Bucket)
 	conf.Concurrency = put.Concurrency
 	if put.NoSSL {
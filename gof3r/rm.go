@@ -29,7 +29,7 @@ func (rm *rmOpts) Execute(args []string) error {
 
 	conf := new(s3gof3r.Config)
 	*conf = *s3gof3r.DefaultConfig
-	s3 := s3gof3r.New(rm.EndPoint, k)
+	s3 := s3gof3r.NewWithRegion(rm.EndPoint, rm.Region, k)
 	s3gof3r.SetLogger(os.Stderr, "", log.Ltime, rm.Debug)
 
 	// parse positional cp args
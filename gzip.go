@@ -0,0 +1,41 @@
+package s3gof3r
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// writerFunc is an io.Writer calling the function
+type writerFunc func(b []byte) (int, error)
+
+func (f writerFunc) Write(b []byte) (int, error) {
+	return f(b)
+}
+
+// gzipReader decompresses the data of a get. Close closes the get, which verifies
+// the md5 and checksums of the compressed data stored.
+type gzipReader struct {
+	*gzip.Reader
+	g *getter
+}
+
+func newGzipReader(g *getter) (io.ReadCloser, error) {
+	zr, err := gzip.NewReader(g)
+	if err != nil {
+		g.Close()
+		return nil, err
+	}
+	return &gzipReader{Reader: zr, g: g}, nil
+}
+
+func (r *gzipReader) Close() error {
+	if err := r.g.Close(); err != nil {
+		return err
+	}
+	return r.Reader.Close()
+}
+
+// Stats returns the Stats of the get, of the compressed data
+func (r *gzipReader) Stats() Stats {
+	return r.g.Stats()
+}
@@ -0,0 +1,128 @@
+package s3gof3r
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"testing"
+)
+
+// gzipped returns data compressed with gzip
+func gzipped(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestGetReaderDecompressGzip(t *testing.T) {
+	data := make([]byte, 12*mb)
+	rand.New(rand.NewSource(1)).Read(data[:6*mb]) // partly compressible, for several parts
+	stored := gzipped(t, data)
+
+	s := newFakeS3()
+	s.objects["/bucket/key"] = stored
+	s.headers["/bucket/key"] = http.Header{"Content-Encoding": {"gzip"}}
+	s.objects["/bucket/.md5/key.md5"] = []byte(fmt.Sprintf("%x", md5.Sum(stored)))
+	b := newTestBucket(t, s)
+	b.Config.Md5Check = true
+
+	for _, decompress := range []bool{false, true} {
+		b.Config.DecompressGzip = decompress
+		r, h, err := b.GetReader("key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Close(); err != nil {
+			t.Fatal(err)
+		}
+		want := stored
+		if decompress {
+			want = data
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("decompress %v: Expected %d bytes. Actual: %d bytes", decompress, len(want), len(got))
+		}
+		if ce := h.Get("Content-Encoding"); ce != "gzip" {
+			t.Errorf("Expected Content-Encoding: gzip. Actual: %s", ce)
+		}
+	}
+}
+
+func TestPutWriterCompressGzip(t *testing.T) {
+	s := newFakeS3()
+	b := newTestBucket(t, s)
+	b.Config.Md5Check = true
+	b.Config.CompressGzip = true
+	b.Config.DecompressGzip = true
+
+	random := make([]byte, 11*mb) // incompressible, for several parts
+	rand.New(rand.NewSource(1)).Read(random)
+	for _, data := range [][]byte{[]byte("small"), random} {
+		w, err := b.PutWriter("key", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if ce := s.headers["/bucket/key"].Get("Content-Encoding"); ce != "gzip" {
+			t.Errorf("Expected Content-Encoding: gzip. Actual: %s", ce)
+		}
+		stored, _ := s.object("/bucket/key")
+		zr, err := gzip.NewReader(bytes.NewReader(stored))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := ioutil.ReadAll(zr); err != nil || !bytes.Equal(got, data) {
+			t.Errorf("Expected stored object to decompress to %d bytes. Actual: %d bytes, %v", len(data), len(got), err)
+		}
+
+		r, _, err := b.GetReader("key") // verifies the md5 of the stored bytes
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("Expected %d bytes. Actual: %d bytes", len(data), len(got))
+		}
+	}
+
+	// data with a Content-Encoding is put as is
+	data := gzipped(t, []byte("compressed"))
+	w, err := b.PutWriter("key", http.Header{"Content-Encoding": {"gzip"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := s.object("/bucket/key"); !bytes.Equal(stored, data) {
+		t.Error("Expected data with a Content-Encoding not to be compressed again")
+	}
+}
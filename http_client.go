@@ -1,6 +1,7 @@
 package s3gof3r
 
 import (
+	"context"
 	"net"
 	"net/http"
 	"time"
@@ -27,23 +28,53 @@ func (c *deadlineConn) Write(b []byte) (n int, err error) {
 
 // ClientWithTimeout is an http client optimized for high throughput
 // to S3, It times out more agressively than the default
-// http client in net/http as well as setting deadlines on the TCP connection
+// http client in net/http as well as setting deadlines on the TCP connection.
+// It uses the proxy of the environment, set in HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
 func ClientWithTimeout(timeout time.Duration) *http.Client {
-	transport := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		Dial: func(netw, addr string) (net.Conn, error) {
-			c, err := net.DialTimeout(netw, addr, timeout)
+	return ClientWithConcurrency(timeout, defaultConcurrency)
+}
+
+// ClientWithConcurrency is like ClientWithTimeout, keeping up to concurrency idle
+// connections per host, for buckets with a Concurrency (or MaxConcurrency) above the
+// 10 of DefaultConfig. Parts are then sent on connections kept alive from previous
+// parts, instead of connections with a new TLS handshake.
+func ClientWithConcurrency(timeout time.Duration, concurrency int) *http.Client {
+	return ClientWithTransport(&http.Transport{MaxIdleConnsPerHost: concurrency}, timeout)
+}
+
+// ClientWithTransport is like ClientWithTimeout, with the TLS, proxy and connection pool
+// settings of t, for custom CAs or proxies. The fields of t that are not set default to
+// those of ClientWithTimeout: the proxy of the environment, dialing with timeout and
+// deadlines on the TCP connection, a timeout for response headers, and 10 idle
+// connections per host.
+//
+// Gets and puts of one object make up to Concurrency requests at a time, so
+// MaxIdleConnsPerHost should be at least the Concurrency of the buckets using the
+// client, or connections are closed and reopened between parts. MaxConnsPerHost, if
+// set, limits the requests of every bucket using the client.
+//
+// To connect without the proxy of the environment, set Proxy to a func returning nil.
+// t is cloned, and may be reused.
+func ClientWithTransport(t *http.Transport, timeout time.Duration) *http.Client {
+	transport := t.Clone()
+	if transport.Proxy == nil {
+		transport.Proxy = http.ProxyFromEnvironment
+	}
+	if transport.DialContext == nil && transport.Dial == nil {
+		dialer := &net.Dialer{Timeout: timeout, KeepAlive: timeout}
+		transport.DialContext = func(ctx context.Context, netw, addr string) (net.Conn, error) {
+			c, err := dialer.DialContext(ctx, netw, addr)
 			if err != nil {
 				return nil, err
 			}
-			if tc, ok := c.(*net.TCPConn); ok {
-				tc.SetKeepAlive(true)
-				tc.SetKeepAlivePeriod(timeout)
-			}
 			return &deadlineConn{timeout, c}, nil
-		},
-		ResponseHeaderTimeout: timeout,
-		MaxIdleConnsPerHost:   10,
+		}
+	}
+	if transport.ResponseHeaderTimeout == 0 {
+		transport.ResponseHeaderTimeout = timeout
+	}
+	if transport.MaxIdleConnsPerHost == 0 {
+		transport.MaxIdleConnsPerHost = defaultConcurrency
 	}
 	return &http.Client{Transport: transport}
 }
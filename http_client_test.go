@@ -0,0 +1,111 @@
+package s3gof3r
+
+import (
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientWithTransport(t *testing.T) {
+	tlsConfig := &tls.Config{ServerName: "s3.example.com"}
+	c := ClientWithTransport(&http.Transport{TLSClientConfig: tlsConfig, MaxIdleConnsPerHost: 32}, time.Second)
+	tr := c.Transport.(*http.Transport)
+	if tr.TLSClientConfig.ServerName != "s3.example.com" {
+		t.Errorf("Expected TLS config kept. Actual: %+v", tr.TLSClientConfig)
+	}
+	if tr.MaxIdleConnsPerHost != 32 {
+		t.Errorf("Expected MaxIdleConnsPerHost: 32. Actual: %d", tr.MaxIdleConnsPerHost)
+	}
+	if tr.Proxy == nil || tr.DialContext == nil || tr.ResponseHeaderTimeout != time.Second {
+		t.Errorf("Expected defaults of ClientWithTimeout. Actual: %+v", tr)
+	}
+	if tr := ClientWithTimeout(time.Second).Transport.(*http.Transport); tr.Proxy == nil || tr.MaxIdleConnsPerHost != 10 {
+		t.Errorf("Expected proxy of the environment and 10 idle connections. Actual: %+v", tr)
+	}
+}
+
+func TestClientWithTransportProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.Write([]byte("ok"))
+	}))
+	defer proxy.Close()
+	pu, _ := url.Parse(proxy.URL)
+
+	c := ClientWithTransport(&http.Transport{Proxy: http.ProxyURL(pu)}, 5*time.Second)
+	resp, err := c.Get("http://bucket.s3.example.com/key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" || proxied != "http://bucket.s3.example.com/key" {
+		t.Errorf("Expected request through proxy. Actual: %q %q", proxied, body)
+	}
+}
+
+func TestClientWithConcurrency(t *testing.T) {
+	tr := ClientWithConcurrency(time.Second, 32).Transport.(*http.Transport)
+	if tr.MaxIdleConnsPerHost != 32 {
+		t.Errorf("Expected MaxIdleConnsPerHost: 32. Actual: %d", tr.MaxIdleConnsPerHost)
+	}
+}
+
+// BenchmarkClientConcurrency sends 32 requests at a time to a TLS server, reporting the
+// TLS handshakes per request with the idle connections of the default transport and of
+// ClientWithConcurrency.
+func BenchmarkClientConcurrency(b *testing.B) {
+	const concurrency = 32
+	for _, bb := range []struct {
+		name string
+		idle int
+	}{
+		{"idle-default", http.DefaultMaxIdleConnsPerHost},
+		{"idle-concurrency", concurrency},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			var handshakes int64
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+			}))
+			srv.Config.ConnState = func(c net.Conn, s http.ConnState) {
+				if s == http.StateNew {
+					atomic.AddInt64(&handshakes, 1)
+				}
+			}
+			srv.StartTLS()
+			defer srv.Close()
+			tlsConfig := srv.Client().Transport.(*http.Transport).TLSClientConfig
+			c := ClientWithTransport(&http.Transport{TLSClientConfig: tlsConfig, MaxIdleConnsPerHost: bb.idle}, 5*time.Second)
+
+			b.ResetTimer()
+			var wg sync.WaitGroup
+			sem := make(chan struct{}, concurrency)
+			for i := 0; i < b.N; i++ {
+				sem <- struct{}{}
+				wg.Add(1)
+				go func() {
+					defer func() { <-sem; wg.Done() }()
+					resp, err := c.Get(srv.URL)
+					if err != nil {
+						b.Error(err)
+						return
+					}
+					io.Copy(ioutil.Discard, resp.Body)
+					resp.Body.Close()
+				}()
+			}
+			wg.Wait()
+			b.ReportMetric(float64(atomic.LoadInt64(&handshakes))/float64(b.N), "handshakes/op")
+		})
+	}
+}
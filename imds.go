@@ -0,0 +1,85 @@
+package s3gof3r
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// InstanceMetadataTokenTTL is the lifetime requested for the IMDSv2 session
+// tokens used to read instance metadata.
+var InstanceMetadataTokenTTL = 6 * time.Hour
+
+// metadataURL is the base url of the EC2 instance metadata service.
+var metadataURL = "http://169.254.169.254"
+
+const (
+	metadataTokenPath      = "/latest/api/token"
+	metadataTokenHeader    = "X-aws-ec2-metadata-token"
+	metadataTokenTTLHeader = "X-aws-ec2-metadata-token-ttl-seconds"
+)
+
+// metadataClient reads from the EC2 instance metadata service.
+// If token is empty, requests are made using IMDSv1.
+type metadataClient struct {
+	client *http.Client
+	token  string
+}
+
+// newMetadataClient requests an IMDSv2 session token, falling back to IMDSv1
+// if the token endpoint is not found or does not respond in time.
+func newMetadataClient() (c *metadataClient, err error) {
+	c = &metadataClient{client: ClientWithTimeout(2 * time.Second)}
+	req, err := http.NewRequest("PUT", metadataURL+metadataTokenPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(metadataTokenTTLHeader, strconv.Itoa(int(InstanceMetadataTokenTTL/time.Second)))
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			logger.debugPrintln("IMDSv2 token request timed out, falling back to IMDSv1")
+			return c, nil
+		}
+		return nil, err
+	}
+	defer checkClose(resp.Body, err)
+	switch resp.StatusCode {
+	case 200:
+	case 404:
+		logger.debugPrintln("IMDSv2 token endpoint not found, falling back to IMDSv1")
+		return c, nil
+	default:
+		return nil, newRespError(resp)
+	}
+	token, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.token = string(token)
+	return c, nil
+}
+
+// get returns the body of the metadata at path
+func (c *metadataClient) get(path string) (b []byte, err error) {
+	req, err := http.NewRequest("GET", metadataURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if c.token != "" {
+		req.Header.Set(metadataTokenHeader, c.token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer checkClose(resp.Body, err)
+	if resp.StatusCode != 200 {
+		return nil, newRespError(resp)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
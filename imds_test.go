@@ -0,0 +1,89 @@
+package s3gof3r
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testInstanceCreds = `{"Code":"Success","Type":"AWS-HMAC","AccessKeyId":"AKIDEXAMPLE","SecretAccessKey":"secret","Token":"token","Expiration":"2030-01-01T00:00:00Z"}`
+
+// newMetadataServer serves instance credentials for the role "role".
+// If v2 is true, a session token is required for all metadata requests.
+func newMetadataServer(t *testing.T, v2 bool) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == metadataTokenPath {
+			if !v2 {
+				http.NotFound(w, r)
+				return
+			}
+			if r.Method != "PUT" || r.Header.Get(metadataTokenTTLHeader) != "21600" {
+				w.WriteHeader(400)
+				return
+			}
+			w.Write([]byte("session-token"))
+			return
+		}
+		if v2 && r.Header.Get(metadataTokenHeader) != "session-token" {
+			w.WriteHeader(401)
+			return
+		}
+		switch r.URL.Path {
+		case instanceCredentialsPath:
+			w.Write([]byte("role"))
+		case instanceCredentialsPath + "role":
+			w.Write([]byte(testInstanceCreds))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	orig := metadataURL
+	metadataURL = srv.URL
+	t.Cleanup(func() {
+		metadataURL = orig
+		srv.Close()
+	})
+}
+
+func TestInstanceKeys(t *testing.T) {
+	for _, v2 := range []bool{true, false} {
+		newMetadataServer(t, v2)
+		k, err := InstanceKeys()
+		if err != nil {
+			t.Errorf("v2 %t: %v", v2, err)
+			continue
+		}
+		if k.AccessKeyID() != "AKIDEXAMPLE" || k.SecretAccessKey() != "secret" || k.SessionToken() != "token" {
+			t.Errorf("v2 %t: unexpected keys %v", v2, k)
+		}
+		if exp := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC); !k.Expiration().Equal(exp) {
+			t.Errorf("v2 %t: expected expiration %v, got %v", v2, exp, k.Expiration())
+		}
+	}
+}
+
+func TestKeysRefresh(t *testing.T) {
+	n := 0
+	k := &Keys{
+		accessKeyID: "old",
+		expiration:  time.Now().Add(time.Minute),
+		refresh: func() (*Keys, error) {
+			n++
+			return &Keys{accessKeyID: "new", expiration: time.Now().Add(time.Hour)}, nil
+		},
+	}
+	b := &Bucket{S3: New("", k), Name: "bucket", Config: DefaultConfig}
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", "https://bucket.s3.amazonaws.com/key", nil)
+		b.Sign(req)
+		if !strings.Contains(req.Header.Get("Authorization"), "Credential=new/") {
+			t.Errorf("request not signed with refreshed keys: %s", req.Header.Get("Authorization"))
+		}
+	}
+	if n != 1 {
+		t.Errorf("expected 1 refresh, got %d", n)
+	}
+}
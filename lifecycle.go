@@ -0,0 +1,132 @@
+package s3gof3r
+
+import (
+	"encoding/xml"
+	"errors"
+	"time"
+)
+
+// LifecycleRule is a rule of the lifecycle configuration of a bucket, expiring or
+// transitioning to other storage classes the objects matched by its Filter.
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/API_LifecycleRule.html
+type LifecycleRule struct {
+	ID     string
+	Status string          // Enabled or Disabled
+	Filter LifecycleFilter `xml:"-"`
+	// Prefix of the keys of the objects the rule applies to, in rules without a Filter
+	Prefix string
+
+	Expiration                     *LifecycleExpiration
+	Transitions                    []LifecycleTransition `xml:"Transition"`
+	NoncurrentVersionExpiration    *NoncurrentVersionExpiration
+	NoncurrentVersionTransitions   []NoncurrentVersionTransition `xml:"NoncurrentVersionTransition"`
+	AbortIncompleteMultipartUpload *AbortIncompleteMultipartUpload
+}
+
+// LifecycleFilter selects the objects a lifecycle rule applies to: those with keys
+// beginning with Prefix, with the tags of Tags, and of a size within the bounds set, if
+// any. An empty filter selects every object of the bucket.
+type LifecycleFilter struct {
+	Prefix                string
+	Tags                  map[string]string
+	ObjectSizeGreaterThan int64
+	ObjectSizeLessThan    int64
+}
+
+// lifecycleFilter is a Filter element, whose conditions, if more than one, are in an
+// And element
+type lifecycleFilter struct {
+	Prefix                string
+	Tag                   *tag
+	ObjectSizeGreaterThan int64
+	ObjectSizeLessThan    int64
+	And                   *struct {
+		Prefix                string
+		Tags                  []tag `xml:"Tag"`
+		ObjectSizeGreaterThan int64
+		ObjectSizeLessThan    int64
+	}
+}
+
+// UnmarshalXML decodes a Rule element, with the conditions of its Filter
+func (r *LifecycleRule) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type rule LifecycleRule // without the method
+	var v struct {
+		rule
+		Filter lifecycleFilter
+	}
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+	*r = LifecycleRule(v.rule)
+	f := v.Filter
+	r.Filter = LifecycleFilter{Prefix: f.Prefix, ObjectSizeGreaterThan: f.ObjectSizeGreaterThan, ObjectSizeLessThan: f.ObjectSizeLessThan}
+	tags := []tag{}
+	if f.Tag != nil {
+		tags = append(tags, *f.Tag)
+	}
+	if and := f.And; and != nil {
+		r.Filter = LifecycleFilter{Prefix: and.Prefix, ObjectSizeGreaterThan: and.ObjectSizeGreaterThan, ObjectSizeLessThan: and.ObjectSizeLessThan}
+		tags = and.Tags
+	}
+	if len(tags) > 0 {
+		r.Filter.Tags = make(map[string]string, len(tags))
+		for _, t := range tags {
+			r.Filter.Tags[t.Key] = t.Value
+		}
+	}
+	return nil
+}
+
+// LifecycleExpiration expires the current versions of objects at Date, or Days after
+// their creation. In versioned buckets, expired objects get a delete marker, and the
+// delete markers without noncurrent versions are removed if ExpiredObjectDeleteMarker.
+type LifecycleExpiration struct {
+	Date                      time.Time
+	Days                      int
+	ExpiredObjectDeleteMarker bool
+}
+
+// LifecycleTransition transitions objects to StorageClass at Date, or Days after their
+// creation.
+type LifecycleTransition struct {
+	Date         time.Time
+	Days         int
+	StorageClass string
+}
+
+// NoncurrentVersionExpiration deletes the versions of objects NoncurrentDays after they
+// become noncurrent, keeping the NewerNoncurrentVersions newest, if set.
+type NoncurrentVersionExpiration struct {
+	NoncurrentDays          int
+	NewerNoncurrentVersions int
+}
+
+// NoncurrentVersionTransition transitions the versions of objects to StorageClass
+// NoncurrentDays after they become noncurrent.
+type NoncurrentVersionTransition struct {
+	NoncurrentDays          int
+	NewerNoncurrentVersions int
+	StorageClass            string
+}
+
+// AbortIncompleteMultipartUpload aborts the multipart uploads not completed
+// DaysAfterInitiation days after they are initiated.
+type AbortIncompleteMultipartUpload struct {
+	DaysAfterInitiation int
+}
+
+// GetBucketLifecycle returns the rules of the lifecycle configuration of the bucket,
+// or no rules if it has no lifecycle configuration.
+func (b *Bucket) GetBucketLifecycle() ([]LifecycleRule, error) {
+	var lc struct {
+		XMLName xml.Name        `xml:"LifecycleConfiguration"`
+		Rules   []LifecycleRule `xml:"Rule"`
+	}
+	err := b.getSubresource("", "lifecycle", &lc)
+	var rerr *RespError
+	if errors.As(err, &rerr) && rerr.Code == "NoSuchLifecycleConfiguration" {
+		return nil, nil
+	}
+	return lc.Rules, err
+}
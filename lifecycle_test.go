@@ -0,0 +1,89 @@
+package s3gof3r
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetBucketLifecycle(t *testing.T) {
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/bucket" || !r.URL.Query().Has("lifecycle") {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		fmt.Fprint(w, `<LifecycleConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+			<Rule>
+				<ID>logs</ID>
+				<Filter><Prefix>logs/</Prefix></Filter>
+				<Status>Enabled</Status>
+				<Transition><Days>30</Days><StorageClass>STANDARD_IA</StorageClass></Transition>
+				<Transition><Days>90</Days><StorageClass>GLACIER</StorageClass></Transition>
+				<Expiration><Days>365</Days></Expiration>
+				<NoncurrentVersionExpiration><NoncurrentDays>7</NoncurrentDays><NewerNoncurrentVersions>2</NewerNoncurrentVersions></NoncurrentVersionExpiration>
+			</Rule>
+			<Rule>
+				<ID>tagged</ID>
+				<Filter><And><Prefix>tmp/</Prefix><Tag><Key>a</Key><Value>1</Value></Tag><Tag><Key>b</Key><Value>2</Value></Tag><ObjectSizeGreaterThan>1024</ObjectSizeGreaterThan></And></Filter>
+				<Status>Disabled</Status>
+				<Expiration><Date>2030-01-01T00:00:00.000Z</Date></Expiration>
+			</Rule>
+			<Rule>
+				<ID>uploads</ID>
+				<Filter><Tag><Key>c</Key><Value>3</Value></Tag></Filter>
+				<Status>Enabled</Status>
+				<AbortIncompleteMultipartUpload><DaysAfterInitiation>3</DaysAfterInitiation></AbortIncompleteMultipartUpload>
+			</Rule>
+			<Rule>
+				<ID>legacy</ID>
+				<Prefix>old/</Prefix>
+				<Status>Enabled</Status>
+				<Expiration><ExpiredObjectDeleteMarker>true</ExpiredObjectDeleteMarker></Expiration>
+			</Rule>
+		</LifecycleConfiguration>`)
+	}))
+	rules, err := b.GetBucketLifecycle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []LifecycleRule{
+		{
+			ID:                          "logs",
+			Status:                      "Enabled",
+			Filter:                      LifecycleFilter{Prefix: "logs/"},
+			Expiration:                  &LifecycleExpiration{Days: 365},
+			Transitions:                 []LifecycleTransition{{Days: 30, StorageClass: "STANDARD_IA"}, {Days: 90, StorageClass: "GLACIER"}},
+			NoncurrentVersionExpiration: &NoncurrentVersionExpiration{NoncurrentDays: 7, NewerNoncurrentVersions: 2},
+		},
+		{
+			ID:         "tagged",
+			Status:     "Disabled",
+			Filter:     LifecycleFilter{Prefix: "tmp/", Tags: map[string]string{"a": "1", "b": "2"}, ObjectSizeGreaterThan: 1024},
+			Expiration: &LifecycleExpiration{Date: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			ID:                             "uploads",
+			Status:                         "Enabled",
+			Filter:                         LifecycleFilter{Tags: map[string]string{"c": "3"}},
+			AbortIncompleteMultipartUpload: &AbortIncompleteMultipartUpload{DaysAfterInitiation: 3},
+		},
+		{
+			ID:         "legacy",
+			Status:     "Enabled",
+			Prefix:     "old/",
+			Expiration: &LifecycleExpiration{ExpiredObjectDeleteMarker: true},
+		},
+	}
+	if !reflect.DeepEqual(rules, expect) {
+		t.Errorf("Expected rules: %+v. Actual: %+v", expect, rules)
+	}
+
+	b = newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		fmt.Fprint(w, `<Error><Code>NoSuchLifecycleConfiguration</Code><Message>The lifecycle configuration does not exist</Message></Error>`)
+	}))
+	if rules, err := b.GetBucketLifecycle(); err != nil || rules != nil {
+		t.Errorf("Expected no rules. Actual: %v, %v", rules, err)
+	}
+}
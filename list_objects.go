@@ -1,23 +1,32 @@
 package s3gof3r
 
 import (
+	"context"
 	"encoding/xml"
-	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
-func newObjectLister(c *Config, b *Bucket, prefixes []string, maxKeys int) (*ObjectLister, error) {
+func newObjectLister(ctx context.Context, c *Config, b *Bucket, prefixes []string, delimiter, startAfter string, maxKeys int) (*ObjectLister, error) {
+	if err := b.checkRequest(); err != nil {
+		return nil, err
+	}
 	l := new(ObjectLister)
-	l.c, l.b = new(Config), new(Bucket)
-	*l.c, *l.b = *c, *b
+	l.c = new(Config)
+	*l.c = *c
+	l.b = b
 	l.c.NTry = max(c.NTry, 1)
 	l.c.Concurrency = max(c.Concurrency, 1)
-	l.getCh, l.putCh = make(chan string), make(chan []string, 1)
+	l.getCh, l.putCh = make(chan string), make(chan listPage, 1)
 	l.quit = make(chan struct{})
+	l.done = make(chan struct{})
+	l.ctx, l.cancel = context.WithCancel(ctx)
 	l.prefixes = prefixes
+	l.delimiter = delimiter
+	l.startAfter = startAfter
 	l.maxKeys = maxKeys
 
 	for i := 0; i < l.c.Concurrency; i++ {
@@ -29,29 +38,79 @@ func newObjectLister(c *Config, b *Bucket, prefixes []string, maxKeys int) (*Obj
 	return l, nil
 }
 
+// ObjectLister lists the objects under prefixes, with requests for each prefix and
+// continuation made by up to Concurrency goroutines. The results are iterated with
+// Next, a page at a time, or with NextObject, an object at a time.
+//
+// The goroutines stop once every object is listed, on the first error, when the
+// context of the lister is cancelled or when the lister is closed. Listers that are not
+// iterated to the end must be closed.
 type ObjectLister struct {
-	b        *Bucket
-	c        *Config
-	prefixes []string
-	maxKeys  int
-
-	next     []string
+	b          *Bucket
+	c          *Config
+	ctx        context.Context
+	cancel     context.CancelFunc
+	prefixes   []string
+	delimiter  string
+	startAfter string
+	maxKeys    int
+
+	next     listPage
+	nextIdx  int // of the next object of NextObject in next
 	err      error
+	errMu    sync.Mutex
 	getCh    chan string
-	putCh    chan []string
+	putCh    chan listPage
 	wg       sync.WaitGroup
 	quit     chan struct{}
 	quitOnce sync.Once
+	done     chan struct{} // closed once the goroutines have stopped
+}
+
+// Object describes an object in a listing.
+type Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+	ETag         string // without surrounding quotes
+	StorageClass string
+}
+
+// listPage is the result of a single list request
+type listPage struct {
+	keys         []string
+	objects      []Object
+	prefixes     []string
+	continuation string // NextContinuationToken of the result
 }
 
 func (l *ObjectLister) closeQuit() {
 	l.quitOnce.Do(func() { close(l.quit) })
 }
 
+// fail stops the lister with err, unless it was stopped before
+func (l *ObjectLister) fail(err error) {
+	l.errMu.Lock()
+	defer l.errMu.Unlock()
+	select {
+	case <-l.quit:
+	default:
+		l.err = err
+		l.closeQuit()
+	}
+}
+
 func (l *ObjectLister) initPrefixes() {
+	defer close(l.done)
+	defer l.cancel()
 	// We first enqueue all of the prefixes we were given
+enqueue:
 	for _, p := range l.prefixes {
-		l.getCh <- p
+		select {
+		case l.getCh <- p:
+		case <-l.quit:
+			break enqueue
+		}
 	}
 	close(l.getCh)
 
@@ -60,31 +119,40 @@ func (l *ObjectLister) initPrefixes() {
 }
 
 func (l *ObjectLister) worker() {
+	defer l.wg.Done()
 	for p := range l.getCh {
 		var continuation string
 	retries:
 		for {
 			res, err := l.retryListObjects(p, continuation)
 			if err != nil {
-				select {
-				case <-l.quit:
-					return
-				default:
-					l.err = err
-					l.closeQuit()
-					return
+				if cerr := l.ctx.Err(); cerr != nil {
+					err = cerr
 				}
+				l.fail(err)
+				return
 			}
 
-			keys := make([]string, 0, len(res.Contents))
+			page := listPage{
+				keys:    make([]string, 0, len(res.Contents)),
+				objects: make([]Object, 0, len(res.Contents)),
+			}
 			for _, c := range res.Contents {
-				keys = append(keys, c.Key)
+				page.keys = append(page.keys, c.Key)
+				page.objects = append(page.objects, c.object())
+			}
+			for _, cp := range res.CommonPrefixes {
+				page.prefixes = append(page.prefixes, cp.Prefix)
 			}
+			page.continuation = res.NextContinuationToken
 
 			select {
 			case <-l.quit:
 				return
-			case l.putCh <- keys:
+			case <-l.ctx.Done():
+				l.fail(l.ctx.Err())
+				return
+			case l.putCh <- page:
 				continuation = res.NextContinuationToken
 				if continuation != "" {
 					continue
@@ -95,38 +163,44 @@ func (l *ObjectLister) worker() {
 			}
 		}
 	}
-
-	l.wg.Done()
 }
 
 func (l *ObjectLister) retryListObjects(p, continuation string) (*listBucketResult, error) {
-	var err error
-	var res *listBucketResult
-	for i := 0; i < l.c.NTry; i++ {
-		opts := listObjectsOptions{MaxKeys: l.maxKeys, Prefix: p, ContinuationToken: continuation}
-		res, err = listObjects(l.c, l.b, opts)
-		if err == nil {
-			return res, nil
-		}
-
-		time.Sleep(time.Duration(math.Exp2(float64(i))) * 100 * time.Millisecond) // exponential back-off
+	opts := listObjectsOptions{MaxKeys: l.maxKeys, Prefix: p, Delimiter: l.delimiter, ContinuationToken: continuation}
+	if continuation == "" {
+		opts.StartAfter = l.startAfter // continued from the token after
 	}
+	return retryListObjects(l.ctx, l.c, l.b, opts)
+}
 
-	return nil, err
+// retryListObjects calls listObjects up to NTry times to recover from transient errors.
+func retryListObjects(ctx context.Context, c *Config, b *Bucket, opts listObjectsOptions) (*listBucketResult, error) {
+	var res *listBucketResult
+	err := retry(ctx, c.NTry, c.retryDelay, func(int) (bool, error) {
+		var err error
+		res, err = listObjects(ctx, c, b, opts)
+		return true, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
 }
 
 // Next moves the iterator to the next set of results. It returns true if there
 // are more results, or false if there are no more results or there was an
 // error.
 func (l *ObjectLister) Next() bool {
-	if l.err != nil {
+	l.nextIdx = 0
+	select {
+	case <-l.quit: // closed, or failed
 		return false
+	default:
 	}
 
 	select {
 	case n, ok := <-l.putCh:
 		if !ok {
-			l.err = nil
 			return false
 		}
 
@@ -137,16 +211,62 @@ func (l *ObjectLister) Next() bool {
 	}
 }
 
+// NextObject returns the next object listed, and true, or false if there are no more
+// objects or there was an error. It iterates over the objects of the results of Next,
+// calling Next as needed, and is not to be mixed with calls to Next.
+func (l *ObjectLister) NextObject() (Object, bool) {
+	for l.nextIdx >= len(l.next.objects) {
+		if !l.Next() {
+			l.next = listPage{}
+			return Object{}, false
+		}
+	}
+	o := l.next.objects[l.nextIdx]
+	l.nextIdx++
+	return o, true
+}
+
+// Value returns the keys of the current set of results.
 func (l *ObjectLister) Value() []string {
-	return l.next
+	return l.next.keys
+}
+
+// Objects returns the keys of the current set of results with their size,
+// last modified time, ETag and storage class.
+func (l *ObjectLister) Objects() []Object {
+	return l.next.objects
 }
 
+// ContinuationToken returns the continuation token of the current set of results, to
+// list the rest of its prefix with ListObjectsPage, or "" if it's the last set of
+// results of its prefix.
+func (l *ObjectLister) ContinuationToken() string {
+	return l.next.continuation
+}
+
+// CommonPrefixes returns the common prefixes of the current set of results.
+// These are only returned when listing with a delimiter, and represent the
+// "directories" under the listed prefix.
+func (l *ObjectLister) CommonPrefixes() []string {
+	return l.next.prefixes
+}
+
+// Error returns the error that stopped the lister, such as the error of the context
+// when it is cancelled, or nil. It is nil when the lister is closed before then.
 func (l *ObjectLister) Error() error {
+	l.errMu.Lock()
+	defer l.errMu.Unlock()
 	return l.err
 }
 
+// Close stops the lister, cancelling its requests in flight, and returns once its
+// goroutines have stopped. Next and NextObject then return false.
 func (l *ObjectLister) Close() {
+	l.errMu.Lock()
 	l.closeQuit()
+	l.errMu.Unlock()
+	l.cancel()
+	<-l.done
 }
 
 // ListObjectsOptions specifies the options for a ListObjects operation on a S3
@@ -156,8 +276,12 @@ type listObjectsOptions struct {
 	MaxKeys int
 	// Only list those keys that start with the given prefix
 	Prefix string
+	// Group keys that contain the delimiter after the prefix into common prefixes
+	Delimiter string
 	// Continuation token from the previous request
 	ContinuationToken string
+	// Only list those keys that sort after the given key
+	StartAfter string
 }
 
 type listBucketResult struct {
@@ -168,15 +292,25 @@ type listBucketResult struct {
 	IsTruncated           bool                 `xml:"IsTrucated"`
 	NextContinuationToken string               `xml:"NextContinuationToken"`
 	Contents              []listBucketContents `xml:"Contents"`
+	CommonPrefixes        []CommonPrefix       `xml:"CommonPrefixes"`
 }
 
 type listBucketContents struct {
-	Key            string         `xml:"Key"`
-	LastModified   time.Time      `xml:"LastModified"`
-	ETag           string         `xml:"ETag"`
-	Size           int64          `xml:"Size"`
-	StorageClass   string         `xml:"StorageClass"`
-	CommonPrefixes []CommonPrefix `xml:"CommonPrefixes"`
+	Key          string    `xml:"Key"`
+	LastModified time.Time `xml:"LastModified"`
+	ETag         string    `xml:"ETag"`
+	Size         int64     `xml:"Size"`
+	StorageClass string    `xml:"StorageClass"`
+}
+
+func (c *listBucketContents) object() Object {
+	return Object{
+		Key:          c.Key,
+		Size:         c.Size,
+		LastModified: c.LastModified,
+		ETag:         strings.Trim(c.ETag, `"`),
+		StorageClass: c.StorageClass,
+	}
 }
 
 type CommonPrefix struct {
@@ -187,7 +321,7 @@ type ListObjectsResult struct {
 	result *listBucketResult
 }
 
-func listObjects(c *Config, b *Bucket, opts listObjectsOptions) (result *listBucketResult, err error) {
+func listObjects(ctx context.Context, c *Config, b *Bucket, opts listObjectsOptions) (result *listBucketResult, err error) {
 	result = new(listBucketResult)
 	u, err := b.url("")
 	if err != nil {
@@ -202,23 +336,32 @@ func listObjects(c *Config, b *Bucket, opts listObjectsOptions) (result *listBuc
 	if opts.Prefix != "" {
 		q.Set("prefix", opts.Prefix)
 	}
+	if opts.Delimiter != "" {
+		q.Set("delimiter", opts.Delimiter)
+	}
 	if opts.ContinuationToken != "" {
 		q.Set("continuation-token", opts.ContinuationToken)
 	}
+	if opts.StartAfter != "" {
+		q.Set("start-after", opts.StartAfter)
+	}
 	u.RawQuery = q.Encode()
 
-	r := http.Request{
+	r := (&http.Request{
 		Method: "GET",
 		URL:    u,
-	}
-	b.Sign(&r)
+	}).WithContext(ctx)
+	b.Sign(r)
 
-	resp, err := b.Do(&r)
+	resp, err := b.Do(r)
 	if err != nil {
 		return nil, err
 	}
 	defer checkClose(resp.Body, err)
 	if resp.StatusCode != 200 {
+		if b.redirect(resp) {
+			return listObjects(ctx, c, b, opts)
+		}
 		return nil, newRespError(resp)
 	}
 
@@ -0,0 +1,125 @@
+package s3gof3r
+
+import (
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// VerifyMd5 verifies the md5 file of the object at path, stored by puts with Md5Check,
+// against the md5 of the object, computed by downloading it. The error matches
+// ErrMd5Missing if the object has no md5 file, ErrMd5Orphaned if the md5 file is left
+// without its object, e.g. after it was deleted without Md5Check, and ErrIntegrity if
+// they don't match. The object is only downloaded once both are found.
+func (b *Bucket) VerifyMd5(path string) error {
+	md5Path, err := b.md5Path(path)
+	if err != nil {
+		return err
+	}
+	if _, err = b.Head(path); errors.Is(err, ErrNotFound) {
+		if _, merr := b.Head(md5Path); merr == nil {
+			return fmt.Errorf("%w: %s, of %s which doesn't exist", ErrMd5Orphaned, md5Path, path)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	given, err := b.getMd5(md5Path)
+	if err != nil {
+		return err
+	}
+	calc, err := b.computeMd5(path)
+	if err != nil {
+		return err
+	}
+	if given != calc {
+		return fmt.Errorf("%w: md5 of %s given by %s: %s, calculated: %s", ErrIntegrity, path, md5Path, given, calc)
+	}
+	return nil
+}
+
+// RepairMd5 puts the md5 file of the object at path, with the md5 computed by
+// downloading the object, replacing any md5 file found missing or mismatched by
+// VerifyMd5. The md5 file of an object that doesn't exist is deleted.
+func (b *Bucket) RepairMd5(path string) error {
+	md5Path, err := b.md5Path(path)
+	if err != nil {
+		return err
+	}
+	if _, err = b.Head(path); errors.Is(err, ErrNotFound) {
+		_, err = b.delete(md5Path) // not Delete, which deletes the md5 file of md5Path too
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	calc, err := b.computeMd5(path)
+	if err != nil {
+		return err
+	}
+	u, err := b.url(md5Path)
+	if err != nil {
+		return err
+	}
+	resp, err := b.retryRequest("PUT", u, []byte(calc), nil)
+	if err != nil {
+		return err
+	}
+	defer checkClose(resp.Body, err)
+	if resp.StatusCode != 200 {
+		return newRespError(resp)
+	}
+	return nil
+}
+
+// md5Path returns the key of the md5 file of the object at path
+func (b *Bucket) md5Path(path string) (string, error) {
+	u, err := b.url(path)
+	if err != nil {
+		return "", err
+	}
+	return md5Key(b.objectKey(*u)), nil
+}
+
+// getMd5 returns the md5 stored in the md5 file at md5Path
+func (b *Bucket) getMd5(md5Path string) (given string, err error) {
+	u, err := b.url(md5Path)
+	if err != nil {
+		return "", err
+	}
+	resp, err := b.retryRequest("GET", u, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	defer checkClose(resp.Body, err)
+	if resp.StatusCode == 404 {
+		return "", fmt.Errorf("%w: %s", ErrMd5Missing, md5Path)
+	}
+	if resp.StatusCode != 200 {
+		return "", newRespError(resp)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	return string(data), err
+}
+
+// computeMd5 returns the md5 of the object at path, in hex, downloading it without
+// checking its md5 file
+func (b *Bucket) computeMd5(path string) (string, error) {
+	c := *b.Config
+	c.Md5Check = false
+	r, _, err := b.withConfig(&c).GetReader(path)
+	if err != nil {
+		return "", err
+	}
+	h := md5.New()
+	if _, err = io.Copy(h, r); err != nil {
+		r.Close()
+		return "", err
+	}
+	if err = r.Close(); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
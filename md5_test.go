@@ -0,0 +1,166 @@
+package s3gof3r
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestVerifyRepairMd5(t *testing.T) {
+	s := newFakeS3()
+	var mu sync.Mutex
+	var deleted []string
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" {
+			mu.Lock()
+			deleted = append(deleted, r.URL.Path)
+			mu.Unlock()
+		}
+		s.ServeHTTP(w, r)
+	}))
+	b.Config.Md5Check = true
+	if err := b.PutBytes("dir/key", []byte("data"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.VerifyMd5("dir/key"); err != nil {
+		t.Errorf("Expected md5 verified. Actual: %v", err)
+	}
+
+	s.mu.Lock()
+	s.objects["/bucket/.md5/dir/key.md5"] = []byte("00000000000000000000000000000000")
+	s.mu.Unlock()
+	if err := b.VerifyMd5("dir/key"); !errors.Is(err, ErrIntegrity) {
+		t.Errorf("Expected md5 mismatch. Actual: %v", err)
+	}
+	if err := b.RepairMd5("dir/key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.VerifyMd5("dir/key"); err != nil {
+		t.Errorf("Expected md5 repaired. Actual: %v", err)
+	}
+
+	s.mu.Lock()
+	delete(s.objects, "/bucket/.md5/dir/key.md5")
+	s.mu.Unlock()
+	if err := b.VerifyMd5("dir/key"); !errors.Is(err, ErrMd5Missing) {
+		t.Errorf("Expected missing md5. Actual: %v", err)
+	}
+	if err := b.RepairMd5("dir/key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.VerifyMd5("dir/key"); err != nil {
+		t.Errorf("Expected md5 repaired. Actual: %v", err)
+	}
+
+	s.mu.Lock()
+	delete(s.objects, "/bucket/dir/key")
+	s.mu.Unlock()
+	if err := b.VerifyMd5("dir/key"); !errors.Is(err, ErrMd5Orphaned) {
+		t.Errorf("Expected orphaned md5. Actual: %v", err)
+	}
+	if err := b.RepairMd5("dir/key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.VerifyMd5("dir/key"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected object not found. Actual: %v", err)
+	}
+	if len(s.objects) != 0 {
+		t.Errorf("Expected orphaned md5 deleted. Actual: %d objects", len(s.objects))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(deleted) != 1 || deleted[0] != "/bucket/.md5/dir/key.md5" {
+		t.Errorf("Expected only the orphaned md5 deleted. Actual: %v", deleted)
+	}
+}
+
+// TestMd5AfterObject puts objects whose md5 file fails to be put, as if the process
+// died after the object was stored, and objects whose completion fails.
+func TestMd5AfterObject(t *testing.T) {
+	s := newFakeS3()
+	var mu sync.Mutex
+	var failMd5, failComplete bool
+	var completed, md5Puts int
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == "PUT" && strings.HasPrefix(r.URL.Path, "/bucket/.md5/"):
+			md5Puts++
+			if completed == 0 {
+				t.Errorf("md5 file put before the object")
+			}
+			if failMd5 {
+				w.WriteHeader(403)
+				w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+				return
+			}
+		case r.Method == "POST" && r.URL.Query().Get("uploadId") != "" && failComplete:
+			w.WriteHeader(500)
+			return
+		case r.Method == "POST" && r.URL.Query().Get("uploadId") != "",
+			r.Method == "PUT" && !r.URL.Query().Has("partNumber"):
+			completed++
+		}
+		s.ServeHTTP(w, r)
+	}))
+	b.Config.Md5Check = true
+
+	for _, size := range []int{10, 12 << 20} {
+		key := fmt.Sprintf("key%d", size)
+		data := bytes.Repeat([]byte("abcdefgh"), size/8+1)[:size]
+		mu.Lock()
+		failMd5, completed, md5Puts = true, 0, 0
+		mu.Unlock()
+		w, err := b.PutWriter(key, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		err = w.Close()
+		if !errors.Is(err, ErrMd5NotStored) || errors.Is(err, ErrAccessDenied) {
+			t.Errorf("Expected error: %v. Actual: %v", ErrMd5NotStored, err)
+		}
+		if r := w.(MultipartWriter).Result(); r.ETag == "" {
+			t.Errorf("Expected result of the object stored. Actual: %+v", r)
+		}
+		if obj, _ := s.object("/bucket/" + key); !bytes.Equal(obj, data) {
+			t.Errorf("Expected object of %d bytes stored. Actual: %d", size, len(obj))
+		}
+		if err := b.VerifyMd5(key); !errors.Is(err, ErrMd5Missing) {
+			t.Errorf("Expected missing md5. Actual: %v", err)
+		}
+
+		mu.Lock()
+		failMd5 = false
+		mu.Unlock()
+		if err := b.RepairMd5(key); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.VerifyMd5(key); err != nil {
+			t.Errorf("Expected md5 repaired. Actual: %v", err)
+		}
+
+		if size < 5<<20 {
+			continue // put with a single request
+		}
+		mu.Lock()
+		failComplete, md5Puts = true, 0
+		mu.Unlock()
+		if err := b.PutBytes(key, data, nil); err == nil || errors.Is(err, ErrMd5NotStored) {
+			t.Errorf("Expected error completing the upload. Actual: %v", err)
+		}
+		mu.Lock()
+		if md5Puts != 0 {
+			t.Errorf("Expected no md5 file put for the failed upload. Actual: %d puts", md5Puts)
+		}
+		failComplete = false
+		mu.Unlock()
+	}
+}
@@ -0,0 +1,165 @@
+package s3gof3r
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// the preconditions of writes, checked by S3 on single puts and on completion of multipart uploads
+var writePreconditionHeaders = []string{"If-Match", "If-None-Match"}
+
+// takePreconditions removes the write precondition headers from h and returns them.
+func takePreconditions(h http.Header) http.Header {
+	c := http.Header{}
+	for _, k := range writePreconditionHeaders {
+		if v, ok := h[k]; ok {
+			c[k] = v
+			h.Del(k)
+		}
+	}
+	return c
+}
+
+type completedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name `xml:"CompleteMultipartUpload"`
+	Part    []completedPart
+}
+
+// multipartURL returns u with the query parameters in v
+func multipartURL(u *url.URL, v url.Values) *url.URL {
+	mu := *u
+	mu.RawQuery = v.Encode()
+	return &mu
+}
+
+// initiateMultipart starts a multipart upload to u and returns its upload ID.
+func (b *Bucket) initiateMultipart(u *url.URL, h http.Header) (uploadID string, err error) {
+	resp, err := b.retryRequest("POST", multipartURL(u, url.Values{"uploads": {""}}), nil, h)
+	if err != nil {
+		return "", err
+	}
+	defer checkClose(resp.Body, err)
+	if resp.StatusCode != 200 {
+		return "", newRespError(resp)
+	}
+	var res struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err = xml.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", err
+	}
+	return res.UploadID, nil
+}
+
+// completeMultipart completes the multipart upload to u with the headers in h and returns
+// the ETag of the object.
+func (b *Bucket) completeMultipart(u *url.URL, uploadID string, parts []completedPart, h http.Header) (etag string, err error) {
+	body, err := xml.Marshal(completeMultipartUpload{Part: parts})
+	if err != nil {
+		return "", err
+	}
+	resp, err := b.retryRequest("POST", multipartURL(u, url.Values{"uploadId": {uploadID}}), body, h)
+	if err != nil {
+		return "", err
+	}
+	defer checkClose(resp.Body, err)
+	if resp.StatusCode != 200 {
+		return "", newRespError(resp)
+	}
+	res, err := decodeResult(resp)
+	if err != nil {
+		return "", err
+	}
+	return res.ETag, nil
+}
+
+type uploadedPart struct {
+	PartNumber int
+	ETag       string
+	Size       int64
+}
+
+type listPartsResult struct {
+	XMLName              xml.Name `xml:"ListPartsResult"`
+	IsTruncated          bool
+	NextPartNumberMarker string
+	Parts                []uploadedPart `xml:"Part"`
+}
+
+// listParts returns the parts uploaded to the multipart upload to u, following continuations.
+func (b *Bucket) listParts(u *url.URL, uploadID string) (parts []uploadedPart, err error) {
+	v := url.Values{"uploadId": {uploadID}}
+	for {
+		res, err := b.listPartsPage(multipartURL(u, v))
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, res.Parts...)
+		if !res.IsTruncated || res.NextPartNumberMarker == "" {
+			return parts, nil
+		}
+		v.Set("part-number-marker", res.NextPartNumberMarker)
+	}
+}
+
+func (b *Bucket) listPartsPage(u *url.URL) (res *listPartsResult, err error) {
+	resp, err := b.retryRequest("GET", u, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer checkClose(resp.Body, err)
+	if resp.StatusCode != 200 {
+		return nil, newRespError(resp)
+	}
+	res = new(listPartsResult)
+	if err = xml.NewDecoder(resp.Body).Decode(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// abortMultipart aborts the multipart upload to u.
+func (b *Bucket) abortMultipart(u *url.URL, uploadID string) (err error) {
+	resp, err := b.retryRequest("DELETE", multipartURL(u, url.Values{"uploadId": {uploadID}}), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer checkClose(resp.Body, err)
+	if resp.StatusCode != 204 {
+		return newRespError(resp)
+	}
+	return nil
+}
+
+type result struct {
+	XMLName xml.Name
+	ETag    string
+}
+
+// decodeResult decodes the xml result of a copy or complete multipart upload request.
+// S3 may return an error under a 200, which is returned as a *RespError.
+func decodeResult(resp *http.Response) (*result, error) {
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	res := new(result)
+	if err := xml.Unmarshal(b, res); err != nil {
+		return nil, fmt.Errorf("parse error: %s", err)
+	}
+	if res.XMLName.Local == "Error" {
+		e := &RespError{StatusCode: resp.StatusCode}
+		xml.NewDecoder(bytes.NewReader(b)).Decode(e)
+		return nil, e
+	}
+	return res, nil
+}
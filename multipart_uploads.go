@@ -0,0 +1,83 @@
+package s3gof3r
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"time"
+)
+
+// MultipartUpload is an initiated multipart upload that has not been completed or aborted.
+type MultipartUpload struct {
+	Key          string
+	UploadID     string `xml:"UploadId"`
+	Initiated    time.Time
+	StorageClass string
+}
+
+type listMultipartUploadsResult struct {
+	XMLName            xml.Name `xml:"ListMultipartUploadsResult"`
+	IsTruncated        bool
+	NextKeyMarker      string
+	NextUploadIDMarker string            `xml:"NextUploadIdMarker"`
+	Uploads            []MultipartUpload `xml:"Upload"`
+}
+
+// ListMultipartUploads returns the multipart uploads in progress for keys beginning with prefix,
+// following continuations until every upload is listed. Parts of these uploads are stored, and
+// charged for, until the upload is completed or aborted with AbortMultipartUpload.
+func (b *Bucket) ListMultipartUploads(prefix string) (uploads []MultipartUpload, err error) {
+	u, err := b.url("")
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{}
+	q.Set("uploads", "")
+	if prefix != "" {
+		q.Set("prefix", prefix)
+	}
+	for {
+		u.RawQuery = q.Encode()
+		res, err := b.listMultipartUploads(u)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, res.Uploads...)
+		if !res.IsTruncated {
+			return uploads, nil
+		}
+		if res.NextKeyMarker == "" && res.NextUploadIDMarker == "" {
+			return nil, errors.New("truncated multipart upload list without a next marker")
+		}
+		q.Set("key-marker", res.NextKeyMarker)
+		q.Set("upload-id-marker", res.NextUploadIDMarker)
+	}
+}
+
+func (b *Bucket) listMultipartUploads(u *url.URL) (res *listMultipartUploadsResult, err error) {
+	resp, err := b.retryRequest("GET", u, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer checkClose(resp.Body, err)
+	if resp.StatusCode != 200 {
+		return nil, newRespError(resp)
+	}
+	res = new(listMultipartUploadsResult)
+	if err = xml.NewDecoder(resp.Body).Decode(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// AbortMultipartUpload aborts the multipart upload of key with uploadID, deleting its stored parts.
+func (b *Bucket) AbortMultipartUpload(key, uploadID string) error {
+	if uploadID == "" {
+		return errors.New("empty upload ID")
+	}
+	u, err := b.url(key)
+	if err != nil {
+		return err
+	}
+	return b.abortMultipart(u, uploadID)
+}
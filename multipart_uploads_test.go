@@ -0,0 +1,73 @@
+package s3gof3r
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestListMultipartUploads(t *testing.T) {
+	pages := []string{
+		`<ListMultipartUploadsResult>
+			<IsTruncated>true</IsTruncated>
+			<NextKeyMarker>a/2</NextKeyMarker>
+			<NextUploadIdMarker>upload-2</NextUploadIdMarker>
+			<Upload><Key>a/1</Key><UploadId>upload-1</UploadId><Initiated>2024-01-02T03:04:05.000Z</Initiated><StorageClass>STANDARD</StorageClass></Upload>
+			<Upload><Key>a/2</Key><UploadId>upload-2</UploadId><Initiated>2024-01-03T03:04:05.000Z</Initiated></Upload>
+		</ListMultipartUploadsResult>`,
+		`<ListMultipartUploadsResult>
+			<IsTruncated>false</IsTruncated>
+			<Upload><Key>a/3</Key><UploadId>upload-3</UploadId><Initiated>2024-01-04T03:04:05.000Z</Initiated></Upload>
+		</ListMultipartUploadsResult>`,
+	}
+	var aborted string
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/bucket" && q.Has("uploads"):
+			if q.Get("prefix") != "a/" {
+				t.Errorf("expected prefix a/, got %q", q.Get("prefix"))
+			}
+			if q.Get("key-marker") == "" {
+				fmt.Fprint(w, pages[0])
+				return
+			}
+			if q.Get("key-marker") != "a/2" || q.Get("upload-id-marker") != "upload-2" {
+				t.Errorf("unexpected markers: %v", q)
+			}
+			fmt.Fprint(w, pages[1])
+		case r.Method == "DELETE" && r.URL.Path == "/bucket/a/1":
+			aborted = q.Get("uploadId")
+			w.WriteHeader(204)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+
+	uploads, err := b.ListMultipartUploads("a/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uploads) != 3 {
+		t.Fatalf("expected 3 uploads, got %d", len(uploads))
+	}
+	for i, u := range uploads {
+		if u.Key != fmt.Sprintf("a/%d", i+1) || u.UploadID != fmt.Sprintf("upload-%d", i+1) {
+			t.Errorf("unexpected upload %d: %+v", i, u)
+		}
+	}
+	if want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC); !uploads[0].Initiated.Equal(want) {
+		t.Errorf("Expected initiated: %v. Actual: %v", want, uploads[0].Initiated)
+	}
+
+	if err := b.AbortMultipartUpload("a/1", uploads[0].UploadID); err != nil {
+		t.Fatal(err)
+	}
+	if aborted != "upload-1" {
+		t.Errorf("expected abort of upload-1, got %q", aborted)
+	}
+	if err := b.AbortMultipartUpload("a/1", ""); err == nil {
+		t.Error("expected error for empty upload ID")
+	}
+}
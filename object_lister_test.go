@@ -0,0 +1,292 @@
+package s3gof3r
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// listHandler serves ListObjectsV2 requests for keys, using the index of the
+// next key as the continuation token, and the start-after of the first page.
+func listHandler(t *testing.T, keys []string) http.Handler {
+	sort.Strings(keys)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("list-type") != "2" {
+			t.Errorf("unexpected list request %s", r.URL)
+		}
+		prefix, delim := q.Get("prefix"), q.Get("delimiter")
+		maxKeys, _ := strconv.Atoi(q.Get("max-keys"))
+		if maxKeys == 0 {
+			maxKeys = 1000
+		}
+		start, _ := strconv.Atoi(q.Get("continuation-token"))
+
+		res := listBucketResult{Name: "bucket", Prefix: prefix}
+		seen := make(map[string]bool)
+		i := start
+		for ; i < len(keys) && res.KeyCount < maxKeys; i++ {
+			k := keys[i]
+			if !strings.HasPrefix(k, prefix) || k <= q.Get("start-after") {
+				continue
+			}
+			if j := strings.Index(k[len(prefix):], delim); delim != "" && j >= 0 {
+				cp := k[:len(prefix)+j+len(delim)]
+				if !seen[cp] {
+					seen[cp] = true
+					res.CommonPrefixes = append(res.CommonPrefixes, CommonPrefix{cp})
+					res.KeyCount++
+				}
+				continue
+			}
+			res.Contents = append(res.Contents, listBucketContents{Key: k, ETag: `"etag"`, Size: int64(len(k))})
+			res.KeyCount++
+		}
+		if i < len(keys) {
+			res.NextContinuationToken = strconv.Itoa(i)
+		}
+		xml.NewEncoder(w).Encode(struct {
+			XMLName xml.Name `xml:"ListBucketResult"`
+			listBucketResult
+		}{listBucketResult: res})
+	})
+}
+
+var listerKeys = []string{
+	"list/a",
+	"list/b",
+	"list/one/two",
+	"list/one/three",
+	"list/two/three",
+	"other",
+}
+
+func TestListObjectsWithDelimiter(t *testing.T) {
+	b := newTestBucket(t, listHandler(t, listerKeys))
+	l, err := b.ListObjectsWithDelimiter([]string{"list/"}, "/", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys, prefixes []string
+	for l.Next() {
+		keys = append(keys, l.Value()...)
+		prefixes = append(prefixes, l.CommonPrefixes()...)
+	}
+	if err := l.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{"list/a", "list/b"}; !reflect.DeepEqual(keys, expect) {
+		t.Errorf("expected keys %v, got %v", expect, keys)
+	}
+	if expect := []string{"list/one/", "list/two/"}; !reflect.DeepEqual(prefixes, expect) {
+		t.Errorf("expected common prefixes %v, got %v", expect, prefixes)
+	}
+}
+
+func TestListObjectsMetadata(t *testing.T) {
+	b := newTestBucket(t, listHandler(t, listerKeys))
+	l, err := b.ListObjects([]string{"list/one/"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var objects []Object
+	for l.Next() {
+		objects = append(objects, l.Objects()...)
+	}
+	if err := l.Error(); err != nil {
+		t.Fatal(err)
+	}
+	expect := []Object{
+		{Key: "list/one/three", Size: 14, ETag: "etag"},
+		{Key: "list/one/two", Size: 12, ETag: "etag"},
+	}
+	if !reflect.DeepEqual(objects, expect) {
+		t.Errorf("expected %+v, got %+v", expect, objects)
+	}
+}
+
+func TestListObjectsNextObject(t *testing.T) {
+	b := newTestBucket(t, listHandler(t, listerKeys))
+	l, err := b.ListObjects([]string{"list/"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for o, ok := l.NextObject(); ok; o, ok = l.NextObject() {
+		keys = append(keys, o.Key)
+	}
+	if err := l.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if expect := listerKeys[:5]; !reflect.DeepEqual(keys, expect) {
+		t.Errorf("expected keys %v, got %v", expect, keys)
+	}
+	l.Close()
+}
+
+func TestListObjectsContext(t *testing.T) {
+	list := listHandler(t, listerKeys)
+	var mu sync.Mutex
+	var requests int
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		n := requests
+		mu.Unlock()
+		if n > 1 {
+			<-r.Context().Done() // the continuation is in flight until cancelled
+			return
+		}
+		list.ServeHTTP(w, r)
+	}))
+	b.Config.NTry = 3
+
+	ctx, cancel := context.WithCancel(context.Background())
+	l, err := b.ListObjectsContext(ctx, []string{"list/"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o, ok := l.NextObject(); !ok || o.Key != "list/a" {
+		t.Fatalf("expected list/a, got %+v %v", o, l.Error())
+	}
+	cancel()
+	for _, ok := l.NextObject(); ok; _, ok = l.NextObject() {
+	}
+	if err := l.Error(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected error: %v. Actual: %v", context.Canceled, err)
+	}
+	waitClose(t, l)
+	mu.Lock()
+	defer mu.Unlock()
+	if requests > 2 {
+		t.Errorf("Expected at most 2 requests, without retries once cancelled. Actual: %d", requests)
+	}
+}
+
+func TestListObjectsCloseEarly(t *testing.T) {
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("list/%03d", i)
+	}
+	b := newTestBucket(t, listHandler(t, keys))
+	b.Config.Concurrency = 4
+	l, err := b.ListObjects([]string{"list/0", "list/1", "list/2", "list/3", "list/4"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !l.Next() {
+		t.Fatal(l.Error())
+	}
+	waitClose(t, l)
+	if l.Next() {
+		t.Error("expected no results once closed")
+	}
+	if err := l.Error(); err != nil {
+		t.Errorf("expected no error once closed, got %v", err)
+	}
+}
+
+// waitClose closes l, failing if its goroutines don't stop.
+func waitClose(t *testing.T, l *ObjectLister) {
+	t.Helper()
+	closed := make(chan struct{})
+	go func() {
+		l.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("lister goroutines did not stop")
+	}
+}
+
+func TestListObjectsAfter(t *testing.T) {
+	h := listHandler(t, listerKeys)
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("continuation-token") != "" && q.Has("start-after") {
+			t.Errorf("unexpected start-after of continuation %s", r.URL)
+		}
+		h.ServeHTTP(w, r)
+	}))
+	for _, tt := range []struct {
+		startAfter string
+		expect     []string
+	}{
+		{"", []string{"list/a", "list/b", "list/one/three", "list/one/two", "list/two/three", "other"}},
+		{"list/a", []string{"list/b", "list/one/three", "list/one/two", "list/two/three", "other"}},
+		{"list/o", []string{"list/one/three", "list/one/two", "list/two/three", "other"}},
+		{"list/z", []string{"other"}},
+		{"p", nil},
+	} {
+		l, err := b.ListObjectsAfter([]string{"list/", "other"}, tt.startAfter, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var keys []string
+		for o, ok := l.NextObject(); ok; o, ok = l.NextObject() {
+			keys = append(keys, o.Key)
+		}
+		if err := l.Error(); err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(keys) // of the prefixes listed concurrently
+		if !reflect.DeepEqual(keys, tt.expect) {
+			t.Errorf("Expected keys after %q: %v. Actual: %v", tt.startAfter, tt.expect, keys)
+		}
+	}
+}
+
+func TestListObjectsPage(t *testing.T) {
+	b := newTestBucket(t, listHandler(t, listerKeys))
+	var keys []string
+	var pages int
+	token := ""
+	for {
+		objects, next, err := b.ListObjectsPage("list/", token, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		for _, o := range objects {
+			keys = append(keys, o.Key)
+		}
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	if expect := listerKeys[:5]; !reflect.DeepEqual(keys, expect) {
+		t.Errorf("expected keys %v, got %v", expect, keys)
+	}
+	if pages != 3 {
+		t.Errorf("Expected 3 pages. Actual: %d", pages)
+	}
+
+	// the token of a lister's page resumes its listing
+	l, err := b.ListObjects([]string{"list/"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if !l.Next() {
+		t.Fatal(l.Error())
+	}
+	objects, _, err := b.ListObjectsPage("list/", l.ContinuationToken(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 2 || objects[0].Key != "list/one/three" {
+		t.Errorf("expected the second page, got %+v", objects)
+	}
+}
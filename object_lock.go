@@ -0,0 +1,173 @@
+package s3gof3r
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	objectLockModeHeader        = "X-Amz-Object-Lock-Mode"
+	objectLockRetainUntilHeader = "X-Amz-Object-Lock-Retain-Until-Date"
+	objectLockLegalHoldHeader   = "X-Amz-Object-Lock-Legal-Hold"
+)
+
+// Modes of the retention of objects in buckets with Object Lock
+// See https://docs.aws.amazon.com/AmazonS3/latest/userguide/object-lock-overview.html
+const (
+	// ObjectLockGovernance protects objects from deletion or overwrite, except by users
+	// with the s3:BypassGovernanceRetention permission
+	ObjectLockGovernance = "GOVERNANCE"
+	// ObjectLockCompliance protects objects from deletion or overwrite by any user,
+	// including the root user, until their retention expires
+	ObjectLockCompliance = "COMPLIANCE"
+)
+
+// Statuses of the legal hold of objects
+const (
+	legalHoldOn  = "ON"
+	legalHoldOff = "OFF"
+)
+
+// ObjectRetention is the retention of an object in a bucket with Object Lock: the object
+// can't be deleted or overwritten in Mode until RetainUntilDate.
+type ObjectRetention struct {
+	XMLName         xml.Name  `xml:"Retention"`
+	Mode            string    `xml:"Mode"`
+	RetainUntilDate time.Time `xml:"RetainUntilDate"`
+}
+
+func (r ObjectRetention) validate() error {
+	if r.Mode != ObjectLockGovernance && r.Mode != ObjectLockCompliance {
+		return fmt.Errorf("invalid object lock mode: %q", r.Mode)
+	}
+	if r.RetainUntilDate.IsZero() {
+		return fmt.Errorf("object lock mode %s without a retain until date", r.Mode)
+	}
+	return nil
+}
+
+type legalHold struct {
+	XMLName xml.Name `xml:"LegalHold"`
+	Status  string   `xml:"Status"`
+}
+
+// validateObjectLock validates the object lock headers of h, which set the retention
+// and legal hold of a put: the mode and the retain until date, in ISO 8601 format, must
+// be set together, and the legal hold is ON or OFF.
+func validateObjectLock(h http.Header) error {
+	mode, until := h.Get(objectLockModeHeader), h.Get(objectLockRetainUntilHeader)
+	if mode != "" || until != "" {
+		if until == "" {
+			return fmt.Errorf("object lock mode %s without a retain until date", mode)
+		}
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return fmt.Errorf("invalid object lock retain until date: %q", until)
+		}
+		if err := (ObjectRetention{Mode: mode, RetainUntilDate: t}).validate(); err != nil {
+			return err
+		}
+	}
+	if s := h.Get(objectLockLegalHoldHeader); s != "" && s != legalHoldOn && s != legalHoldOff {
+		return fmt.Errorf("invalid object lock legal hold: %q", s)
+	}
+	return nil
+}
+
+// PutWriterWithObjectLock is like PutWriter but sets the retention of the object to
+// retention, if its Mode is set, and places a legal hold on it if legalHold is set, for
+// buckets with Object Lock enabled.
+//
+// The object lock headers may be passed to PutWriter instead:
+// x-amz-object-lock-mode, x-amz-object-lock-retain-until-date (in ISO 8601 format,
+// e.g. 2030-01-02T15:04:05Z) and x-amz-object-lock-legal-hold (ON or OFF).
+func (b *Bucket) PutWriterWithObjectLock(path string, h http.Header, retention ObjectRetention, legalHold bool) (w io.WriteCloser, err error) {
+	h = cloneHeader(h)
+	if retention.Mode != "" {
+		if err = retention.validate(); err != nil {
+			return nil, err
+		}
+		h.Set(objectLockModeHeader, retention.Mode)
+		h.Set(objectLockRetainUntilHeader, retention.RetainUntilDate.UTC().Format(time.RFC3339))
+	}
+	if legalHold {
+		h.Set(objectLockLegalHoldHeader, legalHoldOn)
+	}
+	return b.PutWriter(path, h)
+}
+
+// GetObjectRetention returns the retention of the object at path.
+// As with GetReader, a version ID may be included in the path as a url parameter.
+func (b *Bucket) GetObjectRetention(path string) (r ObjectRetention, err error) {
+	err = b.getSubresource(path, "retention", &r)
+	return r, err
+}
+
+// PutObjectRetention sets the retention of the object at path to r. Retention in
+// ObjectLockCompliance mode can only be extended.
+// As with GetReader, a version ID may be included in the path as a url parameter.
+func (b *Bucket) PutObjectRetention(path string, r ObjectRetention) error {
+	if err := r.validate(); err != nil {
+		return err
+	}
+	r.RetainUntilDate = r.RetainUntilDate.UTC().Truncate(time.Second)
+	return b.putSubresource(path, "retention", r)
+}
+
+// PutObjectLegalHold places a legal hold on the object at path if on is set, or
+// removes it. An object with a legal hold can't be deleted or overwritten, regardless
+// of its retention, until the hold is removed.
+// As with GetReader, a version ID may be included in the path as a url parameter.
+func (b *Bucket) PutObjectLegalHold(path string, on bool) error {
+	lh := legalHold{Status: legalHoldOff}
+	if on {
+		lh.Status = legalHoldOn
+	}
+	return b.putSubresource(path, "legal-hold", lh)
+}
+
+// getSubresource decodes the subresource of the object at path, encoded as XML, into v
+func (b *Bucket) getSubresource(path, subresource string, v interface{}) (err error) {
+	u, err := b.subresourceURL(path, subresource)
+	if err != nil {
+		return err
+	}
+	resp, err := b.retryRequest("GET", u, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer checkClose(resp.Body, err)
+	if resp.StatusCode != 200 {
+		return newRespError(resp)
+	}
+	return xml.NewDecoder(resp.Body).Decode(v)
+}
+
+// putSubresource puts v, encoded as XML, to the subresource of the object at path
+func (b *Bucket) putSubresource(path, subresource string, v interface{}) (err error) {
+	u, err := b.subresourceURL(path, subresource)
+	if err != nil {
+		return err
+	}
+	body, err := xml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	sum := md5.Sum(body)
+	h := http.Header{}
+	h.Set(md5Header, base64.StdEncoding.EncodeToString(sum[:]))
+	resp, err := b.retryRequest("PUT", u, body, h)
+	if err != nil {
+		return err
+	}
+	defer checkClose(resp.Body, err)
+	if resp.StatusCode != 200 {
+		return newRespError(resp)
+	}
+	return nil
+}
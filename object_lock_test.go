@@ -0,0 +1,113 @@
+package s3gof3r
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+var objectLockTests = []struct {
+	header http.Header
+	valid  bool
+}{
+	{http.Header{}, true},
+	{http.Header{"X-Amz-Object-Lock-Mode": {"GOVERNANCE"}, "X-Amz-Object-Lock-Retain-Until-Date": {"2030-01-02T15:04:05Z"}}, true},
+	{http.Header{"X-Amz-Object-Lock-Legal-Hold": {"ON"}}, true},
+	{http.Header{"X-Amz-Object-Lock-Mode": {"COMPLIANCE"}}, false},
+	{http.Header{"X-Amz-Object-Lock-Retain-Until-Date": {"2030-01-02T15:04:05Z"}}, false},
+	{http.Header{"X-Amz-Object-Lock-Mode": {"LOCKED"}, "X-Amz-Object-Lock-Retain-Until-Date": {"2030-01-02T15:04:05Z"}}, false},
+	{http.Header{"X-Amz-Object-Lock-Mode": {"GOVERNANCE"}, "X-Amz-Object-Lock-Retain-Until-Date": {"2030-01-02"}}, false},
+	{http.Header{"X-Amz-Object-Lock-Legal-Hold": {"yes"}}, false},
+}
+
+func TestPutWriterObjectLock(t *testing.T) {
+	s := newFakeS3()
+	b := newTestBucket(t, s)
+	for _, tt := range objectLockTests {
+		w, err := b.PutWriter("key", tt.header)
+		if !tt.valid {
+			if err == nil {
+				t.Errorf("expected error for object lock headers %v", tt.header)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	until := time.Date(2030, 1, 2, 15, 4, 5, 0, time.UTC)
+	w, err := b.PutWriterWithObjectLock("locked", nil, ObjectRetention{Mode: ObjectLockCompliance, RetainUntilDate: until}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	h := s.headers["/bucket/locked"]
+	s.mu.Unlock()
+	if h.Get(objectLockModeHeader) != "COMPLIANCE" || h.Get(objectLockRetainUntilHeader) != "2030-01-02T15:04:05Z" || h.Get(objectLockLegalHoldHeader) != "ON" {
+		t.Errorf("unexpected object lock headers: %v", h)
+	}
+	if _, err := b.PutWriterWithObjectLock("locked", nil, ObjectRetention{Mode: ObjectLockGovernance}, false); err == nil {
+		t.Error("expected error for retention without a date")
+	}
+}
+
+func TestObjectRetention(t *testing.T) {
+	var puts []string
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/bucket/key" || !(q.Has("retention") || q.Has("legal-hold")) {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			return
+		}
+		switch r.Method {
+		case "PUT":
+			if r.Header.Get(md5Header) == "" {
+				t.Error("expected Content-MD5")
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			puts = append(puts, string(body))
+		case "GET":
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<Retention xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Mode>GOVERNANCE</Mode><RetainUntilDate>2030-01-02T15:04:05.000Z</RetainUntilDate></Retention>`)
+		}
+	}))
+
+	until := time.Date(2030, 1, 2, 15, 4, 5, 6, time.UTC)
+	if err := b.PutObjectRetention("key", ObjectRetention{Mode: ObjectLockGovernance, RetainUntilDate: until}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.PutObjectLegalHold("key", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.PutObjectLegalHold("key", false); err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		`<Retention><Mode>GOVERNANCE</Mode><RetainUntilDate>2030-01-02T15:04:05Z</RetainUntilDate></Retention>`,
+		`<LegalHold><Status>ON</Status></LegalHold>`,
+		`<LegalHold><Status>OFF</Status></LegalHold>`,
+	}
+	if fmt.Sprint(puts) != fmt.Sprint(expect) {
+		t.Errorf("Expected puts: %v. Actual: %v", expect, puts)
+	}
+	if err := b.PutObjectRetention("key", ObjectRetention{Mode: "LOCKED", RetainUntilDate: until}); err == nil {
+		t.Error("expected error for invalid mode")
+	}
+
+	r, err := b.GetObjectRetention("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Mode != ObjectLockGovernance || !r.RetainUntilDate.Equal(until.Truncate(time.Second)) {
+		t.Errorf("unexpected retention: %+v", r)
+	}
+}
@@ -0,0 +1,91 @@
+package s3gof3r
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// A RequestObserver is notified of each HTTP request of a bucket, including each part
+// request of gets and puts and each retry, e.g. to create tracing spans or measure
+// the latency of parts. s3gof3r does not depend on any tracing library.
+//
+// Methods are called concurrently from the part workers, so they must be safe for
+// concurrent use and return quickly.
+type RequestObserver interface {
+	// OnStart is called before req is sent.
+	OnStart(req *http.Request, info RequestInfo)
+
+	// OnComplete is called when the response headers of req are received, or the
+	// request failed with err. d is the duration since OnStart.
+	OnComplete(req *http.Request, info RequestInfo, resp *http.Response, err error, d time.Duration)
+}
+
+// RequestInfo describes the part of an object requested by an observed request.
+type RequestInfo struct {
+	PartNumber int    // part number of multipart upload parts, or 0
+	Range      string // Range header of ranged gets, such as "bytes=0-1023", or ""
+}
+
+func newRequestInfo(req *http.Request) RequestInfo {
+	n, _ := strconv.Atoi(req.URL.Query().Get("partNumber"))
+	return RequestInfo{PartNumber: n, Range: req.Header.Get("Range")}
+}
+
+// observe sends req with the client of the bucket, notifying o of the request
+func (b *Bucket) observe(o RequestObserver, req *http.Request) (*http.Response, error) {
+	info := newRequestInfo(req)
+	o.OnStart(req, info)
+	start := time.Now()
+	resp, err := b.Config.Client.Do(req)
+	o.OnComplete(req, info, resp, err, time.Since(start))
+	return resp, err
+}
+
+// A PartObserver is notified of the lifecycle of each part of gets and puts, e.g. to
+// find the parts that are slow or failing. Parts of puts are numbered by their part
+// number, and parts of gets from 1 in the order of their offsets in the object.
+//
+// Methods are called concurrently from the part workers, which wait for them, so they
+// must be safe for concurrent use and must not block. The attempts of parts are
+// notified to a RequestObserver instead.
+type PartObserver interface {
+	// PartStarted is called before the first attempt of part n.
+	PartStarted(n int)
+
+	// PartCompleted is called when part n of size bytes is got or put, after attempts
+	// attempts, d after it started.
+	PartCompleted(n int, size int64, attempts int, d time.Duration)
+
+	// PartFailed is called when part n has failed with err, on its last attempt or
+	// when the get or put was cancelled.
+	PartFailed(n int, err error)
+}
+
+type attemptKey struct{}
+
+// withAttempt returns req with the attempt of its retries, from 0, logged with it
+func withAttempt(req *http.Request, attempt int) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), attemptKey{}, attempt))
+}
+
+// logRequest logs req to l, with its response or err, as key=value fields: the method, path,
+// byte range and attempt of the request, the status and S3 request IDs of its response
+// and the duration d until its response headers were received.
+func logRequest(l *internalLogger, req *http.Request, resp *http.Response, err error, d time.Duration) {
+	attempt, _ := req.Context().Value(attemptKey{}).(int)
+	var status int
+	var requestID, id2 string
+	if resp != nil {
+		status = resp.StatusCode
+		requestID, id2 = resp.Header.Get("X-Amz-Request-Id"), resp.Header.Get("X-Amz-Id-2")
+	}
+	msg := fmt.Sprintf("request method=%s path=%s range=%q attempt=%d status=%d request_id=%s id_2=%s duration=%s",
+		req.Method, req.URL.EscapedPath(), req.Header.Get("Range"), attempt, status, requestID, id2, d)
+	if err != nil {
+		msg += fmt.Sprintf(" error=%q", err)
+	}
+	l.debugPrintln(msg)
+}
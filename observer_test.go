@@ -0,0 +1,257 @@
+package s3gof3r
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testObserver struct {
+	mu       sync.Mutex
+	started  int
+	parts    []int
+	ranges   []string
+	statuses []int
+}
+
+func (o *testObserver) OnStart(req *http.Request, info RequestInfo) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.started++
+}
+
+func (o *testObserver) OnComplete(req *http.Request, info RequestInfo, resp *http.Response, err error, d time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if info.PartNumber > 0 {
+		o.parts = append(o.parts, info.PartNumber)
+	}
+	if info.Range != "" {
+		o.ranges = append(o.ranges, info.Range)
+	}
+	if err == nil {
+		o.statuses = append(o.statuses, resp.StatusCode)
+	}
+}
+
+func TestRequestObserver(t *testing.T) {
+	s := newFakeS3()
+	b := newTestBucket(t, s)
+	b.Config.Md5Check = false
+	o := new(testObserver)
+	b.Config.RequestObserver = o
+
+	data := make([]byte, 6*mb)
+	w, err := b.PutWriter("key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sort.Ints(o.parts)
+	if len(o.parts) != 2 || o.parts[0] != 1 || o.parts[1] != 2 {
+		t.Errorf("Expected parts: [1 2]. Actual: %v", o.parts)
+	}
+
+	r, _, err := b.GetReader("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("Expected data to match")
+	}
+	sort.Strings(o.ranges)
+	ranges := []string{"bytes=0-5242879", "bytes=5242880-6291455"}
+	if len(o.ranges) != 2 || o.ranges[0] != ranges[0] || o.ranges[1] != ranges[1] {
+		t.Errorf("Expected ranges: %v. Actual: %v", ranges, o.ranges)
+	}
+	// initiate, 2 parts, complete, get and 2 ranged gets
+	if o.started != 7 || len(o.statuses) != 7 {
+		t.Errorf("Expected 7 observed requests. Actual: %d started, %d completed", o.started, len(o.statuses))
+	}
+}
+
+func TestLogRequest(t *testing.T) {
+	s := newFakeS3()
+	s.objects["/bucket/key"] = []byte("data")
+	var failed bool
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Amz-Request-Id", "req-id")
+		w.Header().Set("X-Amz-Id-2", "id-2")
+		if r.Header.Get("Range") != "" && !failed {
+			failed = true
+			w.WriteHeader(500)
+			return
+		}
+		s.ServeHTTP(w, r)
+	}))
+	l := new(testLogger)
+	b.Config.Logger = l
+	b.Config.Md5Check = false
+	if _, _, err := b.GetBytes("key"); err != nil {
+		t.Fatal(err)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	logged := strings.Join(l.debugs, "\n")
+	for _, expect := range []string{
+		`request method=GET path=/bucket/key range="" attempt=0 status=200 request_id=req-id id_2=id-2 duration=`,
+		`request method=GET path=/bucket/key range="bytes=0-3" attempt=0 status=500 request_id=req-id id_2=id-2 duration=`,
+		`request method=GET path=/bucket/key range="bytes=0-3" attempt=1 status=206 request_id=req-id id_2=id-2 duration=`,
+	} {
+		if !strings.Contains(logged, expect) {
+			t.Errorf("Expected log %q. Actual: %s", expect, logged)
+		}
+	}
+}
+
+// testLogger records the lines logged by a bucket
+type testLogger struct {
+	mu     sync.Mutex
+	lines  []string
+	debugs []string
+}
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func (l *testLogger) Debugf(format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.debugs = append(l.debugs, fmt.Sprintf(format, v...))
+}
+
+func TestConfigLogger(t *testing.T) {
+	s := newFakeS3()
+	b := newTestBucket(t, s)
+	l := new(testLogger)
+	b.Config.Logger = l
+	b.Config.Md5Check = false
+	if err := b.PutBytes("key", []byte("data"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Delete("key"); err != nil {
+		t.Fatal(err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.lines) != 1 || l.lines[0] != "key deleted from bucket" {
+		t.Errorf("Expected deleted line. Actual: %q", l.lines)
+	}
+	var requests int
+	for _, d := range l.debugs {
+		if strings.HasPrefix(d, "request method=") {
+			requests++
+		}
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests logged. Actual: %q", l.debugs)
+	}
+}
+
+// testPartObserver records the events of parts as "started n", "completed n size
+// attempts" and "failed n"
+type testPartObserver struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (o *testPartObserver) add(format string, v ...interface{}) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, fmt.Sprintf(format, v...))
+}
+
+func (o *testPartObserver) PartStarted(n int) { o.add("started %d", n) }
+
+func (o *testPartObserver) PartCompleted(n int, size int64, attempts int, d time.Duration) {
+	if d <= 0 {
+		o.add("invalid duration %d", n)
+	}
+	o.add("completed %d %d %d", n, size, attempts)
+}
+
+func (o *testPartObserver) PartFailed(n int, err error) { o.add("failed %d", n) }
+
+// sorted returns the events recorded, sorted, and resets them
+func (o *testPartObserver) sorted() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	e := o.events
+	o.events = nil
+	sort.Strings(e)
+	return e
+}
+
+func TestPartObserver(t *testing.T) {
+	s := newFakeS3()
+	var mu sync.Mutex
+	failures := map[string]int{"2": 1} // failed attempts by part number
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		n := r.URL.Query().Get("partNumber")
+		fail := r.Method == "PUT" && failures[n] > 0
+		if fail {
+			failures[n]--
+		}
+		mu.Unlock()
+		if fail {
+			w.WriteHeader(500)
+			return
+		}
+		s.ServeHTTP(w, r)
+	}))
+	b.Config.Backoff = &Backoff{Initial: time.Millisecond}
+	o := &testPartObserver{}
+	b.Config.PartObserver = o
+
+	data := bytes.Repeat([]byte("a"), 12<<20)
+	if err := b.PutBytes("key", data, nil); err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		"completed 1 5242880 1", "completed 2 5242880 2", "completed 3 2097152 1",
+		"started 1", "started 2", "started 3",
+	}
+	if e := o.sorted(); strings.Join(e, ",") != strings.Join(expect, ",") {
+		t.Errorf("Expected put events: %v. Actual: %v", expect, e)
+	}
+	if _, _, err := b.GetBytes("key"); err != nil {
+		t.Fatal(err)
+	}
+	expect[1] = "completed 2 5242880 1"
+	if e := o.sorted(); strings.Join(e, ",") != strings.Join(expect, ",") {
+		t.Errorf("Expected get events: %v. Actual: %v", expect, e)
+	}
+
+	mu.Lock()
+	failures = map[string]int{"1": 2}
+	mu.Unlock()
+	if err := b.PutBytes("key", data, nil); err == nil {
+		t.Error("Expected put error")
+	}
+	if e := o.sorted(); !strings.Contains(strings.Join(e, ","), "failed 1") {
+		t.Errorf("Expected part 1 failed. Actual: %v", e)
+	}
+}
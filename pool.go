@@ -2,9 +2,32 @@ package s3gof3r
 
 import (
 	"container/list"
+	"sync"
 	"time"
 )
 
+// partBuffers holds the buffers freed by the pools of getters and putters, in a
+// sync.Pool for each buffer size, so that later transfers reuse them instead of
+// allocating buffers of PartSize for every transfer.
+var partBuffers sync.Map // int64 size -> *sync.Pool of *[]byte
+
+// getPartBuffer returns a buffer of size from partBuffers, or a new buffer.
+func getPartBuffer(size int64) []byte {
+	if p, ok := partBuffers.Load(size); ok {
+		if b, ok := p.(*sync.Pool).Get().(*[]byte); ok {
+			return *b
+		}
+	}
+	return make([]byte, size)
+}
+
+// putPartBuffer frees b to partBuffers.
+func putPartBuffer(b []byte) {
+	b = b[:cap(b)]
+	p, _ := partBuffers.LoadOrStore(int64(len(b)), new(sync.Pool))
+	p.(*sync.Pool).Put(&b)
+}
+
 type qb struct {
 	when time.Time
 	s    []byte
@@ -18,10 +41,12 @@ type bp struct {
 	timeout time.Duration
 	bufsz   int64
 	sizech  chan int64
+	logger  *internalLogger
 }
 
-func bufferPool(bufsz int64) (sp *bp) {
+func bufferPool(bufsz int64, l *internalLogger) (sp *bp) {
 	sp = &bp{
+		logger:  l,
 		get:     make(chan []byte),
 		give:    make(chan []byte),
 		quit:    make(chan struct{}),
@@ -32,7 +57,7 @@ func bufferPool(bufsz int64) (sp *bp) {
 		q := new(list.List)
 		for {
 			if q.Len() == 0 {
-				q.PushFront(qb{when: time.Now(), s: make([]byte, bufsz)})
+				q.PushFront(qb{when: time.Now(), s: getPartBuffer(bufsz)})
 				sp.makes++
 			}
 
@@ -42,6 +67,10 @@ func bufferPool(bufsz int64) (sp *bp) {
 			select {
 			case b := <-sp.give:
 				timeout.Stop()
+				if int64(cap(b)) < bufsz {
+					putPartBuffer(b) // of the size before an update
+					continue
+				}
 				q.PushFront(qb{when: time.Now(), s: b})
 			case sp.get <- e.Value.(qb).s:
 				timeout.Stop()
@@ -53,14 +82,19 @@ func bufferPool(bufsz int64) (sp *bp) {
 					n := e.Next()
 					if time.Since(e.Value.(qb).when) > sp.timeout {
 						q.Remove(e)
+						putPartBuffer(e.Value.(qb).s)
 						e.Value = nil
 					}
 					e = n
 				}
 			case sz := <-sp.sizech: // update buffer size, free buffers
+				timeout.Stop()
 				bufsz = sz
+				sp.free(q)
 			case <-sp.quit:
-				logger.debugPrintf("%d buffers of %d MB allocated", sp.makes, bufsz/(1*mb))
+				timeout.Stop()
+				sp.free(q)
+				sp.logger.debugPrintf("%d buffers of %d MB allocated", sp.makes, bufsz/(1*mb))
 				return
 			}
 		}
@@ -68,3 +102,10 @@ func bufferPool(bufsz int64) (sp *bp) {
 	}()
 	return sp
 }
+
+// free frees the buffers of q to partBuffers
+func (sp *bp) free(q *list.List) {
+	for e := q.Front(); e != nil; e = q.Front() {
+		putPartBuffer(q.Remove(e).(qb).s)
+	}
+}
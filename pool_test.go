@@ -19,7 +19,7 @@ func TestBP(t *testing.T) {
 	SetLogger(&lf, "", log.LstdFlags, true)
 	defer SetLogger(ioutil.Discard, "", log.LstdFlags, true)
 
-	bp := bufferPool(mb)
+	bp := bufferPool(mb, &logger)
 	bp.timeout = 1 * time.Millisecond
 	b := <-bp.get
 	if cap(b) != int(mb) {
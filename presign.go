@@ -0,0 +1,130 @@
+package s3gof3r
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPresignExpires is the longest validity of a SigV4 presigned url
+const maxPresignExpires = 7 * 24 * time.Hour
+
+// PresignGet returns a url that may be used to get the object at path without
+// other authentication until it expires. expires must be between one second and
+// seven days.
+//
+// Temporary credentials are included in the url, which is then valid for at most
+// as long as they are.
+func (b *Bucket) PresignGet(path string, expires time.Duration) (string, error) {
+	return b.presign("GET", path, expires, nil, nil)
+}
+
+// ResponseHeaders override the headers of the response to a get of an object, such
+// as a Content-Disposition of `attachment; filename="report.csv"` to make browsers
+// download it as report.csv, whatever its key and the headers it was put with. They
+// are sent as the response-* parameters of the url. Headers that are empty are not
+// overridden.
+type ResponseHeaders struct {
+	ContentType        string
+	ContentDisposition string
+	ContentLanguage    string
+	CacheControl       string
+	ContentEncoding    string
+	Expires            time.Time
+}
+
+// setQuery sets the response-* parameters of the headers set in q
+func (r ResponseHeaders) setQuery(q url.Values) {
+	for k, v := range map[string]string{
+		"response-content-type":        r.ContentType,
+		"response-content-disposition": r.ContentDisposition,
+		"response-content-language":    r.ContentLanguage,
+		"response-cache-control":       r.CacheControl,
+		"response-content-encoding":    r.ContentEncoding,
+	} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	if !r.Expires.IsZero() {
+		q.Set("response-expires", r.Expires.UTC().Format(http.TimeFormat))
+	}
+}
+
+// PresignGetResponse is like PresignGet but the response to the url has the headers
+// of rh instead of those of the object. The overrides are signed, so they can't be
+// changed by the client.
+func (b *Bucket) PresignGetResponse(path string, expires time.Duration, rh ResponseHeaders) (string, error) {
+	q := url.Values{}
+	rh.setQuery(q)
+	return b.presign("GET", path, expires, nil, q)
+}
+
+// PresignPut returns a url that may be used to put an object at path without
+// other authentication until it expires, as for PresignGet.
+//
+// Each header in h, such as Content-Type or x-amz-server-side-encryption, is signed
+// and must be sent with the same value in the put request.
+func (b *Bucket) PresignPut(path string, expires time.Duration, h http.Header) (string, error) {
+	return b.presign("PUT", path, expires, h, nil)
+}
+
+// presign returns the url of method at path, with the headers h and the parameters q
+// of the request signed, valid until expires
+func (b *Bucket) presign(method, path string, expires time.Duration, h http.Header, q url.Values) (string, error) {
+	if expires < time.Second || expires > maxPresignExpires {
+		return "", fmt.Errorf("invalid expiration %v: must be between 1s and %v", expires, maxPresignExpires)
+	}
+	if err := b.checkRequest(); err != nil {
+		return "", err
+	}
+	u, err := b.url(path)
+	if err != nil {
+		return "", err
+	}
+	if len(q) > 0 {
+		uq := u.Query()
+		for k, v := range q {
+			uq[k] = v
+		}
+		u.RawQuery = uq.Encode()
+	}
+	s := &signer{
+		Time:     time.Now(),
+		Request:  &http.Request{Method: method, URL: u, Header: cloneHeader(h)},
+		S3Config: b.S3,
+		Region:   b.signingRegion(),
+		Service:  b.Config.SigningService,
+	}
+	s.presign(expires)
+	return u.String(), nil
+}
+
+// presign adds SigV4 query string authentication, valid for expires, to the request url.
+// The headers of the request are signed and must be sent with the presigned request.
+func (s *signer) presign(expires time.Duration) {
+	s.loadCredentials()
+	s.presigned = true
+	s.unsignedPayload = true
+	s.buildCredentialString()
+	s.buildCanonicalHeaders()
+
+	q := s.Request.URL.Query()
+	q.Set("X-Amz-Algorithm", prefix)
+	q.Set("X-Amz-Credential", s.accessKeyID+"/"+s.credentialString)
+	q.Set("X-Amz-Date", s.Time.UTC().Format(isoFormat))
+	q.Set("X-Amz-Expires", strconv.Itoa(int(expires/time.Second)))
+	q.Set("X-Amz-SignedHeaders", s.signedHeaders)
+	if s.sessionToken != "" {
+		q.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+	s.Request.URL.RawQuery = q.Encode()
+
+	s.buildCanonicalString()
+	s.buildStringToSign()
+	s.buildSignature()
+	s.Request.URL.RawQuery = strings.Join([]string{s.Request.URL.RawQuery, "X-Amz-Signature=" + s.signature}, "&")
+}
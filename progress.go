@@ -0,0 +1,37 @@
+package s3gof3r
+
+import "sync"
+
+// ProgressFunc is called as each part of a get or put completes, with the bytes
+// transferred so far and the total bytes of the transfer, or -1 if it is unknown,
+// as for puts of streamed data.
+//
+// Calls are serialized but made from the part workers, so a ProgressFunc must
+// return quickly to avoid stalling the transfer.
+type ProgressFunc func(bytesTransferred, totalBytes int64)
+
+// progress tracks the bytes transferred for a ProgressFunc. A nil *progress is a no-op.
+type progress struct {
+	mu    sync.Mutex
+	f     ProgressFunc
+	n     int64
+	total int64
+}
+
+func newProgress(f ProgressFunc, total int64) *progress {
+	if f == nil {
+		return nil
+	}
+	return &progress{f: f, total: total}
+}
+
+// add records n more bytes transferred and reports the progress
+func (p *progress) add(n int64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.n += n
+	p.f(p.n, p.total)
+}
@@ -0,0 +1,53 @@
+package s3gof3r
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+)
+
+func TestProgress(t *testing.T) {
+	b := newTestBucket(t, newFakeS3())
+	var mu sync.Mutex
+	var transferred, total int64
+	b.Config.Progress = func(n, t int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		transferred, total = n, t
+	}
+	data := bytes.Repeat([]byte("a"), int(12*mb))
+
+	w, err := b.PutWriter("key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	if transferred != 12*mb || total != -1 {
+		t.Errorf("put progress: expected %d of -1, got %d of %d", 12*mb, transferred, total)
+	}
+	mu.Unlock()
+
+	r, _, err := b.GetReader("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	if transferred != 12*mb || total != 12*mb {
+		t.Errorf("get progress: expected %d of %d, got %d of %d", 12*mb, 12*mb, transferred, total)
+	}
+	mu.Unlock()
+}
@@ -0,0 +1,62 @@
+package s3gof3r
+
+import (
+	"errors"
+	"time"
+)
+
+// A Provider provides AWS keys that are rotated, such as the keys of an STS session or of
+// a secret store. The keys returned by ProviderKeys are retrieved from the provider whenever
+// it reports them expired, so each request is signed with current keys.
+//
+// *Keys is a Provider, so the keys of EnvKeys, SharedKeys, InstanceKeys and ContainerKeys
+// may be used as a Provider.
+type Provider interface {
+	// Retrieve returns new keys
+	Retrieve() (accessKeyID, secretAccessKey, sessionToken string, err error)
+	// IsExpired reports whether the keys last retrieved should be renewed
+	IsExpired() bool
+}
+
+// ProviderKeys returns keys retrieved from p, and retrieved again from p when it reports them
+// expired. If renewing the keys fails, the error is logged and the current keys are used.
+func ProviderKeys(p Provider) (*Keys, error) {
+	retrieve := func() (*Keys, error) {
+		accessKeyID, secretAccessKey, sessionToken, err := p.Retrieve()
+		if err != nil {
+			return nil, err
+		}
+		if accessKeyID == "" || secretAccessKey == "" {
+			return nil, errors.New("provider returned empty keys")
+		}
+		return &Keys{accessKeyID: accessKeyID, secretAccessKey: secretAccessKey, sessionToken: sessionToken}, nil
+	}
+	k, err := retrieve()
+	if err != nil {
+		return nil, err
+	}
+	k.provider = p
+	k.refresh = retrieve
+	return k, nil
+}
+
+// Retrieve returns the keys as for Credentials, so refreshable keys are renewed first
+// if they expire within a few minutes. It fails if the keys have expired.
+func (k *Keys) Retrieve() (accessKeyID, secretAccessKey, sessionToken string, err error) {
+	accessKeyID, secretAccessKey, sessionToken = k.Credentials()
+	if exp := k.Expiration(); !exp.IsZero() && !time.Now().Before(exp) {
+		return "", "", "", errors.New("keys expired at " + exp.Format(time.RFC3339))
+	}
+	return
+}
+
+// IsExpired reports whether temporary keys expire within a few minutes, when refreshable
+// keys are renewed. Keys of a Provider are expired when the provider reports them expired.
+func (k *Keys) IsExpired() bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.provider != nil {
+		return k.provider.IsExpired()
+	}
+	return !k.expiration.IsZero() && time.Until(k.expiration) < refreshWindow
+}
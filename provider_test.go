@@ -0,0 +1,95 @@
+package s3gof3r
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rotatingProvider provides keys numbered by the times they were retrieved
+type rotatingProvider struct {
+	mu        sync.Mutex
+	retrieved int
+	expired   bool
+}
+
+func (p *rotatingProvider) Retrieve() (string, string, string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.retrieved++
+	p.expired = false
+	return fmt.Sprintf("AKID%d", p.retrieved), "secret", "token", nil
+}
+
+func (p *rotatingProvider) IsExpired() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.expired
+}
+
+func TestProviderKeys(t *testing.T) {
+	s := newFakeS3()
+	s.objects["/bucket/key"] = []byte("data")
+	var mu sync.Mutex
+	var credentials []string
+	s.check = func(r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		auth := r.Header.Get("Authorization")
+		credentials = append(credentials, strings.SplitN(strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 Credential="), "/", 2)[0])
+	}
+	b := newTestBucket(t, s)
+	p := new(rotatingProvider)
+	keys, err := ProviderKeys(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.S3.(*S3).Keys = keys
+
+	for i := 0; i < 3; i++ {
+		if _, err := b.Head("key"); err != nil {
+			t.Fatal(err)
+		}
+		if i == 1 {
+			p.mu.Lock()
+			p.expired = true
+			p.mu.Unlock()
+		}
+	}
+	expect := []string{"AKID1", "AKID1", "AKID2"}
+	if fmt.Sprint(credentials) != fmt.Sprint(expect) {
+		t.Errorf("Expected keys: %v. Actual: %v", expect, credentials)
+	}
+	if keys.SessionToken() != "token" {
+		t.Errorf("Expected session token: token. Actual: %s", keys.SessionToken())
+	}
+}
+
+func TestKeysProvider(t *testing.T) {
+	var keysProviderTests = []struct {
+		expiration time.Time
+		expired    bool
+		err        bool
+	}{
+		{time.Time{}, false, false},
+		{time.Now().Add(time.Hour), false, false},
+		{time.Now().Add(time.Minute), true, false},
+		{time.Now().Add(-time.Minute), true, true},
+	}
+	for _, tt := range keysProviderTests {
+		var p Provider = &Keys{accessKeyID: "AKID", secretAccessKey: "secret", expiration: tt.expiration}
+		if p.IsExpired() != tt.expired {
+			t.Errorf("%v: Expected expired: %v. Actual: %v", tt.expiration, tt.expired, p.IsExpired())
+		}
+		id, _, _, err := p.Retrieve()
+		if (err != nil) != tt.err {
+			t.Errorf("%v: Expected error: %v. Actual: %v", tt.expiration, tt.err, err)
+		}
+		if err == nil && id != "AKID" {
+			t.Errorf("Expected access key ID: AKID. Actual: %s", id)
+		}
+	}
+}
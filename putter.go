@@ -2,6 +2,8 @@ package s3gof3r
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"crypto/md5"
 	"crypto/sha256"
 	"encoding/base64"
@@ -11,9 +13,11 @@ import (
 	"fmt"
 	"hash"
 	"io"
-	"math"
+	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/url"
+	"path"
 	"runtime"
 	"strconv"
 	"strings"
@@ -37,37 +41,82 @@ type part struct {
 	len int64
 	b   []byte
 
+	src io.ReaderAt // read into b at off by the worker, for parts of UploadReaderAt
+	off int64
+
 	// Read by xml encoder
-	PartNumber int
-	ETag       string
+	PartNumber     int
+	ETag           string
+	ChecksumCRC32  string `xml:",omitempty"`
+	ChecksumCRC32C string `xml:",omitempty"`
+	ChecksumSHA1   string `xml:",omitempty"`
+	ChecksumSHA256 string `xml:",omitempty"`
 
 	// Checksums
 	md5    string
 	sha256 string
 }
 
+// checksum returns the field of the checksum of algorithm alg
+func (p *part) checksum(alg string) *string {
+	switch alg {
+	case ChecksumCRC32:
+		return &p.ChecksumCRC32
+	case ChecksumCRC32C:
+		return &p.ChecksumCRC32C
+	case ChecksumSHA1:
+		return &p.ChecksumSHA1
+	}
+	return &p.ChecksumSHA256
+}
+
 type putter struct {
+	ctx    context.Context
+	cancel context.CancelFunc // cancels the part uploads in flight on Abort
 	url    url.URL
 	bucket *Bucket
 
 	ntry       int
 	bufsz      int64
+	growth     partGrowth
 	buf        []byte
 	bufbytes   int // bytes written to current buffer
 	ch         chan *part
 	part       int
 	closed     bool
-	err        error
+	err        error // set by the workers, guarded by errMu
+	errMu      sync.Mutex
 	wg         sync.WaitGroup
 	md5OfParts hash.Hash
 	md5        hash.Hash
 	ETag       string
 	Code       string
+	versionID  string // of the object put, in versioned buckets
+	abortOnce  sync.Once
+	abortErr   error       // of aborting the multipart upload, set by abortOnce
+	sse        http.Header // SSE-C headers sent with each part
+	kms        bool        // encrypted with SSE-KMS, whose etags are not md5s either
+	initHeader http.Header // headers of the upload, sent on initiation or with a single put
+	cond       http.Header // If-Match and If-None-Match, sent with a single put or on completion
+	sniff      bool        // sniff the Content-Type from the first part
+	progress   *progress
+	stats      *stats
+	limiter    *limiter  // shared by the workers
+	adaptive   *adaptive // shared by the workers
+
+	checksumAlg string // algorithm of the checksums sent with each part, if any
+
+	// computedETag is the ETag of the data put: the md5 of a single put, or the md5 of
+	// the md5s of the parts followed by their number
+	computedETag string
+
+	zw *gzip.Writer // compresses the data written, with CompressGzip
 
 	sp *bp
 
 	makes    int
-	UploadID string `xml:"UploadId"`
+	uploadID string
+	uploaded map[int]uploadedPart // parts of a resumed upload, by part number
 	xml      struct {
 		XMLName string `xml:"CompleteMultipartUpload"`
 		Part    []*part
@@ -75,33 +124,71 @@ type putter struct {
 	putsz int64
 }
 
-// Sends an S3 multipart upload initiation request.
+// Returns a putter that buffers the first part of the data before uploading it.
+// Data smaller than the part size is uploaded with a single PUT request when the
+// putter is closed. Otherwise an S3 multipart upload initiation request is sent
+// when the first part is full.
 // See http://docs.amazonwebservices.com/AmazonS3/latest/dev/mpuoverview.html.
 // The initial request returns an UploadId that we use to identify
 // subsequent PUT requests.
-func newPutter(url url.URL, h http.Header, bucket *Bucket) (p *putter, err error) {
+func newPutter(ctx context.Context, url url.URL, h http.Header, bucket *Bucket) (p *putter, err error) {
+	if err := bucket.checkRequest(); err != nil {
+		return nil, err
+	}
 	p = new(putter)
+	p.stats = newStats()
+	p.ctx, p.cancel = context.WithCancel(ctx)
 	p.url = url
 
 	p.bucket = bucket
 
-	concurrency := max(bucket.Config.Concurrency, 1)
+	p.adaptive = newAdaptive(bucket.Config)
+	concurrency := p.adaptive.workers(bucket.Config.Concurrency)
 	p.ntry = max(bucket.Config.NTry, 1)
-	p.bufsz = max64(minPartSize, bucket.Config.PartSize)
+	if p.bufsz, err = putPartSize(bucket.Config); err != nil {
+		return nil, err
+	}
+	p.growth = newPartGrowth(bucket.Config)
 
-	resp, err := p.retryRequest("POST", url.String()+"?uploads", nil, h)
+	p.sse, err = sseCustomerHeader(bucket.Config.SSECustomerKey, h)
 	if err != nil {
 		return nil, err
 	}
-	defer checkClose(resp.Body, err)
-
-	if resp.StatusCode != 200 {
-		return nil, newRespError(resp)
+	h = cloneHeader(h)
+	setHeaders(h, p.sse)
+	p.cond = takePreconditions(h)
+	if err = setStorageClass(h, bucket.Config.StorageClass); err != nil {
+		return nil, err
 	}
-	err = xml.NewDecoder(resp.Body).Decode(p)
-	if err != nil {
+	if err = setACL(h, bucket.Config.ACL); err != nil {
+		return nil, err
+	}
+	if err = setServerSideEncryption(h, bucket.Config); err != nil {
 		return nil, err
 	}
+	p.kms = isKMS(h.Get(sseHeader))
+	if err = validateObjectLock(h); err != nil {
+		return nil, err
+	}
+	if p.checksumAlg = bucket.Config.ChecksumAlgorithm; p.checksumAlg != "" {
+		if _, err = newChecksumHash(p.checksumAlg); err != nil {
+			return nil, err
+		}
+	}
+
+	compress := bucket.Config.CompressGzip && h.Get("Content-Encoding") == ""
+	if compress {
+		h.Set("Content-Encoding", "gzip")
+		p.zw = gzip.NewWriter(writerFunc(p.write))
+	}
+	if h.Get("Content-Type") == "" && bucket.Config.DetectContentType {
+		if ct := mime.TypeByExtension(path.Ext(url.Path)); ct != "" {
+			h.Set("Content-Type", ct)
+		} else {
+			p.sniff = !compress // the parts are compressed
+		}
+	}
+	p.initHeader = h
 	p.ch = make(chan *part)
 	for i := 0; i < concurrency; i++ {
 		go p.worker()
@@ -109,19 +196,98 @@ func newPutter(url url.URL, h http.Header, bucket *Bucket) (p *putter, err error
 	p.md5OfParts = md5.New()
 	p.md5 = md5.New()
 
-	p.sp = bufferPool(p.bufsz)
+	p.sp = bufferPool(p.bufsz, p.bucket.Config.logger())
+	p.progress = newProgress(bucket.Config.Progress, -1)
+	p.limiter = newLimiter(bucket.Config.MaxBytesPerSec)
 
 	return p, nil
 }
 
+// initiate starts the multipart upload with the headers in h
+func (p *putter) initiate(h http.Header) (err error) {
+	if p.checksumAlg != "" {
+		h = cloneHeader(h)
+		h.Set(checksumAlgorithmHeader, p.checksumAlg)
+	}
+	resp, err := p.retryRequest(p.ctx, "POST", p.url.String()+"?uploads", nil, h)
+	if err != nil {
+		return err
+	}
+	defer checkClose(resp.Body, err)
+
+	if resp.StatusCode != 200 {
+		return newRespError(resp)
+	}
+	var res struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err = xml.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return err
+	}
+	p.uploadID = res.UploadID
+	return nil
+}
+
+// PutResult identifies the object put by a MultipartWriter
+type PutResult struct {
+	ETag      string // without surrounding quotes
+	VersionID string // of the object in versioned buckets, otherwise empty
+
+	// ComputedETag is the ETag computed from the data written, which Close verifies is
+	// the ETag returned by S3, except for objects encrypted with SSE-C or SSE-KMS whose
+	// ETag isn't the md5 of their data.
+	ComputedETag string
+}
+
+// Result returns the ETag and version ID of the object put, once Close has succeeded.
+// The ETag of objects put with a multipart upload is the md5 of the md5s of their parts,
+// followed by "-" and the number of parts.
+func (p *putter) Result() PutResult {
+	return PutResult{ETag: strings.Trim(p.ETag, `"`), VersionID: p.versionID, ComputedETag: p.computedETag}
+}
+
+// UploadID returns the ID of the multipart upload, for resuming it with ResumePutWriter
+// if the upload is interrupted. It is empty until the first part is written, and for
+// objects smaller than one part which are put with a single request.
+func (p *putter) UploadID() string {
+	return p.uploadID
+}
+
+// Stats returns the Stats of the put
+func (p *putter) Stats() Stats {
+	return p.stats.stats()
+}
+
+// resume continues the multipart upload with uploadID instead of initiating one.
+// Parts already uploaded with the same size and md5 are not uploaded again.
+func (p *putter) resume(uploadID string, parts []uploadedPart) {
+	p.uploadID = uploadID
+	p.uploaded = make(map[int]uploadedPart, len(parts))
+	for _, up := range parts {
+		p.uploaded[up.PartNumber] = up
+	}
+}
+
 func (p *putter) Write(b []byte) (int, error) {
+	if p.zw != nil {
+		return p.zw.Write(b) // to p.write
+	}
+	return p.write(b)
+}
+
+// write writes b, the data of the object stored, to the parts
+func (p *putter) write(b []byte) (int, error) {
 	if p.closed {
 		p.abort()
 		return 0, syscall.EINVAL
 	}
-	if p.err != nil {
+	if err := p.ctx.Err(); err != nil {
+		p.abort()
+		return 0, err
+	}
+	if err := p.error(); err != nil {
 		p.abort()
-		return 0, p.err
+		return 0, err
 	}
 	nw := 0
 	for nw < len(b) {
@@ -138,12 +304,35 @@ func (p *putter) Write(b []byte) (int, error) {
 
 		if len(p.buf) == p.bufbytes {
 			p.flush()
+			if err := p.error(); err != nil {
+				p.abort()
+				return nw, err
+			}
 		}
 	}
 	return nw, nil
 }
 
+// sniffContentType sets the Content-Type of the upload from the buffered data, if enabled
+func (p *putter) sniffContentType() {
+	if p.sniff {
+		p.initHeader.Set("Content-Type", http.DetectContentType(p.buf[:p.bufbytes]))
+		p.sniff = false
+	}
+}
+
 func (p *putter) flush() {
+	if p.part >= maxNPart {
+		p.setErr(fmt.Errorf("object exceeds the %d part limit of S3 at %d bytes: increase PartSize or MaxPartSize", maxNPart, p.putsz))
+		return
+	}
+	if p.uploadID == "" {
+		p.sniffContentType()
+		if err := p.initiate(p.initHeader); err != nil {
+			p.setErr(err)
+			return
+		}
+	}
 	p.wg.Add(1)
 	p.part++
 	p.putsz += int64(p.bufbytes)
@@ -155,93 +344,272 @@ func (p *putter) flush() {
 	}
 	var err error
 	part.md5, part.sha256, part.ETag, err = p.hashContent(part.r)
+	if err == nil {
+		err = p.checksumContent(part)
+	}
 	if err != nil {
-		p.err = err
+		p.setErr(err)
 	}
 
 	p.xml.Part = append(p.xml.Part, part)
-	p.ch <- part
+	if up, ok := p.uploaded[part.PartNumber]; ok && up.Size == part.len && strings.Trim(up.ETag, `"`) == part.ETag {
+		// uploaded before the upload was resumed
+		p.progress.add(part.len)
+		p.sp.give <- part.b
+		p.wg.Done()
+	} else {
+		p.ch <- part
+	}
 	p.buf, p.bufbytes = nil, 0
 
-	// if necessary, double buffer size every 2000 parts due to the 10000-part AWS limit
-	// to reach the 5 Terabyte max object size, initial part size must be ~85 MB
-	if p.part%2000 == 0 && p.part < maxNPart && growPartSize(p.part, p.bufsz, p.putsz) {
-		p.bufsz = min64(p.bufsz*2, maxPartSize)
+	// if necessary, double buffer size every 2000 parts (by default) due to the 10000-part
+	// AWS limit. To reach the 5 Terabyte max object size, initial part size must be ~85 MB
+	if size := p.growth.next(p.part, p.bufsz, p.putsz); size != p.bufsz {
+		p.bufsz = size
 		p.sp.sizech <- p.bufsz // update pool buffer size
-		logger.debugPrintf("part size doubled to %d", p.bufsz)
+		p.bucket.Config.logger().debugPrintf("part size doubled to %d", p.bufsz)
 	}
 }
 
+func (p *putter) setErr(err error) {
+	p.errMu.Lock()
+	defer p.errMu.Unlock()
+	p.err = err
+}
+
+func (p *putter) error() error {
+	p.errMu.Lock()
+	defer p.errMu.Unlock()
+	return p.err
+}
+
 func (p *putter) worker() {
 	for part := range p.ch {
+		if part.src != nil {
+			if err := p.readPart(part); err != nil {
+				p.sp.give <- part.b
+				p.setErr(err)
+				p.abort()
+				p.wg.Done()
+				continue
+			}
+		}
 		p.retryPutPart(part)
 	}
 }
 
+// readPart reads part from its source into a buffer and hashes it
+func (p *putter) readPart(part *part) error {
+	part.b = <-p.sp.get
+	if int64(cap(part.b)) < part.len {
+		part.b = make([]byte, p.bufsz)
+	}
+	buf := part.b[:part.len]
+	if n, err := part.src.ReadAt(buf, part.off); n < len(buf) {
+		return fmt.Errorf("read part %d at %d: %w", part.PartNumber, part.off, err)
+	}
+	part.r = bytes.NewReader(buf)
+	md5Sum, shaSum, err := p.sumContent(part.r, ioutil.Discard)
+	if err != nil {
+		return err
+	}
+	part.md5, part.sha256, part.ETag = base64.StdEncoding.EncodeToString(md5Sum), shaSum, hex.EncodeToString(md5Sum)
+	return p.checksumContent(part)
+}
+
+// putReaderAt uploads size bytes of r, larger than the part size, with a multipart
+// upload whose parts are read and hashed by the workers, Concurrency at a time, then
+// completes it as Close.
+func (p *putter) putReaderAt(r io.ReaderAt, size int64) error {
+	n := int((size + p.bufsz - 1) / p.bufsz)
+	if n > maxNPart {
+		p.setErr(fmt.Errorf("object exceeds the %d part limit of S3 at %d bytes: increase PartSize", maxNPart, size))
+		return p.Close() // aborts
+	}
+	if p.sniff {
+		head := make([]byte, 512)
+		m, _ := r.ReadAt(head, 0)
+		p.initHeader.Set("Content-Type", http.DetectContentType(head[:m]))
+		p.sniff = false
+	}
+	if err := p.initiate(p.initHeader); err != nil {
+		p.setErr(err)
+		return p.Close() // aborts
+	}
+	md5s := make(chan error, 1)
+	if p.bucket.Config.Md5Check {
+		go func() { // the md5 of the object, read in order
+			_, err := io.Copy(p.md5, io.NewSectionReader(r, 0, size))
+			md5s <- err
+		}()
+	} else {
+		md5s <- nil
+	}
+	for i := 0; i < n && p.error() == nil && p.ctx.Err() == nil; i++ {
+		off := int64(i) * p.bufsz
+		part := &part{src: r, off: off, len: min64(p.bufsz, size-off), PartNumber: i + 1}
+		p.xml.Part = append(p.xml.Part, part)
+		p.wg.Add(1)
+		p.ch <- part
+	}
+	p.wg.Wait()
+	p.part, p.putsz = len(p.xml.Part), size
+	err := <-md5s
+	if err == nil {
+		err = p.error()
+	}
+	if err != nil {
+		p.setErr(err)
+		return p.Close() // aborts
+	}
+	for _, part := range p.xml.Part { // in order, once all are hashed
+		sum, _ := hex.DecodeString(part.ETag)
+		p.md5OfParts.Write(sum)
+	}
+	return p.Close()
+}
+
 // Calls putPart up to nTry times to recover from transient errors.
+// The multipart upload is aborted if the part still fails.
 func (p *putter) retryPutPart(part *part) {
 	defer p.wg.Done()
-	var err error
-	for i := 0; i < p.ntry; i++ {
-		err = p.putPart(part)
+	o := p.bucket.Config.PartObserver
+	start := time.Now()
+	if o != nil {
+		o.PartStarted(part.PartNumber)
+	}
+	err := retry(p.ctx, p.ntry, p.bucket.Config.retryDelay, func(i int) (bool, error) {
+		if i > 0 {
+			p.stats.retry(part.PartNumber)
+		}
+		p.adaptive.acquire()
+		p.stats.begin()
+		err := p.putPart(part, i)
+		p.stats.done()
+		p.adaptive.release(err)
 		if err == nil {
-			p.sp.give <- part.b
-			part.b = nil
-			return
+			if o != nil {
+				o.PartCompleted(part.PartNumber, part.len, i+1, time.Since(start))
+			}
+			return false, nil
+		}
+		if errors.Is(err, ErrPreconditionFailed) {
+			return false, err // retrying can't succeed
 		}
-		logger.debugPrintf("Error on attempt %d: Retrying part: %d, Error: %s", i, part.PartNumber, err)
-		time.Sleep(time.Duration(math.Exp2(float64(i))) * 100 * time.Millisecond) // exponential back-off
+		p.bucket.Config.logger().debugPrintf("Error on attempt %d: Retrying part: %d, Error: %s", i, part.PartNumber, err)
+		return true, err
+	})
+	if err == nil {
+		p.progress.add(part.len)
+		p.stats.part(part.len)
+		p.sp.give <- part.b
+		part.b = nil
+		return
 	}
-	p.err = err
+	if o != nil {
+		o.PartFailed(part.PartNumber, err)
+	}
+	p.setErr(err)
+	p.abort() // the part failed permanently, do not wait for Close to clean up
 }
 
-// uploads a part, checking the etag against the calculated value
-func (p *putter) putPart(part *part) error {
-	v := url.Values{}
-	v.Set("partNumber", strconv.Itoa(part.PartNumber))
-	v.Set("uploadId", p.UploadID)
+// uploads a part, checking the etag against the calculated value.
+// Before a multipart upload is initiated, the part is put as the whole object.
+func (p *putter) putPart(part *part, attempt int) error {
+	urlStr := p.url.String()
+	if p.uploadID != "" {
+		v := url.Values{}
+		v.Set("partNumber", strconv.Itoa(part.PartNumber))
+		v.Set("uploadId", p.uploadID)
+		urlStr += "?" + v.Encode()
+	}
 	if _, err := part.r.Seek(0, 0); err != nil { // move back to beginning, if retrying
 		return err
 	}
-	req, err := http.NewRequest("PUT", p.url.String()+"?"+v.Encode(), part.r)
+	var body io.Reader = part.r
+	if part.len > 0 { // an empty part must stay a known type for its zero Content-Length
+		body = limitReader(p.ctx, part.r, p.limiter)
+	}
+	req, err := http.NewRequest("PUT", urlStr, body)
 	if err != nil {
 		return err
 	}
+	req = withAttempt(req.WithContext(p.ctx), attempt)
 	req.ContentLength = part.len
 	req.Header.Set(md5Header, part.md5)
-	req.Header.Set(sha256Header, part.sha256)
+	if p.uploadID == "" {
+		setHeaders(req.Header, p.initHeader)
+		setHeaders(req.Header, p.cond)
+	} else {
+		setHeaders(req.Header, p.sse)
+	}
+	if p.checksumAlg != "" {
+		req.Header.Set(checksumHeader(p.checksumAlg), *part.checksum(p.checksumAlg))
+	}
 
-	p.bucket.Sign(req)
+	if p.bucket.Config.StreamingSignature && !p.bucket.unsignedPayload(req.URL) {
+		p.bucket.signStreaming(req, part.len)
+	} else {
+		req.Header.Set(sha256Header, part.sha256)
+		p.bucket.Sign(req)
+	}
 	resp, err := p.bucket.Do(req)
 	if err != nil {
 		return err
 	}
 	defer checkClose(resp.Body, err)
 	if resp.StatusCode != 200 {
+		if p.bucket.redirect(resp) {
+			return p.putPart(part, attempt) // to the region of the bucket, which is now cached
+		}
 		return newRespError(resp)
 	}
 	s := resp.Header.Get("etag")
 	if len(s) < 2 {
 		return fmt.Errorf("Got Bad etag:%s", s)
 	}
+	if p.uploadID == "" {
+		p.ETag = s
+		p.versionID = resp.Header.Get(versionIDHeader)
+	}
 	s = s[1 : len(s)-1] // includes quote chars for some reason
+	if len(p.sse) > 0 || p.kms {
+		return nil // the etag of SSE-C and SSE-KMS parts is not their md5
+	}
 	if part.ETag != s {
-		return fmt.Errorf("Response etag does not match. Remote:%s Calculated:%s", s, p.ETag)
+		return fmt.Errorf("%w: response etag does not match. Remote:%s Calculated:%s", ErrIntegrity, s, part.ETag)
 	}
 	return nil
 }
 
 func (p *putter) Close() (err error) {
+	defer p.stats.close()
+	defer p.cancel() // release the context once the upload is completed
 	if p.closed {
 		p.abort()
 		return syscall.EINVAL
 	}
-	if p.err != nil {
+	if err := p.ctx.Err(); err != nil {
+		p.abort()
+		return err
+	}
+	if err := p.error(); err != nil {
 		p.abort()
-		return p.err
+		return err
+	}
+	if p.zw != nil {
+		zw := p.zw
+		p.zw = nil
+		if err := zw.Close(); err != nil { // flushes the compressed data to p.write
+			p.abort()
+			return err
+		}
+	}
+	if p.part == 0 && p.uploadID == "" { // data fits in a single part
+		return p.putObject()
 	}
 	if p.bufbytes > 0 || // partial part
-		p.part == 0 { // 0 length file
+		p.part == 0 { // 0 length file of a resumed upload
 		p.flush()
 	}
 	p.wg.Wait()
@@ -249,10 +617,14 @@ func (p *putter) Close() (err error) {
 	p.closed = true
 	close(p.sp.quit)
 
-	// check p.err before completing
-	if p.err != nil {
+	// check for cancellation and p.err before completing
+	if err := p.ctx.Err(); err != nil {
 		p.abort()
-		return p.err
+		return err
+	}
+	if err := p.error(); err != nil {
+		p.abort()
+		return err
 	}
 	// Complete Multipart upload
 	body, err := xml.Marshal(p.xml)
@@ -264,10 +636,10 @@ func (p *putter) Close() (err error) {
 	for retries := 0; retries < 5; retries++ {
 		b := bytes.NewReader(body)
 		v := url.Values{}
-		v.Set("uploadId", p.UploadID)
+		v.Set("uploadId", p.uploadID)
 
 		var resp *http.Response
-		resp, err = p.retryRequest("POST", p.url.String()+"?"+v.Encode(), b, nil)
+		resp, err = p.retryRequest(p.ctx, "POST", p.url.String()+"?"+v.Encode(), b, p.cond)
 		if err != nil {
 			p.abort()
 			return
@@ -287,74 +659,141 @@ func (p *putter) Close() (err error) {
 		// Parse etag from body of response
 		err = xml.NewDecoder(resp.Body).Decode(p)
 		if err != nil {
+			p.abort()
 			return
 		}
+		p.versionID = resp.Header.Get(versionIDHeader)
 
 		// This is what S3 returns instead of a 500 when we should try
 		// to complete the multipart upload again
 		if p.Code == "InternalError" {
 			continue
 		}
-		// Some other generic error
-		if p.Code != "" {
-			return fmt.Errorf("CompleteMultipartUpload error: %s", p.Code)
-		}
-
 		break
 	}
-	// Check md5 hash of concatenated part md5 hashes against ETag
+	// Some other generic error, or InternalError on every attempt
+	if p.Code != "" {
+		p.abort()
+		return fmt.Errorf("CompleteMultipartUpload error: %s", p.Code)
+	}
+	// The ETag of multipart uploads is the md5 hash of the concatenated part md5
+	// hashes, followed by the number of parts
 	// more info: https://forums.aws.amazon.com/thread.jspa?messageID=456442&#456442
-	calculatedMd5ofParts := fmt.Sprintf("%x", p.md5OfParts.Sum(nil))
-	// Trim quotes '"' and strip part count from end.
-	remoteMd5ofParts := strings.Trim(p.ETag, "\"")
-	remoteMd5ofParts = strings.Split(remoteMd5ofParts, "-")[0]
-	if len(remoteMd5ofParts) == 0 {
+	p.computedETag = fmt.Sprintf("%x-%d", p.md5OfParts.Sum(nil), len(p.xml.Part))
+	if len(p.sse) > 0 || p.kms {
+		// the etag of SSE-C and SSE-KMS objects is not the md5 of their parts'
+		// md5s, each part's Content-MD5 was checked by S3 instead
+		return p.putMd5IfChecked()
+	}
+	// Trim quotes '"', and compare the part count if the service includes it
+	remoteETag := strings.Trim(p.ETag, "\"")
+	if len(remoteETag) == 0 {
 		return fmt.Errorf("Nil ETag")
 	}
-	if calculatedMd5ofParts != remoteMd5ofParts {
-		if err != nil {
-			return err
-		}
-		return fmt.Errorf("MD5 hash of part hashes comparison failed. Hash from multipart complete header: %s."+
-			" Calculated multipart hash: %s.", remoteMd5ofParts, calculatedMd5ofParts)
+	expected := p.computedETag
+	if !strings.Contains(remoteETag, "-") {
+		expected = strings.Split(expected, "-")[0]
 	}
-	if p.bucket.Config.Md5Check {
-		for i := 0; i < p.ntry; i++ {
-			if err = p.putMd5(); err == nil {
-				break
-			}
-		}
+	if remoteETag != expected {
+		return fmt.Errorf("%w: ETag from multipart complete: %s. Calculated ETag: %s.", ErrIntegrity, remoteETag, expected)
 	}
-	return
+	return p.putMd5IfChecked()
+}
+
+// putMd5IfChecked puts the md5 file of the object if md5 checking is configured, once
+// the object is stored, so that an md5 file is never put for an object that failed.
+// Its errors match ErrMd5NotStored rather than the errors of the object.
+func (p *putter) putMd5IfChecked() error {
+	if !p.bucket.Config.Md5Check {
+		return nil
+	}
+	if err := p.putMd5(); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrMd5NotStored, md5Key(p.bucket.objectKey(p.url)), err)
+	}
+	return nil
+}
+
+// putObject puts the buffered data with a single request instead of a multipart upload
+func (p *putter) putObject() (err error) {
+	p.sniffContentType()
+	p.part++
+	p.putsz += int64(p.bufbytes)
+	part := &part{
+		r:          bytes.NewReader(p.buf[:p.bufbytes]),
+		len:        int64(p.bufbytes),
+		b:          p.buf,
+		PartNumber: p.part,
+	}
+	part.md5, part.sha256, part.ETag, err = p.hashContent(part.r)
+	if err == nil {
+		err = p.checksumContent(part)
+	}
+	if err != nil {
+		return err
+	}
+	p.computedETag = part.ETag
+	p.buf, p.bufbytes = nil, 0
+	p.wg.Add(1)
+	p.retryPutPart(part)
+	close(p.ch)
+	p.closed = true
+	close(p.sp.quit)
+	if err := p.error(); err != nil {
+		return err
+	}
+	return p.putMd5IfChecked()
 }
 
 // Try to abort multipart upload. Do not error on failure.
+// The upload is aborted at most once, and the request is not bound to p.ctx
+// so that cancelled uploads are still cleaned up.
 func (p *putter) abort() {
+	p.abortOnce.Do(p.abortUpload)
+}
+
+func (p *putter) abortUpload() {
+	if p.uploadID == "" {
+		return // not initiated
+	}
 	v := url.Values{}
-	v.Set("uploadId", p.UploadID)
+	v.Set("uploadId", p.uploadID)
 	s := p.url.String() + "?" + v.Encode()
-	resp, err := p.retryRequest("DELETE", s, nil, nil)
+	resp, err := p.retryRequest(context.Background(), "DELETE", s, nil, nil)
 	if err != nil {
-		logger.Printf("Error aborting multipart upload: %v\n", err)
+		p.bucket.Config.logger().Printf("Error aborting multipart upload: %v\n", err)
+		p.abortErr = err
 		return
 	}
 	defer checkClose(resp.Body, err)
 	if resp.StatusCode != 204 {
-		logger.Printf("Error aborting multipart upload: %v", newRespError(resp))
+		p.abortErr = newRespError(resp)
+		p.bucket.Config.logger().Printf("Error aborting multipart upload: %v", p.abortErr)
 	}
 	return
 }
 
+// Abort discards the upload without completing it: part uploads in flight are
+// cancelled and the multipart upload, if initiated, is aborted. Callers must call
+// exactly one of Close or Abort.
+func (p *putter) Abort() error {
+	if p.closed {
+		return syscall.EINVAL
+	}
+	p.closed = true
+	p.cancel()
+	p.wg.Wait()
+	close(p.ch)
+	close(p.sp.quit)
+	p.abort()
+	return p.abortErr
+}
+
 // Md5 functions
 func (p *putter) hashContent(r io.ReadSeeker) (string, string, string, error) {
-	m := md5.New()
-	s := sha256.New()
-	mw := io.MultiWriter(m, s, p.md5)
-	if _, err := io.Copy(mw, r); err != nil {
+	md5Sum, shaSum, err := p.sumContent(r, p.md5)
+	if err != nil {
 		return "", "", "", err
 	}
-	md5Sum := m.Sum(nil)
-	shaSum := hex.EncodeToString(s.Sum(nil))
 	etag := hex.EncodeToString(md5Sum)
 	// add to checksum of all parts for verification on upload completion
 	if _, err := p.md5OfParts.Write(md5Sum); err != nil {
@@ -363,25 +802,53 @@ func (p *putter) hashContent(r io.ReadSeeker) (string, string, string, error) {
 	return base64.StdEncoding.EncodeToString(md5Sum), shaSum, etag, nil
 }
 
+// sumContent returns the md5 and hex sha256 of the data of part r, also written to whole
+func (p *putter) sumContent(r io.Reader, whole io.Writer) ([]byte, string, error) {
+	m := md5.New()
+	s := sha256.New()
+	mw := io.MultiWriter(m, s, whole)
+	if p.bucket.Config.StreamingSignature || p.bucket.unsignedPayload(&p.url) {
+		mw = io.MultiWriter(m, whole) // the chunks are hashed as they are sent, or not at all
+	}
+	if _, err := io.Copy(mw, r); err != nil {
+		return nil, "", err
+	}
+	return m.Sum(nil), hex.EncodeToString(s.Sum(nil)), nil
+}
+
+// checksumContent sets the checksum of the part of the configured algorithm, if any
+func (p *putter) checksumContent(part *part) error {
+	if p.checksumAlg == "" {
+		return nil
+	}
+	h, err := newChecksumHash(p.checksumAlg)
+	if err != nil {
+		return err
+	}
+	if _, err := part.r.Seek(0, 0); err != nil {
+		return err
+	}
+	if _, err := io.Copy(h, part.r); err != nil {
+		return err
+	}
+	*part.checksum(p.checksumAlg) = base64.StdEncoding.EncodeToString(h.Sum(nil))
+	return nil
+}
+
 // Put md5 file in .md5 subdirectory of bucket  where the file is stored
 // e.g. the md5 for https://mybucket.s3.amazonaws.com/gof3r will be stored in
 // https://mybucket.s3.amazonaws.com/.md5/gof3r.md5
 func (p *putter) putMd5() (err error) {
 	calcMd5 := fmt.Sprintf("%x", p.md5.Sum(nil))
 	md5Reader := strings.NewReader(calcMd5)
-	md5Path := fmt.Sprint(".md5", p.url.Path, ".md5")
+	md5Path := md5Key(p.bucket.objectKey(p.url))
 	md5Url, err := p.bucket.url(md5Path)
 	if err != nil {
 		return err
 	}
-	logger.debugPrintln("md5: ", calcMd5)
-	logger.debugPrintln("md5Path: ", md5Path)
-	r, err := http.NewRequest("PUT", md5Url.String(), md5Reader)
-	if err != nil {
-		return
-	}
-	p.bucket.Sign(r)
-	resp, err := p.bucket.Do(r)
+	p.bucket.Config.logger().debugPrintln("md5: ", calcMd5)
+	p.bucket.Config.logger().debugPrintln("md5Path: ", md5Path)
+	resp, err := p.retryRequest(p.ctx, "PUT", md5Url.String(), md5Reader, nil)
 	if err != nil {
 		return
 	}
@@ -392,42 +859,10 @@ func (p *putter) putMd5() (err error) {
 	return
 }
 
-var err500 = errors.New("received 500 from server")
-
-func (p *putter) retryRequest(method, urlStr string, body io.ReadSeeker, h http.Header) (resp *http.Response, err error) {
-	for i := 0; i < p.ntry; i++ {
-		var req *http.Request
-		req, err = http.NewRequest(method, urlStr, body)
-		if err != nil {
-			return
-		}
-		for k := range h {
-			for _, v := range h[k] {
-				req.Header.Add(k, v)
-			}
-		}
-
-		if body != nil {
-			req.Header.Set(sha256Header, shaReader(body))
-		}
-
-		p.bucket.Sign(req)
-		resp, err = p.bucket.Do(req)
-		if err == nil && resp.StatusCode == 500 {
-			err = err500
-			time.Sleep(time.Duration(math.Exp2(float64(i))) * 100 * time.Millisecond) // exponential back-off
-		}
-		if err == nil {
-			return
-		}
-		logger.debugPrintln(err)
-		if body != nil {
-			if _, err = body.Seek(0, 0); err != nil {
-				return
-			}
-		}
-	}
-	return
+// retryRequest sends a request of the put with ctx, retrying network errors and 500
+// and 503 responses up to NTry attempts
+func (p *putter) retryRequest(ctx context.Context, method, urlStr string, body io.ReadSeeker, h http.Header) (resp *http.Response, err error) {
+	return p.bucket.sendRetry(ctx, p.ntry, method, urlStr, body, h, retryableStatus)
 }
 
 // returns true unless partSize is large enough
@@ -435,3 +870,60 @@ func (p *putter) retryRequest(method, urlStr string, body io.ReadSeeker, h http.
 func growPartSize(partIndex int, partSize, putsz int64) bool {
 	return (maxObjSize-putsz)/(maxNPart-int64(partIndex)) > partSize
 }
+
+// putPartSize returns the initial part size of puts for the PartSize of c, which is
+// raised to the 5 MB minimum part size of S3. Sizes over the 5 GB maximum are an error.
+func putPartSize(c *Config) (int64, error) {
+	partSize := c.PartSize
+	if partSize > maxPartSize {
+		return 0, fmt.Errorf("part size %d exceeds the maximum part size of S3: %d", partSize, maxPartSize)
+	}
+	if partSize < minPartSize {
+		c.logger().debugPrintf("part size %d raised to the minimum part size of S3: %d", partSize, minPartSize)
+		return minPartSize, nil
+	}
+	return partSize, nil
+}
+
+// partGrowth is the part size growth schedule of puts
+type partGrowth struct {
+	every int   // parts between doublings
+	max   int64 // maximum part size
+}
+
+func newPartGrowth(c *Config) partGrowth {
+	g := partGrowth{every: c.PartSizeGrowthParts, max: c.MaxPartSize}
+	if g.every <= 0 {
+		g.every = 2000
+	}
+	if g.max <= 0 || g.max > maxPartSize {
+		g.max = maxPartSize
+	}
+	return g
+}
+
+// next returns the size of the part after part partIndex of partSize, with putsz
+// bytes put so far.
+func (g partGrowth) next(partIndex int, partSize, putsz int64) int64 {
+	if partIndex%g.every == 0 && partIndex < maxNPart && growPartSize(partIndex, partSize, putsz) {
+		return max64(min64(partSize*2, g.max), partSize)
+	}
+	return partSize
+}
+
+// MaxObjectSize returns the size of the largest object that can be put with c
+// in the 10000 parts allowed by S3, following the growth of the part size, or 0 if
+// PartSize exceeds the maximum part size.
+func (c *Config) MaxObjectSize() int64 {
+	g := newPartGrowth(c)
+	size, err := putPartSize(c)
+	if err != nil {
+		return 0
+	}
+	var putsz int64
+	for i := 1; i <= maxNPart; i++ {
+		putsz += size
+		size = g.next(i, size, putsz)
+	}
+	return putsz
+}
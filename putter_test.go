@@ -0,0 +1,535 @@
+package s3gof3r
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPutterCancel(t *testing.T) {
+	var mu sync.Mutex
+	var aborted string
+	started := make(chan struct{}, 1)
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case r.Method == "POST" && q.Has("uploads"):
+			w.Write([]byte(`<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`))
+		case r.Method == "PUT" && q.Get("partNumber") != "":
+			select {
+			case started <- struct{}{}:
+			default:
+			}
+			io.Copy(ioutil.Discard, r.Body)
+			<-r.Context().Done() // hang until the client gives up
+		case r.Method == "DELETE":
+			mu.Lock()
+			aborted = q.Get("uploadId")
+			mu.Unlock()
+			w.WriteHeader(204)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w, err := b.PutWriterContext(ctx, "key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(bytes.Repeat([]byte("a"), int(5*mb))); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("part upload not started")
+	}
+	cancel()
+	if err := w.Close(); err != context.Canceled {
+		t.Errorf("expected %v on Close, got %v", context.Canceled, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if aborted != "upload-1" {
+		t.Errorf("expected abort of upload-1, got %q", aborted)
+	}
+}
+
+var putRequestsTests = []struct {
+	size      int64
+	multipart bool
+}{
+	{0, false},
+	{1 * kb, false},
+	{5*mb - 1, false},
+	{5 * mb, true},
+	{11 * mb, true},
+}
+
+func TestPutRequests(t *testing.T) {
+	s := newFakeS3()
+	var mu sync.Mutex
+	var multipart bool
+	s.check = func(r *http.Request) {
+		if r.URL.Query().Has("uploads") {
+			mu.Lock()
+			multipart = true
+			mu.Unlock()
+		}
+	}
+	b := newTestBucket(t, s)
+	b.Config.Md5Check = true
+	for _, tt := range putRequestsTests {
+		multipart = false
+		data := bytes.Repeat([]byte("a"), int(tt.size))
+		w, err := b.PutWriter("key", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		mu.Lock()
+		if multipart != tt.multipart {
+			t.Errorf("size %d: expected multipart %v, got %v", tt.size, tt.multipart, multipart)
+		}
+		mu.Unlock()
+		if got, _ := s.object("/bucket/key"); !bytes.Equal(got, data) {
+			t.Errorf("size %d: put data differs, got %d bytes", tt.size, len(got))
+		}
+		r, _, err := b.GetReader("key") // verifies the md5
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.Copy(ioutil.Discard, r); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Close(); err != nil {
+			t.Errorf("size %d: %v", tt.size, err)
+		}
+	}
+}
+
+func TestPutterAbortOnPartFailure(t *testing.T) {
+	aborted := make(chan string, 1)
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		io.Copy(ioutil.Discard, r.Body)
+		switch {
+		case r.Method == "POST" && q.Has("uploads"):
+			w.Write([]byte(`<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`))
+		case r.Method == "PUT" && q.Get("partNumber") != "":
+			http.Error(w, "InternalError", 500)
+		case r.Method == "DELETE":
+			aborted <- q.Get("uploadId")
+			w.WriteHeader(204)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+
+	w, err := b.PutWriter("key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(bytes.Repeat([]byte("a"), int(5*mb))); err != nil {
+		t.Fatal(err)
+	}
+	// the upload is aborted once the part fails NTry times, before Close
+	select {
+	case id := <-aborted:
+		if id != "upload-1" {
+			t.Errorf("expected abort of upload-1, got %q", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("upload not aborted after part failure")
+	}
+	if err := w.Close(); err == nil {
+		t.Error("expected error on Close after part failure")
+	}
+	select {
+	case id := <-aborted:
+		t.Errorf("unexpected second abort of %q", id)
+	default:
+	}
+}
+
+func TestResumePutWriter(t *testing.T) {
+	s := newFakeS3()
+	var mu sync.Mutex
+	var putParts []string
+	s.check = func(r *http.Request) {
+		if n := r.URL.Query().Get("partNumber"); n != "" {
+			mu.Lock()
+			putParts = append(putParts, n)
+			mu.Unlock()
+		}
+	}
+	b := newTestBucket(t, s)
+	data := make([]byte, 12*mb)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	// interrupt an upload after two parts
+	w, err := b.PutWriter("key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data[:10*mb]); err != nil {
+		t.Fatal(err)
+	}
+	uploadID := w.(MultipartWriter).UploadID()
+	if uploadID == "" {
+		t.Fatal("expected upload ID after first part")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.mu.Lock()
+		n := len(s.uploads[uploadID])
+		s.mu.Unlock()
+		if n == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("parts not uploaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	putParts = nil
+	mu.Unlock()
+	w, err = b.ResumePutWriter("key", nil, uploadID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	if len(putParts) != 1 || putParts[0] != "3" {
+		t.Errorf("expected only part 3 to be uploaded on resume, got %v", putParts)
+	}
+	mu.Unlock()
+	if got, _ := s.object("/bucket/key"); !bytes.Equal(got, data) {
+		t.Error("resumed upload data differs")
+	}
+
+	if _, err := b.ResumePutWriter("key", nil, "missing"); err == nil {
+		t.Error("expected error resuming missing upload")
+	}
+}
+
+var maxObjectSizeTests = []struct {
+	config *Config
+	size   int64
+}{
+	{&Config{PartSize: 20 * mb}, 2000 * (20 + 40 + 80 + 160 + 320) * mb},
+	{&Config{PartSize: 20 * mb, MaxPartSize: 100 * mb}, 2000 * (20 + 40 + 80 + 100 + 100) * mb},
+	{&Config{PartSize: 5 * mb, PartSizeGrowthParts: 1000}, 1000 * 5 * 1023 * mb},
+	{&Config{PartSize: 1 * gb}, 10000 * gb},
+	{&Config{PartSize: 6 * gb}, 0},
+	{&Config{PartSize: 1 * mb}, 2000 * (5 + 10 + 20 + 40 + 80) * mb}, // at least 5 MB
+}
+
+func TestMaxObjectSize(t *testing.T) {
+	for _, tt := range maxObjectSizeTests {
+		if s := tt.config.MaxObjectSize(); s != tt.size {
+			t.Errorf("Expected max object size: %d. Actual: %d", tt.size, s)
+		}
+	}
+}
+
+func TestPutterPartLimit(t *testing.T) {
+	b := newTestBucket(t, newFakeS3())
+	b.Config.Md5Check = false
+	w, err := b.PutWriter("key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := w.(*putter)
+	if _, err := p.Write(make([]byte, 6*mb)); err != nil {
+		t.Fatal(err)
+	}
+	p.part = maxNPart // as if the rest of the parts were written
+	if _, err := p.Write(make([]byte, 5*mb)); err == nil {
+		t.Error("Expected error for part beyond the part limit")
+	}
+	if err := p.Close(); err == nil {
+		t.Error("Expected error on Close")
+	}
+}
+
+func TestPutterPartSize(t *testing.T) {
+	b := newTestBucket(t, newFakeS3())
+	b.Config.PartSize = 6 * gb
+	if _, err := b.PutWriter("key", nil); err == nil {
+		t.Error("Expected error for part size over 5 GB")
+	}
+	b.Config.PartSize = 1 * mb
+	w, err := b.PutWriter("key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := w.(*putter).bufsz; s != minPartSize {
+		t.Errorf("Expected part size: %d. Actual: %d", minPartSize, s)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPutterAbort(t *testing.T) {
+	s := newFakeS3()
+	b := newTestBucket(t, s)
+	b.Config.Md5Check = false
+
+	for _, size := range []int64{10, 6 * mb} {
+		w, err := b.PutWriter("key", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(make([]byte, size)); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.(MultipartWriter).Abort(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte("more")); err == nil {
+			t.Error("Expected error writing after Abort")
+		}
+		if _, ok := s.object("/bucket/key"); ok {
+			t.Errorf("%d bytes: Expected no object after Abort", size)
+		}
+		s.mu.Lock()
+		n := len(s.uploads)
+		s.mu.Unlock()
+		if n != 0 {
+			t.Errorf("%d bytes: Expected multipart upload to be aborted. Actual: %d uploads", size, n)
+		}
+	}
+}
+
+func TestPutWriterPreconditions(t *testing.T) {
+	s := newFakeS3()
+	b := newTestBucket(t, s)
+	b.Config.Md5Check = false
+	put := func(size int64, k, v string) error {
+		h := http.Header{}
+		h.Set(k, v)
+		w, err := b.PutWriter("key", h)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(bytes.Repeat([]byte("a"), int(size))); err != nil {
+			return err
+		}
+		return w.Close()
+	}
+
+	for _, size := range []int64{10, 6 * mb} {
+		if err := put(size, "If-None-Match", "*"); err != nil {
+			t.Fatalf("%d bytes: %v", size, err)
+		}
+		if err := put(size, "If-None-Match", "*"); !errors.Is(err, ErrPreconditionFailed) {
+			t.Errorf("%d bytes: Expected ErrPreconditionFailed for existing object. Actual: %v", size, err)
+		}
+		obj, _ := s.object("/bucket/key")
+		etag := fmt.Sprintf(`"%x"`, md5.Sum(obj))
+		if err := put(size, "If-Match", `"0123"`); !errors.Is(err, ErrPreconditionFailed) {
+			t.Errorf("%d bytes: Expected ErrPreconditionFailed for changed object. Actual: %v", size, err)
+		}
+		if err := put(size, "If-Match", etag); err != nil {
+			t.Errorf("%d bytes: %v", size, err)
+		}
+		s.mu.Lock()
+		delete(s.objects, "/bucket/key")
+		n := len(s.uploads)
+		inits := s.headers["/bucket/key"].Get("If-Match")
+		s.mu.Unlock()
+		if n != 0 {
+			t.Errorf("%d bytes: Expected failed uploads to be aborted. Actual: %d uploads", size, n)
+		}
+		if size > 5*mb && inits != "" {
+			t.Errorf("%d bytes: Expected no If-Match on initiation. Actual: %s", size, inits)
+		}
+	}
+}
+
+func TestPutterResult(t *testing.T) {
+	b := newTestBucket(t, newFakeS3())
+	b.Config.Md5Check = false
+
+	for _, size := range []int64{10, 11 * mb} {
+		data := bytes.Repeat([]byte("a"), int(size))
+		w, err := b.PutWriter("key", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		// the md5 of the data, or of the md5s of the 5 MB parts followed by the number of parts
+		etag := fmt.Sprintf("%x", md5.Sum(data))
+		if size > 5*mb {
+			var sums []byte
+			for i := int64(0); i < size; i += 5 * mb {
+				sum := md5.Sum(data[i:min64(i+5*mb, size)])
+				sums = append(sums, sum[:]...)
+			}
+			etag = fmt.Sprintf("%x-3", md5.Sum(sums))
+		}
+		res := w.(MultipartWriter).Result()
+		if res.ETag != etag || res.ComputedETag != etag {
+			t.Errorf("Expected ETag: %s. Actual: %s, computed %s", etag, res.ETag, res.ComputedETag)
+		}
+		if res.VersionID == "" {
+			t.Errorf("%d bytes: Expected version ID", size)
+		}
+	}
+}
+
+func TestPutterETagMismatch(t *testing.T) {
+	s := newFakeS3()
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || !r.URL.Query().Has("uploadId") {
+			s.ServeHTTP(w, r)
+			return
+		}
+		// complete with the ETag of an upload of other parts
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, r)
+		body := regexp.MustCompile(`<ETag>"?[0-9a-f]+`).ReplaceAll(rec.Body.Bytes(), []byte(`<ETag>"d41d8cd98f00b204e9800998ecf8427e`))
+		w.WriteHeader(rec.Code)
+		w.Write(body)
+	}))
+	w, err := b.PutWriter("key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(bytes.Repeat([]byte("a"), int(6*mb))); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); !errors.Is(err, ErrIntegrity) {
+		t.Errorf("Expected error: %v. Actual: %v", ErrIntegrity, err)
+	}
+}
+
+// discardS3 is a service discarding the data put, whose allocations aren't reported
+// with the putter's
+var discardS3 = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == "POST" && r.URL.Query().Has("uploads"):
+		fmt.Fprint(w, `<InitiateMultipartUploadResult><UploadId>upload</UploadId></InitiateMultipartUploadResult>`)
+	case r.Method == "PUT":
+		h := md5.New()
+		io.Copy(h, r.Body)
+		w.Header().Set("ETag", fmt.Sprintf(`"%x"`, h.Sum(nil)))
+	case r.Method == "POST":
+		var req struct {
+			Part []struct{ ETag string }
+		}
+		xml.NewDecoder(r.Body).Decode(&req)
+		h := md5.New()
+		for _, p := range req.Part {
+			sum, _ := hex.DecodeString(strings.Trim(p.ETag, `"`))
+			h.Write(sum)
+		}
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><ETag>"%x-%d"</ETag></CompleteMultipartUploadResult>`, h.Sum(nil), len(req.Part))
+	}
+})
+
+// BenchmarkPutWriter uploads objects of 8 parts, reporting the allocations of part
+// buffers, reused from the transfers before.
+func BenchmarkPutWriter(b *testing.B) {
+	bucket := newTestBucket(b, discardS3)
+	data := bytes.Repeat([]byte("a"), int(bucket.Config.PartSize))
+	b.ReportAllocs()
+	b.SetBytes(8 * int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w, err := bucket.PutWriter("key", nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for j := 0; j < 8; j++ {
+			if _, err := w.Write(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestPutWriterOptions(t *testing.T) {
+	s := newFakeS3()
+	b := newTestBucket(t, s)
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	o := PutOptions{
+		ContentType:        "text/csv",
+		ContentDisposition: `attachment; filename="report.csv"`,
+		CacheControl:       "max-age=3600",
+		ContentEncoding:    "identity",
+		Expires:            expires,
+	}
+	expect := map[string]string{
+		"Content-Type":        "text/csv",
+		"Content-Disposition": `attachment; filename="report.csv"`,
+		"Cache-Control":       "max-age=3600",
+		"Content-Encoding":    "identity",
+		"Expires":             "Wed, 02 Jan 2030 03:04:05 GMT",
+		"X-Amz-Meta-Owner":    "reports",
+	}
+	h := http.Header{}
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Amz-Meta-Owner", "reports")
+	for _, size := range []int{10, 12 << 20} {
+		w, err := b.PutWriterOptions("key", h, o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(make([]byte, size)); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		s.mu.Lock()
+		ph := s.headers["/bucket/key"]
+		s.mu.Unlock()
+		for k, v := range expect {
+			if ph.Get(k) != v {
+				t.Errorf("Expected %s of %d bytes: %s. Actual: %s", k, size, v, ph.Get(k))
+			}
+		}
+	}
+	if h.Get("Cache-Control") != "no-cache" {
+		t.Errorf("Expected headers passed unchanged. Actual: %v", h)
+	}
+}
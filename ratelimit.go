@@ -0,0 +1,82 @@
+package s3gof3r
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// limiter is a token bucket limiting the aggregate throughput of the part
+// workers of a get or put. A nil *limiter does not limit.
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens (bytes) added per second
+	burst  float64 // maximum tokens
+	tokens float64
+	last   time.Time
+}
+
+// newLimiter returns a limiter of bytesPerSec, or nil if bytesPerSec is not positive
+func newLimiter(bytesPerSec int64) *limiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &limiter{
+		rate:   float64(bytesPerSec),
+		burst:  float64(bytesPerSec),
+		tokens: float64(bytesPerSec),
+		last:   time.Now(),
+	}
+}
+
+// wait takes n tokens, blocking until they are available or ctx is done.
+// Tokens are reserved before waiting so that concurrent callers are queued fairly.
+func (l *limiter) wait(ctx context.Context, n int) {
+	if l == nil || n <= 0 {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	d := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+	if d > 0 {
+		sleep(ctx, d)
+	}
+}
+
+// maxLimitedRead is the maximum size of each read of a limitedReader, to smooth throughput
+const maxLimitedRead = 32 << 10
+
+// limitedReader is a reader limited by a limiter shared with other readers.
+type limitedReader struct {
+	ctx context.Context
+	r   io.Reader
+	l   *limiter
+}
+
+// limitReader returns r limited by l, or r if l is nil
+func limitReader(ctx context.Context, r io.Reader, l *limiter) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &limitedReader{ctx, r, l}
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > maxLimitedRead {
+		p = p[:maxLimitedRead]
+	}
+	n, err := lr.r.Read(p)
+	lr.l.wait(lr.ctx, n)
+	if err == nil {
+		err = lr.ctx.Err()
+	}
+	return n, err
+}
@@ -0,0 +1,65 @@
+package s3gof3r
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(1 * mb)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ { // 3 MB read concurrently, less the 1 MB burst, at 1 MB/s
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := limitReader(context.Background(), bytes.NewReader(make([]byte, 1*mb)), l)
+			if _, err := io.Copy(ioutil.Discard, r); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if d := time.Since(start); d < 1900*time.Millisecond || d > 4*time.Second {
+		t.Errorf("expected aggregate limit of 3 MB in about 2s, took %s", d)
+	}
+
+	if newLimiter(0) != nil {
+		t.Error("expected no limiter for zero MaxBytesPerSec")
+	}
+}
+
+func TestLimiterCancel(t *testing.T) {
+	l := newLimiter(1 * kb)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	r := limitReader(ctx, bytes.NewReader(make([]byte, 1*mb)), l)
+	if _, err := io.Copy(ioutil.Discard, r); err != context.DeadlineExceeded {
+		t.Errorf("Expected error: %v. Actual: %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestMaxBytesPerSec(t *testing.T) {
+	b := newTestBucket(t, newFakeS3())
+	b.Config.MaxBytesPerSec = 20 * mb
+	data := bytes.Repeat([]byte("a"), int(30*mb))
+	start := time.Now()
+	w, err := b.PutWriter("key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 400*time.Millisecond {
+		t.Errorf("expected put of 30 MB at 20 MB/s to take at least 0.5s, took %s", d)
+	}
+}
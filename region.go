@@ -0,0 +1,237 @@
+package s3gof3r
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+)
+
+const bucketRegionHeader = "X-Amz-Bucket-Region"
+
+// Region returns the region of the bucket, from the x-amz-bucket-region header of a
+// HEAD request on the bucket, or else GetBucketLocation. S3 includes the header even
+// in the error responses to requests of the wrong region.
+//
+// The region is cached and used to sign later requests of the bucket instead of the
+// region of the S3 endpoint, correcting AuthorizationHeaderMalformed errors. Requests
+// redirected by S3 to the region of the bucket also cache it.
+func (b *Bucket) Region() (string, error) {
+	if r := b.cachedRegion(); r != "" {
+		return r, nil
+	}
+	u, err := b.url("")
+	if err != nil {
+		return "", err
+	}
+	resp, err := b.retryRequest("HEAD", u, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	checkClose(resp.Body, err)
+	region := resp.Header.Get(bucketRegionHeader)
+	if region == "" {
+		if region, err = b.location(); err != nil {
+			return "", err
+		}
+	}
+	b.setRegion(region)
+	return region, nil
+}
+
+// location returns the region of the bucket from GetBucketLocation
+func (b *Bucket) location() (region string, err error) {
+	u, err := b.url("")
+	if err != nil {
+		return "", err
+	}
+	u.RawQuery = "location"
+	resp, err := b.retryRequest("GET", u, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	defer checkClose(resp.Body, err)
+	if resp.StatusCode != 200 {
+		return "", newRespError(resp)
+	}
+	var loc struct {
+		XMLName xml.Name `xml:"LocationConstraint"`
+		Region  string   `xml:",chardata"`
+	}
+	if err = xml.NewDecoder(resp.Body).Decode(&loc); err != nil {
+		return "", err
+	}
+	switch loc.Region {
+	case "": // buckets in us-east-1 have no location constraint
+		return "us-east-1", nil
+	case "EU":
+		return "eu-west-1", nil
+	}
+	return loc.Region, nil
+}
+
+func (b *Bucket) cachedRegion() string {
+	b.regionMu.RLock()
+	defer b.regionMu.RUnlock()
+	return b.region
+}
+
+func (b *Bucket) setRegion(region string) {
+	b.regionMu.Lock()
+	defer b.regionMu.Unlock()
+	b.region = region
+}
+
+// withConfig returns a copy of the bucket with config c, sharing the region and
+// endpoint found so far.
+func (b *Bucket) withConfig(c *Config) *Bucket {
+	b.regionMu.RLock()
+	defer b.regionMu.RUnlock()
+	return &Bucket{S3: b.S3, Name: b.Name, Config: c, region: b.region, endpoint: b.endpoint}
+}
+
+// checkRequest returns ErrNoRegion if the region to sign requests of the bucket for
+// is unknown, or ErrNoCredentials if its keys are empty, before sending a request.
+func (b *Bucket) checkRequest() error {
+	if err := b.checkRegion(); err != nil {
+		return err
+	}
+	return b.checkCredentials()
+}
+
+// checkRegion returns ErrNoRegion if the region to sign requests of the bucket for is unknown
+func (b *Bucket) checkRegion() error {
+	if b.signingRegion() == "" {
+		return ErrNoRegion
+	}
+	return nil
+}
+
+// signingRegion returns the region requests of the bucket are signed for: the
+// SigningRegion of the config, if set, or the region of the bucket
+func (b *Bucket) signingRegion() string {
+	if b.Config.SigningRegion != "" {
+		return b.Config.SigningRegion
+	}
+	return b.bucketRegion()
+}
+
+// bucketRegion returns the region of the bucket, found from a redirect or Region,
+// or the region of its S3 service
+func (b *Bucket) bucketRegion() string {
+	if r := b.cachedRegion(); r != "" {
+		return r
+	}
+	return b.S3.Region()
+}
+
+// host returns the host of requests to the bucket, which is the endpoint S3
+// redirected the bucket to, if any, unless the Endpoint of the config is set.
+func (b *Bucket) host() string {
+	if b.Config.Endpoint != "" {
+		return b.defaultHost()
+	}
+	b.regionMu.RLock()
+	defer b.regionMu.RUnlock()
+	if b.endpoint != "" {
+		return b.endpoint
+	}
+	return b.defaultHost()
+}
+
+// defaultHost returns the host of requests to the bucket at the S3 domain, or at the
+// Endpoint of the config
+func (b *Bucket) defaultHost() string {
+	if b.Config.Endpoint != "" {
+		e, _ := b.endpointURL()
+		return e.Host
+	}
+	if b.Config.DualStack {
+		return b.hostFor(b.S3.Domain())
+	}
+	if b.pathStyle() {
+		return b.S3.Domain()
+	}
+	return path.Clean(b.S3.DomainForBucket(b.Name))
+}
+
+// hostFor returns the host of requests to the bucket at domain
+func (b *Bucket) hostFor(domain string) string {
+	if b.Config.DualStack {
+		domain = dualStackDomain(domain)
+	}
+	if b.pathStyle() {
+		return domain
+	}
+	return path.Clean(fmt.Sprintf("%s.%s", b.Name, domain))
+}
+
+// redirect follows the region redirect of resp, if any: a 301 PermanentRedirect or,
+// with AutoRegion, a 400 AuthorizationHeaderMalformed error. The region and endpoint
+// given by S3 are cached on the bucket, so later requests are signed for the region
+// and sent to the endpoint. It reports whether the request should be retried, in
+// which case resp is closed.
+func (b *Bucket) redirect(resp *http.Response) bool {
+	if resp.StatusCode != 301 && (resp.StatusCode != 400 || !b.Config.AutoRegion) {
+		return false
+	}
+	region, endpoint := redirectLocation(resp)
+	if region == "" {
+		if endpoint == "" {
+			return false
+		}
+		region = b.bucketRegion()
+	}
+	host := b.host()
+	switch {
+	case b.Config.Endpoint != "": // requests are sent to the endpoint regardless
+	case endpoint != "":
+		host = b.hostFor(strings.TrimPrefix(endpoint, b.Name+"."))
+	case resp.StatusCode == 301 && strings.HasSuffix(b.S3.Domain(), ".amazonaws.com"):
+		host = b.hostFor(fmt.Sprintf("s3.%s.amazonaws.com", region))
+	}
+	if region == b.bucketRegion() && host == b.host() {
+		return false
+	}
+	checkClose(resp.Body, nil)
+	b.Config.logger().debugPrintf("bucket %s redirected to region %s at %s", b.Name, region, host)
+	b.regionMu.Lock()
+	defer b.regionMu.Unlock()
+	b.region, b.endpoint = region, host
+	return true
+}
+
+// redirectLocation returns the region and endpoint of the bucket given by S3 in a
+// 301 PermanentRedirect or 400 AuthorizationHeaderMalformed response, if any.
+// The body of resp is left unread.
+func redirectLocation(resp *http.Response) (region, endpoint string) {
+	region = resp.Header.Get(bucketRegionHeader)
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return region, ""
+	}
+	var e struct {
+		Code     string
+		Region   string
+		Endpoint string
+	}
+	if xml.Unmarshal(body, &e) != nil {
+		return region, ""
+	}
+	switch e.Code {
+	case "PermanentRedirect":
+		endpoint = e.Endpoint
+	case "AuthorizationHeaderMalformed":
+	default:
+		return region, ""
+	}
+	if region == "" {
+		region = e.Region
+	}
+	return region, endpoint
+}
@@ -0,0 +1,278 @@
+package s3gof3r
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// credentialRegion returns the region of the credential scope of the authorization header of r
+func credentialRegion(r *http.Request) string {
+	a := r.Header.Get("Authorization")
+	i := strings.Index(a, "Credential=")
+	if i < 0 {
+		return ""
+	}
+	scope := strings.Split(a[i+len("Credential="):], "/")
+	if len(scope) < 3 {
+		return ""
+	}
+	return scope[2]
+}
+
+var regionTests = []struct {
+	header   string // x-amz-bucket-region of the HEAD response
+	location string // LocationConstraint of GetBucketLocation
+	region   string
+}{
+	{"eu-central-1", "", "eu-central-1"},
+	{"", "ap-southeast-2", "ap-southeast-2"},
+	{"", "EU", "eu-west-1"},
+	{"", "", "us-east-1"},
+}
+
+func TestBucketRegion(t *testing.T) {
+	for _, tt := range regionTests {
+		b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == "HEAD" && r.URL.Path == "/bucket":
+				if tt.header != "" {
+					w.Header().Set(bucketRegionHeader, tt.header)
+				}
+			case r.Method == "GET" && r.URL.Query().Has("location"):
+				fmt.Fprintf(w, `<LocationConstraint>%s</LocationConstraint>`, tt.location)
+			default:
+				http.Error(w, "unexpected request", 400)
+			}
+		}))
+		region, err := b.Region()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if region != tt.region {
+			t.Errorf("Expected region: %s. Actual: %s", tt.region, region)
+		}
+		if b.signingRegion() != tt.region {
+			t.Errorf("Expected signing region: %s. Actual: %s", tt.region, b.signingRegion())
+		}
+	}
+}
+
+// wrongRegionS3 rejects requests not signed for region like S3, with 301 responses
+// for HEAD requests and 400 AuthorizationHeaderMalformed errors otherwise.
+func wrongRegionS3(region string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if credentialRegion(r) == region {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if r.Method == "HEAD" {
+			w.Header().Set(bucketRegionHeader, region)
+			w.WriteHeader(301)
+			return
+		}
+		w.WriteHeader(400)
+		fmt.Fprintf(w, `<Error><Code>AuthorizationHeaderMalformed</Code><Message>the region is wrong</Message><Region>%s</Region></Error>`, region)
+	})
+}
+
+func TestAutoRegion(t *testing.T) {
+	s := newFakeS3()
+	s.objects["/bucket/key"] = []byte("data")
+	b := newTestBucket(t, wrongRegionS3("eu-west-1", s))
+
+	if _, err := b.GetObjectTagging("key"); err == nil {
+		t.Fatal("Expected error for wrong region")
+	}
+
+	b.Config.AutoRegion = true
+	if err := b.PutObjectTagging("key", map[string]string{"k": "v"}); err != nil {
+		t.Fatal(err)
+	}
+	if tags, err := b.GetObjectTagging("key"); err != nil || tags["k"] != "v" {
+		t.Errorf("Expected tags: map[k:v]. Actual: %v, %v", tags, err)
+	}
+	if r := b.signingRegion(); r != "eu-west-1" {
+		t.Errorf("Expected signing region: eu-west-1. Actual: %s", r)
+	}
+
+	b = newTestBucket(t, wrongRegionS3("eu-west-1", s))
+	b.Config.AutoRegion = true
+	if _, err := b.Head("key"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newRedirectedBucket returns a test bucket whose requests are redirected to s in
+// region eu-west-1 by 301 PermanentRedirect responses, and the count of requests
+// before the redirect.
+func newRedirectedBucket(t *testing.T, s http.Handler) (*Bucket, *int32) {
+	srv := httptest.NewServer(wrongRegionS3("eu-west-1", s))
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	var redirects int32
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&redirects, 1)
+		w.Header().Set(bucketRegionHeader, "eu-west-1")
+		w.WriteHeader(301)
+		fmt.Fprintf(w, `<Error><Code>PermanentRedirect</Code><Message>use the endpoint</Message><Endpoint>bucket.%s</Endpoint></Error>`, u.Host)
+	}))
+	return b, &redirects
+}
+
+var redirectTests = []struct {
+	name string
+	do   func(b *Bucket) error
+}{
+	{"get", func(b *Bucket) error {
+		r, _, err := b.GetReader("key")
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		_, err = io.Copy(ioutil.Discard, r)
+		return err
+	}},
+	{"put", func(b *Bucket) error {
+		w, err := b.PutWriter("small", nil)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte("data")); err != nil {
+			return err
+		}
+		return w.Close()
+	}},
+	{"multipart put", func(b *Bucket) error {
+		w, err := b.PutWriter("large", nil)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(make([]byte, 6*mb)); err != nil {
+			return err
+		}
+		return w.Close()
+	}},
+	{"delete", func(b *Bucket) error {
+		return b.Delete("key")
+	}},
+}
+
+func TestRegionRedirect(t *testing.T) {
+	for _, tt := range redirectTests {
+		s := newFakeS3()
+		s.objects["/bucket/key"] = []byte("data")
+		b, redirects := newRedirectedBucket(t, s)
+		b.Config.Md5Check = false
+		// the first operation is redirected, the second sent to the region of the bucket
+		for i := 0; i < 2; i++ {
+			if err := tt.do(b); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+		}
+		if n := atomic.LoadInt32(redirects); n != 1 {
+			t.Errorf("%s: Expected 1 redirected request. Actual: %d", tt.name, n)
+		}
+		if r, err := b.Region(); err != nil || r != "eu-west-1" {
+			t.Errorf("%s: Expected region eu-west-1. Actual: %s, %v", tt.name, r, err)
+		}
+	}
+}
+
+var dualStackTests = []struct {
+	domain    string
+	pathStyle bool
+	host      string
+}{
+	{"s3.amazonaws.com", false, "bucket.s3.dualstack.us-east-1.amazonaws.com"},
+	{"s3.amazonaws.com", true, "s3.dualstack.us-east-1.amazonaws.com"},
+	{"s3-eu-west-1.amazonaws.com", false, "bucket.s3.dualstack.eu-west-1.amazonaws.com"},
+	{"s3.cn-north-1.amazonaws.com.cn", true, "s3.dualstack.cn-north-1.amazonaws.com.cn"},
+	{"s3.dualstack.ap-south-1.amazonaws.com", false, "bucket.s3.dualstack.ap-south-1.amazonaws.com"},
+	{"s3-accelerate.amazonaws.com", false, "bucket.s3-accelerate.dualstack.amazonaws.com"},
+	{"minio.example.com", true, "minio.example.com"},
+}
+
+func TestDualStack(t *testing.T) {
+	t.Setenv("AWS_REGION", "us-east-1")
+	for _, tt := range dualStackTests {
+		b, _ := NewBucket(New(tt.domain, nil), "bucket", &Config{DualStack: true, PathStyle: tt.pathStyle})
+		u, err := b.url("key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Host != tt.host {
+			t.Errorf("Expected host: %s. Actual: %s", tt.host, u.Host)
+		}
+	}
+	if r := New("s3.dualstack.ap-south-1.amazonaws.com", nil).Region(); r != "ap-south-1" {
+		t.Errorf("Expected region: ap-south-1. Actual: %s", r)
+	}
+}
+
+func TestNewWithRegion(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	if r := NewWithRegion("minio.example.com:9000", "local", nil).Region(); r != "local" {
+		t.Errorf("Expected region: local. Actual: %s", r)
+	}
+	if r := NewWithRegion("", "us-west-1", nil).Region(); r != "us-west-1" {
+		t.Errorf("Expected region: us-west-1. Actual: %s", r)
+	}
+}
+
+func TestNoRegion(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	b := New("s3-accelerate.amazonaws.com", nil).Bucket("bucket")
+	if _, err := b.Head("key"); !errors.Is(err, ErrNoRegion) {
+		t.Errorf("Expected error: %v. Actual: %v", ErrNoRegion, err)
+	}
+	if _, _, err := b.GetReader("key"); !errors.Is(err, ErrNoRegion) {
+		t.Errorf("Expected error: %v. Actual: %v", ErrNoRegion, err)
+	}
+	if _, err := b.PutWriter("key", nil); !errors.Is(err, ErrNoRegion) {
+		t.Errorf("Expected error: %v. Actual: %v", ErrNoRegion, err)
+	}
+	if _, err := b.PresignGet("key", time.Minute); !errors.Is(err, ErrNoRegion) {
+		t.Errorf("Expected error: %v. Actual: %v", ErrNoRegion, err)
+	}
+}
+
+func TestEndpointPort(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	if r := New("s3.eu-west-1.amazonaws.com:8443", nil).Region(); r != "eu-west-1" {
+		t.Errorf("Expected region: eu-west-1. Actual: %s", r)
+	}
+	keys := &Keys{accessKeyID: "AKIDEXAMPLE", secretAccessKey: "secret"}
+	for _, pathStyle := range []bool{true, false} {
+		b, _ := NewBucket(NewWithRegion("localhost:9000", "us-east-1", keys), "bucket", &Config{Scheme: "http", PathStyle: pathStyle})
+		u, err := b.url("key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		host := "localhost:9000"
+		if !pathStyle {
+			host = "bucket.localhost:9000"
+		}
+		if u.Host != host {
+			t.Errorf("Expected host: %s. Actual: %s", host, u.Host)
+		}
+		r, _ := http.NewRequest("GET", u.String(), nil)
+		s := &signer{Time: time.Now(), Request: r, S3Config: b.S3}
+		s.sign()
+		if !strings.Contains(s.canonicalHeaders, "host:"+host) {
+			t.Errorf("Expected canonical headers to include host:%s. Actual: %q", host, s.canonicalHeaders)
+		}
+	}
+	b, _ := NewBucket(New("s3.amazonaws.com:443", nil), "bucket", &Config{DualStack: true})
+	if u, _ := b.url("key"); u.Host != "bucket.s3.dualstack.us-east-1.amazonaws.com:443" {
+		t.Errorf("Expected host: bucket.s3.dualstack.us-east-1.amazonaws.com:443. Actual: %s", u.Host)
+	}
+}
@@ -0,0 +1,76 @@
+package s3gof3r
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Retrieval tiers of restores of archived objects, from the fastest to the cheapest
+// See https://docs.aws.amazon.com/AmazonS3/latest/userguide/restoring-objects-retrieval-options.html
+const (
+	TierExpedited = "Expedited"
+	TierStandard  = "Standard"
+	TierBulk      = "Bulk"
+)
+
+type restoreRequest struct {
+	XMLName              xml.Name `xml:"RestoreRequest"`
+	Days                 int      `xml:"Days"`
+	GlacierJobParameters struct {
+		Tier string `xml:"Tier"`
+	} `xml:"GlacierJobParameters"`
+}
+
+// Restore requests a temporary copy of the archived object at path, e.g. of the GLACIER or
+// DEEP_ARCHIVE storage class, to be made readable for days, retrieved with tier (TierStandard
+// if empty). The restore takes minutes to hours depending on the tier: use Stat to check whether
+// the object has been Restored before getting it. Restoring a restored object extends its days.
+//
+// If the object is being restored already, the error matches ErrRestoreInProgress, which may be
+// ignored by callers only wanting the restore to be started.
+func (b *Bucket) Restore(path string, days int, tier string) error {
+	if days < 1 {
+		return fmt.Errorf("invalid restore days: %d", days)
+	}
+	var rr restoreRequest
+	rr.Days = days
+	rr.GlacierJobParameters.Tier = tier
+	if tier == "" {
+		rr.GlacierJobParameters.Tier = TierStandard
+	}
+	body, err := xml.Marshal(rr)
+	if err != nil {
+		return err
+	}
+	u, err := b.url(path)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	q.Set("restore", "")
+	u.RawQuery = q.Encode()
+
+	sum := md5.Sum(body)
+	h := make(http.Header)
+	h.Set(md5Header, base64.StdEncoding.EncodeToString(sum[:]))
+	resp, err := b.retryRequest("POST", u, body, h)
+	if err != nil {
+		return err
+	}
+	defer checkClose(resp.Body, err)
+	switch resp.StatusCode {
+	case 200, 202: // restored already, or restore started
+		return nil
+	}
+	return newRespError(resp)
+}
+
+// Restored reports whether a requested restore of an archived object has completed, from
+// its x-amz-restore header, e.g. `ongoing-request="false", expiry-date="..."`.
+func (i *ObjectInfo) Restored() bool {
+	return strings.Contains(i.Restore, `ongoing-request="false"`)
+}
@@ -0,0 +1,54 @@
+package s3gof3r
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+var restoreTests = []struct {
+	status     int
+	body       string
+	tier       string
+	fail       bool
+	inProgress bool
+}{
+	{202, ``, "", false, false},
+	{200, ``, TierBulk, false, false},
+	{409, `<Error><Code>RestoreAlreadyInProgress</Code><Message>Object restore is already in progress</Message></Error>`, TierExpedited, true, true},
+	{403, `<Error><Code>InvalidObjectState</Code></Error>`, "", true, false},
+}
+
+func TestRestore(t *testing.T) {
+	for _, tt := range restoreTests {
+		var req restoreRequest
+		b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" || r.URL.Path != "/bucket/key" || !r.URL.Query().Has("restore") {
+				t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			if err := xml.Unmarshal(body, &req); err != nil {
+				t.Error(err)
+			}
+			w.WriteHeader(tt.status)
+			fmt.Fprint(w, tt.body)
+		}))
+		err := b.Restore("key", 3, tt.tier)
+		if (err != nil) != tt.fail {
+			t.Errorf("%d: expected error %v, got %v", tt.status, tt.fail, err)
+		}
+		if errors.Is(err, ErrRestoreInProgress) != tt.inProgress {
+			t.Errorf("%d: expected ErrRestoreInProgress %v, got %v", tt.status, tt.inProgress, err)
+		}
+		tier := tt.tier
+		if tier == "" {
+			tier = TierStandard
+		}
+		if req.Days != 3 || req.GlacierJobParameters.Tier != tier {
+			t.Errorf("%d: unexpected restore request: %+v", tt.status, req)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package s3gof3r
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// RetryTransport is an http.RoundTripper sending requests with Transport, or
+// http.DefaultTransport if nil, and retrying the idempotent ones (GET, HEAD, PUT, DELETE
+// and OPTIONS) on network errors and 500 and 503 responses, up to NTry attempts in all,
+// waiting between attempts as in Backoff, or DefaultBackoff if nil. The Retry-After of
+// responses, such as 503 SlowDown, is honored, up to 30 seconds.
+//
+// Requests with a body are only retried if their GetBody is set, as it is by
+// http.NewRequest for bodies of bytes.Reader, bytes.Buffer and strings.Reader. Signed
+// requests are resent with the same signature, which S3 accepts for 15 minutes.
+// A context done ends the wait for the next attempt, returning its error.
+//
+// Its retries are those of the requests of buckets, gets and puts, which are already
+// made up to Config.NTry times each: a bucket whose Client uses a RetryTransport
+// should have an NTry of 1.
+type RetryTransport struct {
+	Transport http.RoundTripper
+	NTry      int
+	Backoff   *Backoff
+}
+
+// RoundTrip sends req, retrying it as needed
+func (t *RetryTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	ntry := t.NTry
+	if !idempotent(req.Method) || req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		ntry = 1
+	}
+	delay := func(attempt int, err error) time.Duration {
+		return retryDelay(t.Backoff, attempt, err)
+	}
+	err = retry(req.Context(), ntry, delay, func(attempt int) (bool, error) {
+		r := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return false, err
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+		resp, err = transport.RoundTrip(r)
+		if err != nil {
+			return true, err
+		}
+		if !retryableStatus(resp.StatusCode) || attempt >= ntry-1 {
+			return false, nil // the last response is returned as is
+		}
+		rerr := &RespError{StatusCode: resp.StatusCode, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+		io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4*kb)) // to reuse the connection
+		resp.Body.Close()
+		return true, rerr
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// idempotent reports whether requests of method may be retried
+func idempotent(method string) bool {
+	switch method {
+	case "", "GET", "HEAD", "PUT", "DELETE", "OPTIONS": // "" is GET
+		return true
+	}
+	return false
+}
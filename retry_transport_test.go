@@ -0,0 +1,130 @@
+package s3gof3r
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flakyTransport fails the requests it sends with the errors and statuses of fails,
+// in turn, and then responds with 200 and the body of each request.
+type flakyTransport struct {
+	fails  []interface{} // error or status code
+	bodies []string      // of the requests sent
+}
+
+func (t *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+	}
+	t.bodies = append(t.bodies, string(body))
+	status := 200
+	if len(t.fails) > 0 {
+		f := t.fails[0]
+		t.fails = t.fails[1:]
+		if err, ok := f.(error); ok {
+			return nil, err
+		}
+		status = f.(int)
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader(string(body))),
+		Request:    req,
+	}, nil
+}
+
+var errReset = errors.New("connection reset")
+
+var retryTransportTests = []struct {
+	method   string
+	body     string
+	fails    []interface{}
+	status   int
+	err      error
+	attempts int
+}{
+	{"GET", "", nil, 200, nil, 1},
+	{"GET", "", []interface{}{503, errReset, 500}, 200, nil, 4},
+	{"PUT", "data", []interface{}{500, errReset}, 200, nil, 3},
+	{"HEAD", "", []interface{}{404}, 404, nil, 1},
+	{"DELETE", "", []interface{}{500, 500, 500, 500, 500}, 500, nil, 4},
+	{"GET", "", []interface{}{errReset, errReset, errReset, errReset}, 0, errReset, 4},
+	{"POST", "data", []interface{}{500}, 500, nil, 1},
+}
+
+func TestRetryTransport(t *testing.T) {
+	for _, tt := range retryTransportTests {
+		ft := &flakyTransport{fails: tt.fails}
+		c := &http.Client{Transport: &RetryTransport{Transport: ft, NTry: 4, Backoff: &Backoff{Initial: time.Millisecond}}}
+		req, _ := http.NewRequest(tt.method, "http://bucket.s3.amazonaws.com/key", strings.NewReader(tt.body))
+		resp, err := c.Do(req)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s %v: Expected error: %v. Actual: %v", tt.method, tt.fails, tt.err, err)
+		}
+		if err == nil {
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != tt.status || string(body) != tt.body {
+				t.Errorf("%s %v: Expected %d %q. Actual: %d %q", tt.method, tt.fails, tt.status, tt.body, resp.StatusCode, body)
+			}
+		}
+		if len(ft.bodies) != tt.attempts {
+			t.Errorf("%s %v: Expected attempts: %d. Actual: %d", tt.method, tt.fails, tt.attempts, len(ft.bodies))
+		}
+		for _, b := range ft.bodies {
+			if b != tt.body {
+				t.Errorf("%s %v: Expected body of each attempt: %q. Actual: %q", tt.method, tt.fails, tt.body, b)
+			}
+		}
+	}
+}
+
+func TestRetryTransportContext(t *testing.T) {
+	ft := &flakyTransport{fails: []interface{}{503}}
+	rt := &RetryTransport{Transport: ft, NTry: 2, Backoff: &Backoff{Initial: time.Hour}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://bucket.s3.amazonaws.com/key", nil)
+	if _, err := rt.RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded. Actual: %v", err)
+	}
+}
+
+// TestRetryTransportBucket gets an object of a bucket retrying with a RetryTransport
+// instead of its own attempts.
+func TestRetryTransportBucket(t *testing.T) {
+	s := newFakeS3()
+	b := newTestBucket(t, s)
+	if err := b.PutBytes("key", []byte("data"), nil); err != nil {
+		t.Fatal(err)
+	}
+	var fails int
+	b.Config.NTry = 1
+	b.Config.Client = &http.Client{Transport: &RetryTransport{
+		Transport: roundTripper(func(req *http.Request) (*http.Response, error) {
+			if fails < 2 {
+				fails++
+				return nil, errReset
+			}
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+		NTry:    3,
+		Backoff: &Backoff{Initial: time.Millisecond},
+	}}
+	data, _, err := b.GetBytes("key")
+	if err != nil || string(data) != "data" {
+		t.Errorf("Expected data: data. Actual: %q %v", data, err)
+	}
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
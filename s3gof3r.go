@@ -6,39 +6,47 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"net"
 	"os"
 	"regexp"
+	"strings"
 )
 
-const versionParam = "versionId"
+const (
+	versionParam    = "versionId"
+	versionIDHeader = "X-Amz-Version-Id"
+)
 
-var regionMatcher = regexp.MustCompile("s3[-.]([a-z0-9-]+).amazonaws.com([.a-z0-9]*)")
+var regionMatcher = regexp.MustCompile(`s3[-.](?:dualstack\.)?([a-z0-9-]+).amazonaws.com([.a-z0-9]*)`)
 
 // S3 contains the domain or endpoint of an S3-compatible service and
 // the authentication keys for that service.
 type S3 struct {
 	domain string // The s3-compatible endpoint. Defaults to "s3.amazonaws.com"
+	region string // The region of the endpoint, if set by NewWithRegion
 	*Keys
 }
 
-// Region returns the service region infering it from S3 domain.
+// Region returns the service region infering it from S3 domain, or else the
+// AWS_REGION environment variable. The region given to NewWithRegion is returned
+// for any domain.
+//
+// "" is returned if the region can't be found, in which case requests of the
+// buckets of s fail with ErrNoRegion.
 func (s *S3) Region() string {
-	region := os.Getenv("AWS_REGION")
-	switch s.Domain() {
+	if s.region != "" {
+		return s.region
+	}
+	domain, _ := splitPort(s.Domain())
+	switch domain {
 	case "s3.amazonaws.com", "s3-external-1.amazonaws.com":
 		return "us-east-1"
 	case "s3-accelerate.amazonaws.com":
-		if region == "" {
-			panic("can't find endpoint region")
-		}
-		return region
+		return os.Getenv("AWS_REGION")
 	default:
-		regions := regionMatcher.FindStringSubmatch(s.Domain())
+		regions := regionMatcher.FindStringSubmatch(domain)
 		if len(regions) < 2 {
-			if region == "" {
-				panic("can't find endpoint region")
-			}
-			return region
+			return os.Getenv("AWS_REGION")
 		}
 		return regions[1]
 	}
@@ -52,6 +60,34 @@ func (s *S3) DomainForBucket(bucket string) string {
 	return fmt.Sprintf("%s.%s", bucket, s.Domain())
 }
 
+// dualStackDomain returns the dual-stack form of an AWS S3 domain, such as
+// s3.dualstack.us-east-1.amazonaws.com for s3.amazonaws.com. Other domains are returned unchanged.
+func dualStackDomain(domain string) string {
+	host, port := splitPort(domain)
+	if strings.Contains(host, ".dualstack.") {
+		return domain
+	}
+	switch host {
+	case "s3.amazonaws.com", "s3-external-1.amazonaws.com":
+		return "s3.dualstack.us-east-1.amazonaws.com" + port
+	case "s3-accelerate.amazonaws.com":
+		return "s3-accelerate.dualstack.amazonaws.com" + port
+	}
+	if m := regionMatcher.FindStringSubmatch(host); len(m) == 3 {
+		return fmt.Sprintf("s3.dualstack.%s.amazonaws.com%s%s", m[1], m[2], port)
+	}
+	return domain
+}
+
+// splitPort splits domain into its host and port, if any, such as localhost and
+// :9000 for localhost:9000.
+func splitPort(domain string) (host, port string) {
+	if h, p, err := net.SplitHostPort(domain); err == nil {
+		return h, ":" + p
+	}
+	return domain, ""
+}
+
 // DefaultDomain is set to the endpoint for the U.S. S3 service.
 const DefaultDomain = "s3.amazonaws.com"
 
@@ -64,9 +100,22 @@ func New(domain string, keys *Keys) *S3 {
 	return &S3{domain: domain, Keys: keys}
 }
 
+// NewWithRegion returns a new S3 for domain in region, for S3-compatible services
+// such as MinIO or Ceph whose region can't be inferred from the domain.
+// domain defaults to DefaultDomain if empty
+func NewWithRegion(domain, region string, keys *Keys) *S3 {
+	s := New(domain, keys)
+	s.region = region
+	return s
+}
+
+// defaultConcurrency is the Concurrency of DefaultConfig, and the number of idle
+// connections per host kept by ClientWithTimeout
+const defaultConcurrency = 10
+
 // DefaultConfig contains defaults used if *Config is nil
 var DefaultConfig = &Config{
-	Concurrency: 10,
+	Concurrency: defaultConcurrency,
 	PartSize:    20 * mb,
 	NTry:        10,
 	Md5Check:    true,
@@ -75,46 +124,102 @@ var DefaultConfig = &Config{
 }
 
 // Bucket returns a bucket on s3
-// Bucket Config is initialized to DefaultConfig
+// Bucket Config is initialized to a copy of DefaultConfig, so changes to the Config of
+// one bucket don't affect other buckets or DefaultConfig.
 func (s *S3) Bucket(name string) *Bucket {
-	bucket, _ := NewBucket(s, name, DefaultConfig)
+	bucket, _ := NewBucket(s, name, DefaultConfig.clone())
 	return bucket
 }
 
+// clone returns a copy of c sharing none of its headers, keys or backoff, which may then
+// be changed independently of c. The Client, with its connections, is shared.
+func (c *Config) clone() *Config {
+	cc := *c
+	if c.DefaultHeaders != nil {
+		cc.DefaultHeaders = cloneHeader(c.DefaultHeaders)
+	}
+	if c.SSECustomerKey != nil {
+		cc.SSECustomerKey = append([]byte(nil), c.SSECustomerKey...)
+	}
+	if c.Backoff != nil {
+		b := *c.Backoff
+		cc.Backoff = &b
+	}
+	return &cc
+}
+
 // SetLogger wraps the standard library log package.
 //
 // It allows the internal logging of s3gof3r to be set to a desired output and format.
+// The Logger of the Config of a bucket, if set, is used for the bucket instead.
 // Setting debug to true enables debug logging output. s3gof3r does not log output by default.
+// Debug logging includes a line of key=value fields for each request, with its method,
+// path, byte range, attempt, status, x-amz-request-id, x-amz-id-2 and duration.
 func SetLogger(out io.Writer, prefix string, flag int, debug bool) {
 	logger = internalLogger{
-		log.New(out, prefix, flag),
-		debug,
+		Logger: log.New(out, prefix, flag),
+		debug:  debug,
 	}
 }
 
+// A Logger logs the output of the requests of a bucket through the logging framework
+// of the caller, e.g. slog, zap or logrus, instead of the global logger of SetLogger.
+// Debugf is called for the debug output, which the Logger may discard, and Printf for
+// errors that are not returned, such as failures to abort multipart uploads.
+type Logger interface {
+	Printf(format string, v ...interface{})
+	Debugf(format string, v ...interface{})
+}
+
 type internalLogger struct {
 	*log.Logger
-	debug bool
+	debug  bool
+	custom Logger // of the Config, instead of Logger
 }
 
 var logger internalLogger
 
+// logger returns the logger of c: its Logger, if set, or the global logger
+func (c *Config) logger() *internalLogger {
+	if c == nil || c.Logger == nil {
+		return &logger
+	}
+	return &internalLogger{debug: true, custom: c.Logger} // Debugf decides
+}
+
+func (l *internalLogger) Printf(format string, v ...interface{}) {
+	if l.custom != nil {
+		l.custom.Printf(strings.TrimSuffix(format, "\n"), v...)
+		return
+	}
+	l.Logger.Printf(format, v...)
+}
+
 func (l *internalLogger) debugPrintln(v ...interface{}) {
-	if logger.debug {
-		logger.Println(v...)
+	if !l.debug {
+		return
 	}
+	if l.custom != nil {
+		l.custom.Debugf("%s", strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+		return
+	}
+	l.Logger.Println(v...)
 }
 
 func (l *internalLogger) debugPrintf(format string, v ...interface{}) {
-	if logger.debug {
-		logger.Printf(format, v...)
+	if !l.debug {
+		return
+	}
+	if l.custom != nil {
+		l.custom.Debugf(strings.TrimSuffix(format, "\n"), v...)
+		return
 	}
+	l.Logger.Printf(format, v...)
 }
 
 // Initialize internal logger to log to no-op (ioutil.Discard) by default.
 func init() {
 	logger = internalLogger{
-		log.New(ioutil.Discard, "", log.LstdFlags),
-		false,
+		Logger: log.New(ioutil.Discard, "", log.LstdFlags),
 	}
 }
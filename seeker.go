@@ -0,0 +1,159 @@
+package s3gof3r
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"syscall"
+)
+
+// GetSeeker provides a reader of the object at path that supports seeking, for formats
+// read out of order such as zip archives. The size and ETag of the object are found with
+// a HEAD request, whose headers are returned in h.
+//
+// Reads get the object from the current offset with ranged GET requests of up to
+// PartSize bytes, one at a time, and the get of a range is discarded when the offset
+// is moved by Seek. Each get is conditional on the ETag, so reads fail with
+// ErrPreconditionFailed if the object is replaced. A get failing while it's read is
+// resent from the offset reached by the next Read.
+// The stored bytes are read from any offset, so gzip encoded objects are not decompressed
+// with DecompressGzip, and the md5 hash and checksums of the object are not verified.
+func (b *Bucket) GetSeeker(path string) (r io.ReadSeekCloser, h http.Header, err error) {
+	return b.GetSeekerContext(context.Background(), path)
+}
+
+// GetSeekerContext is like GetSeeker but binds every request made by the seeker to ctx.
+func (b *Bucket) GetSeekerContext(ctx context.Context, path string) (r io.ReadSeekCloser, h http.Header, err error) {
+	if path == "" {
+		return nil, nil, errors.New("empty path requested")
+	}
+	h, err = b.Head(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	size, err := strconv.ParseInt(h.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid content-length: %s", err)
+	}
+	return &seeker{ctx: ctx, b: b, path: path, size: size, etag: h.Get("ETag")}, h, nil
+}
+
+type seeker struct {
+	ctx    context.Context
+	b      *Bucket
+	path   string
+	size   int64
+	etag   string
+	offset int64
+
+	r      io.ReadCloser // get from offset, if any
+	closed bool
+}
+
+func (s *seeker) Read(p []byte) (n int, err error) {
+	if s.closed {
+		return 0, syscall.EINVAL
+	}
+	if s.offset >= s.size {
+		return 0, io.EOF
+	}
+	for {
+		if s.r == nil {
+			if s.r, err = s.get(); err != nil {
+				return 0, err
+			}
+		}
+		n, err = s.r.Read(p)
+		s.offset += int64(n)
+		if err == io.EOF && s.offset < s.size {
+			s.closeGet() // end of the range, the next is got from the offset
+			err = nil
+		} else if err != nil && err != io.EOF {
+			s.closeGet() // to get from the offset reached by the next Read
+		}
+		if n > 0 || err != nil || len(p) == 0 {
+			return n, err
+		}
+	}
+}
+
+// get sends a ranged GET request of up to PartSize bytes of the object from the
+// offset, returning its body
+func (s *seeker) get() (io.ReadCloser, error) {
+	if err := s.b.checkRequest(); err != nil {
+		return nil, err
+	}
+	u, err := s.b.url(s.path)
+	if err != nil {
+		return nil, err
+	}
+	h, err := sseCustomerHeader(s.b.Config.SSECustomerKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	end := s.size
+	if ps := s.b.Config.PartSize; ps > 0 && s.offset+ps < end {
+		end = s.offset + ps
+	}
+	h.Set("Range", fmt.Sprintf("bytes=%d-%d", s.offset, end-1))
+	if s.etag != "" {
+		h.Set("If-Match", s.etag)
+	}
+	resp, err := s.b.sendRetry(s.ctx, s.b.Config.NTry, "GET", u.String(), nil, h, func(status int) bool {
+		return status >= 500
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 206 {
+		defer checkClose(resp.Body, err)
+		return nil, newRespError(resp)
+	}
+	return resp.Body, nil
+}
+
+// Seek sets the offset of the next Read. Seeking past the end of the object is
+// allowed, and reads from there return io.EOF.
+func (s *seeker) Seek(offset int64, whence int) (int64, error) {
+	if s.closed {
+		return 0, syscall.EINVAL
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.offset
+	case io.SeekEnd:
+		offset += s.size
+	default:
+		return 0, fmt.Errorf("invalid whence: %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("invalid offset: %d", offset)
+	}
+	if offset != s.offset {
+		s.closeGet()
+		s.offset = offset
+	}
+	return offset, nil
+}
+
+func (s *seeker) Close() error {
+	if s.closed {
+		return syscall.EINVAL
+	}
+	s.closed = true
+	s.closeGet()
+	return nil
+}
+
+// closeGet discards the get from the current offset. Its error is ignored, as it
+// is an error to close a get before reading to the end.
+func (s *seeker) closeGet() {
+	if s.r != nil {
+		s.r.Close()
+		s.r = nil
+	}
+}
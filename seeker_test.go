@@ -0,0 +1,219 @@
+package s3gof3r
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestGetSeeker(t *testing.T) {
+	s := newFakeS3()
+	obj := make([]byte, 11*mb)
+	rand.New(rand.NewSource(1)).Read(obj)
+	s.objects["/bucket/key"] = obj
+	b := newTestBucket(t, s)
+
+	r, h, err := b.GetSeeker("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if h.Get("Content-Length") != "11534336" {
+		t.Errorf("Expected Content-Length: 11534336. Actual: %s", h.Get("Content-Length"))
+	}
+
+	var seekTests = []struct {
+		offset int64
+		whence int
+		pos    int64
+		n      int
+	}{
+		{0, io.SeekStart, 0, 100},
+		{-100, io.SeekEnd, 11*mb - 100, 100},
+		{6 * mb, io.SeekStart, 6 * mb, int(mb)},
+		{-mb, io.SeekCurrent, 6 * mb, 5 * int(mb)},
+	}
+	for _, tt := range seekTests {
+		pos, err := r.Seek(tt.offset, tt.whence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pos != tt.pos {
+			t.Errorf("Expected offset: %d. Actual: %d", tt.pos, pos)
+		}
+		p := make([]byte, tt.n)
+		if _, err := io.ReadFull(r, p); err != nil {
+			t.Fatalf("read at %d: %v", pos, err)
+		}
+		if !bytes.Equal(p, obj[pos:pos+int64(tt.n)]) {
+			t.Errorf("Unexpected data read at %d", pos)
+		}
+	}
+
+	if _, err := r.Seek(12*mb, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := r.Read(make([]byte, 10)); n != 0 || err != io.EOF {
+		t.Errorf("Expected EOF past the end. Actual: %d, %v", n, err)
+	}
+	if _, err := r.Seek(-1, io.SeekStart); err == nil {
+		t.Error("Expected error for negative offset")
+	}
+}
+
+func TestGetSeekerReplaced(t *testing.T) {
+	s := newFakeS3()
+	s.objects["/bucket/key"] = []byte("data")
+	b := newTestBucket(t, s)
+
+	r, _, err := b.GetSeeker("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	s.mu.Lock()
+	s.objects["/bucket/key"] = []byte("other")
+	s.mu.Unlock()
+	if _, err := ioutil.ReadAll(r); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("Expected ErrPreconditionFailed. Actual: %v", err)
+	}
+}
+
+// TestGetSeekerStored reads the stored bytes of a gzip encoded object from any offset,
+// with DecompressGzip and Md5Check set, without verifying its wrong md5 file.
+func TestGetSeekerStored(t *testing.T) {
+	stored := gzipped(t, []byte("hello world, hello gzip"))
+	s := newFakeS3()
+	s.objects["/bucket/key"] = stored
+	s.headers["/bucket/key"] = http.Header{"Content-Encoding": {"gzip"}}
+	s.objects["/bucket/.md5/key.md5"] = []byte("00000000000000000000000000000000")
+	b := newTestBucket(t, s)
+	b.Config.Md5Check = true
+	b.Config.DecompressGzip = true
+
+	r, _, err := b.GetSeeker("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, stored) {
+		t.Errorf("Expected stored bytes from 0: %q. Actual: %q", stored, got)
+	}
+	for _, offset := range []int64{6, 0} {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		p := make([]byte, 10)
+		if _, err := io.ReadFull(r, p); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(p, stored[offset:offset+10]) {
+			t.Errorf("Expected stored bytes at %d: %q. Actual: %q", offset, stored[offset:offset+10], p)
+		}
+	}
+	if end, err := r.Seek(0, io.SeekEnd); err != nil || end != int64(len(stored)) {
+		t.Errorf("Expected size of the stored bytes: %d. Actual: %d, %v", len(stored), end, err)
+	}
+}
+
+// seekerCounter counts the GET requests served and the bytes of their responses
+type seekerCounter struct {
+	h     http.Handler
+	mu    sync.Mutex
+	gets  int
+	bytes int64
+}
+
+func (c *seekerCounter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		c.h.ServeHTTP(w, r)
+		return
+	}
+	c.mu.Lock()
+	c.gets++
+	c.mu.Unlock()
+	c.h.ServeHTTP(countingResponseWriter{w, c}, r)
+}
+
+type countingResponseWriter struct {
+	http.ResponseWriter
+	c *seekerCounter
+}
+
+func (w countingResponseWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.c.mu.Lock()
+	w.c.bytes += int64(n)
+	w.c.mu.Unlock()
+	return n, err
+}
+
+// TestGetSeekerRandomAccess reads a little at several offsets of a large object with one
+// ranged get of up to PartSize bytes each.
+func TestGetSeekerRandomAccess(t *testing.T) {
+	s := newFakeS3()
+	size := 64 * mb
+	obj := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(obj)
+	s.objects["/bucket/key"] = obj
+	c := &seekerCounter{h: s}
+	b := newTestBucket(t, c)
+	b.Config.Concurrency = 4
+	b.Config.PartSize = mb
+
+	r, _, err := b.GetSeeker("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	offsets := []int64{size - 10*kb, mb, 30 * mb, 0}
+	for _, offset := range offsets {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		p := make([]byte, kb)
+		if _, err := io.ReadFull(r, p); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(p, obj[offset:offset+kb]) {
+			t.Errorf("Unexpected data read at %d", offset)
+		}
+	}
+	r.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gets != len(offsets) {
+		t.Errorf("Expected gets: %d. Actual: %d", len(offsets), c.gets)
+	}
+	if max := int64(len(offsets)) * mb; c.bytes > max {
+		t.Errorf("Expected bytes served: <= %d. Actual: %d", max, c.bytes)
+	}
+}
+
+func TestGetSeekerContext(t *testing.T) {
+	s := newFakeS3()
+	s.objects["/bucket/key"] = []byte("data")
+	b := newTestBucket(t, s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r, _, err := b.GetSeekerContext(ctx, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	cancel()
+	if _, err := r.Read(make([]byte, 4)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled. Actual: %v", err)
+	}
+}
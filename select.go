@@ -0,0 +1,246 @@
+package s3gof3r
+
+import (
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// SelectRequest is a query of the content of an object with SelectObjectContent: the SQL
+// Expression, such as "SELECT s.name FROM S3Object s WHERE s.size > 100", is run by S3
+// on the object in the format of InputSerialization, and the records matched are
+// returned in the format of OutputSerialization.
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/API_SelectObjectContent.html
+type SelectRequest struct {
+	XMLName             xml.Name     `xml:"SelectObjectContentRequest"`
+	Expression          string       `xml:"Expression"`
+	ExpressionType      string       `xml:"ExpressionType"` // SQL if empty
+	InputSerialization  SelectInput  `xml:"InputSerialization"`
+	OutputSerialization SelectOutput `xml:"OutputSerialization"`
+}
+
+// SelectInput is the format of the object queried by a SelectRequest: one of CSV, JSON or
+// Parquet, compressed with CompressionType NONE, GZIP or BZIP2 (NONE if empty).
+type SelectInput struct {
+	CompressionType string        `xml:"CompressionType,omitempty"`
+	CSV             *CSVInput     `xml:"CSV"`
+	JSON            *JSONInput    `xml:"JSON"`
+	Parquet         *ParquetInput `xml:"Parquet"`
+}
+
+// CSVInput is the format of CSV objects queried. FileHeaderInfo is USE to refer to the
+// columns by the names of the first line, IGNORE to skip it, or NONE.
+type CSVInput struct {
+	FileHeaderInfo             string `xml:"FileHeaderInfo,omitempty"`
+	Comments                   string `xml:"Comments,omitempty"`
+	QuoteEscapeCharacter       string `xml:"QuoteEscapeCharacter,omitempty"`
+	RecordDelimiter            string `xml:"RecordDelimiter,omitempty"`
+	FieldDelimiter             string `xml:"FieldDelimiter,omitempty"`
+	QuoteCharacter             string `xml:"QuoteCharacter,omitempty"`
+	AllowQuotedRecordDelimiter bool   `xml:"AllowQuotedRecordDelimiter,omitempty"`
+}
+
+// JSONInput is the format of JSON objects queried: Type is DOCUMENT for a single
+// document, or LINES for a document per line.
+type JSONInput struct {
+	Type string `xml:"Type"`
+}
+
+// ParquetInput is the format of Parquet objects queried, which has no options
+type ParquetInput struct{}
+
+// SelectOutput is the format of the records returned for a SelectRequest: CSV or JSON
+type SelectOutput struct {
+	CSV  *CSVOutput  `xml:"CSV"`
+	JSON *JSONOutput `xml:"JSON"`
+}
+
+// CSVOutput is the format of CSV records returned. QuoteFields is ALWAYS or ASNEEDED.
+type CSVOutput struct {
+	QuoteFields          string `xml:"QuoteFields,omitempty"`
+	QuoteEscapeCharacter string `xml:"QuoteEscapeCharacter,omitempty"`
+	RecordDelimiter      string `xml:"RecordDelimiter,omitempty"`
+	FieldDelimiter       string `xml:"FieldDelimiter,omitempty"`
+	QuoteCharacter       string `xml:"QuoteCharacter,omitempty"`
+}
+
+// JSONOutput is the format of JSON records returned, separated by RecordDelimiter
+// (a newline if empty)
+type JSONOutput struct {
+	RecordDelimiter string `xml:"RecordDelimiter,omitempty"`
+}
+
+// Select runs the query of req on the object at path with SelectObjectContent, so
+// that only the records matched are downloaded, and returns a reader of the records.
+// The records are read from the event stream of the response as they are sent by S3;
+// errors of the query found after the response started, such as a malformed record,
+// are returned by Read as a *RespError.
+// Callers should call Close on r to ensure that all resources are released.
+func (b *Bucket) Select(path string, req SelectRequest) (r io.ReadCloser, err error) {
+	if req.Expression == "" {
+		return nil, errors.New("empty select expression")
+	}
+	if req.ExpressionType == "" {
+		req.ExpressionType = "SQL"
+	}
+	body, err := xml.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	u, err := b.subresourceURL(path, "select")
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("select-type", "2")
+	u.RawQuery = q.Encode()
+	resp, err := b.retryRequest("POST", u, body, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		return nil, newRespError(resp)
+	}
+	return &selectReader{body: resp.Body}, nil
+}
+
+// selectReader reads the payloads of the Records events of the event stream of a
+// SelectObjectContent response, until its End event.
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/RESTSelectObjectAppendix.html
+type selectReader struct {
+	body    io.ReadCloser
+	records []byte // remaining of the payload of the current Records event
+	err     error
+}
+
+func (s *selectReader) Read(p []byte) (int, error) {
+	for len(s.records) == 0 && s.err == nil {
+		s.err = s.next()
+	}
+	if len(s.records) == 0 {
+		return 0, s.err
+	}
+	n := copy(p, s.records)
+	s.records = s.records[n:]
+	return n, nil
+}
+
+func (s *selectReader) Close() error {
+	return s.body.Close()
+}
+
+// next reads the next message of the event stream, keeping the payload of Records
+// events. It returns io.EOF after the End event.
+func (s *selectReader) next() error {
+	headers, payload, err := readEventMessage(s.body)
+	if err == io.EOF {
+		return fmt.Errorf("select: %w before the End event", io.ErrUnexpectedEOF)
+	}
+	if err != nil {
+		return err
+	}
+	switch headers[":message-type"] {
+	case "error":
+		return &RespError{Code: headers[":error-code"], Message: headers[":error-message"], StatusCode: 200}
+	case "event":
+	default:
+		return fmt.Errorf("select: unexpected message type %q", headers[":message-type"])
+	}
+	switch headers[":event-type"] {
+	case "Records":
+		s.records = payload
+	case "End":
+		return io.EOF
+	} // Stats, Progress and Cont events are ignored
+	return nil
+}
+
+// eventPrelude is the prelude of an event stream message: its total length, the
+// length of its headers, and the CRC of these lengths
+const eventPrelude = 12
+
+// readEventMessage reads a message of an event stream from r, returning the string
+// values of its headers and its payload, once its CRCs are verified. It returns
+// io.EOF if r ends before the message.
+func readEventMessage(r io.Reader) (map[string]string, []byte, error) {
+	prelude := make([]byte, eventPrelude)
+	if _, err := io.ReadFull(r, prelude); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, nil, fmt.Errorf("select: truncated event message: %w", err)
+		}
+		return nil, nil, err
+	}
+	total := binary.BigEndian.Uint32(prelude[0:4])
+	headersLen := binary.BigEndian.Uint32(prelude[4:8])
+	if crc32.ChecksumIEEE(prelude[0:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+		return nil, nil, fmt.Errorf("select: %w: prelude CRC mismatch", ErrIntegrity)
+	}
+	if total < eventPrelude+4 || headersLen > total-eventPrelude-4 {
+		return nil, nil, fmt.Errorf("select: invalid event message lengths: %d, headers %d", total, headersLen)
+	}
+	msg := make([]byte, total)
+	copy(msg, prelude)
+	if _, err := io.ReadFull(r, msg[eventPrelude:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, nil, fmt.Errorf("select: truncated event message: %w", err)
+	}
+	if crc32.ChecksumIEEE(msg[:total-4]) != binary.BigEndian.Uint32(msg[total-4:]) {
+		return nil, nil, fmt.Errorf("select: %w: message CRC mismatch", ErrIntegrity)
+	}
+	headers, err := parseEventHeaders(msg[eventPrelude : eventPrelude+headersLen])
+	if err != nil {
+		return nil, nil, err
+	}
+	return headers, msg[eventPrelude+headersLen : total-4], nil
+}
+
+// eventValueSizes are the sizes of the fixed size header values of event streams, by
+// type: bool true and false, byte, short, integer, long, timestamp and uuid
+var eventValueSizes = map[byte]int{0: 0, 1: 0, 2: 1, 3: 2, 4: 4, 5: 8, 8: 8, 9: 16}
+
+// Types of the variable size header values of event streams, encoded as their
+// length and bytes
+const (
+	eventValueBytes  = 6
+	eventValueString = 7
+)
+
+// parseEventHeaders returns the string headers of an event stream message, skipping
+// the headers of other types.
+func parseEventHeaders(b []byte) (map[string]string, error) {
+	headers := make(map[string]string)
+	invalid := errors.New("select: invalid event message headers")
+	for len(b) > 0 {
+		n := int(b[0])
+		if len(b) < 1+n+1 {
+			return nil, invalid
+		}
+		name, typ := string(b[1:1+n]), b[1+n]
+		b = b[2+n:]
+		if typ == eventValueBytes || typ == eventValueString {
+			if len(b) < 2 {
+				return nil, invalid
+			}
+			n = int(binary.BigEndian.Uint16(b))
+			if len(b) < 2+n {
+				return nil, invalid
+			}
+			if typ == eventValueString {
+				headers[name] = string(b[2 : 2+n])
+			}
+			b = b[2+n:]
+			continue
+		}
+		size, ok := eventValueSizes[typ]
+		if !ok || len(b) < size {
+			return nil, invalid
+		}
+		b = b[size:]
+	}
+	return headers, nil
+}
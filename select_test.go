@@ -0,0 +1,114 @@
+package s3gof3r
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"hash/crc32"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+// eventMessage encodes a message of an event stream with the string headers of kv,
+// name and value pairs, and payload
+func eventMessage(payload string, kv ...string) []byte {
+	var headers bytes.Buffer
+	for i := 0; i+1 < len(kv); i += 2 {
+		headers.WriteByte(byte(len(kv[i])))
+		headers.WriteString(kv[i])
+		headers.WriteByte(eventValueString)
+		binary.Write(&headers, binary.BigEndian, uint16(len(kv[i+1])))
+		headers.WriteString(kv[i+1])
+	}
+	var msg bytes.Buffer
+	binary.Write(&msg, binary.BigEndian, uint32(eventPrelude+headers.Len()+len(payload)+4))
+	binary.Write(&msg, binary.BigEndian, uint32(headers.Len()))
+	binary.Write(&msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	msg.Write(headers.Bytes())
+	msg.WriteString(payload)
+	binary.Write(&msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	return msg.Bytes()
+}
+
+func recordsEvent(payload string) []byte {
+	return eventMessage(payload, ":message-type", "event", ":event-type", "Records", ":content-type", "application/octet-stream")
+}
+
+// corruptEvent flips a byte of the payload of msg
+func corruptEvent(msg []byte) []byte {
+	msg[len(msg)-5]++
+	return msg
+}
+
+var endEvent = eventMessage("", ":message-type", "event", ":event-type", "End")
+
+var selectTests = []struct {
+	stream []byte
+	expect string
+	err    string
+}{
+	{bytes.Join([][]byte{
+		recordsEvent("a,1\n"),
+		eventMessage("<Stats/>", ":message-type", "event", ":event-type", "Stats"),
+		recordsEvent("b,2\n"),
+		endEvent,
+	}, nil), "a,1\nb,2\n", ""},
+	{endEvent, "", ""},
+	{recordsEvent("a,1\n"), "a,1\n", "unexpected EOF"},
+	{recordsEvent("a,1\n")[:20], "", "truncated"},
+	{append(recordsEvent("a,1\n"), eventMessage("", ":message-type", "error", ":error-code", "CSVParsingError", ":error-message", "bad record")...), "a,1\n", "bad record"},
+	{corruptEvent(recordsEvent("a,1\n")), "", "CRC mismatch"},
+}
+
+func TestSelect(t *testing.T) {
+	for _, tt := range selectTests {
+		var req SelectRequest
+		b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			if r.Method != "POST" || r.URL.Path != "/bucket/key.csv" || !q.Has("select") || q.Get("select-type") != "2" {
+				t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			if err := xml.Unmarshal(body, &req); err != nil {
+				t.Error(err)
+			}
+			w.Write(tt.stream)
+		}))
+		r, err := b.Select("key.csv", SelectRequest{
+			Expression:          "SELECT * FROM S3Object",
+			InputSerialization:  SelectInput{CSV: &CSVInput{FileHeaderInfo: "USE"}},
+			OutputSerialization: SelectOutput{CSV: &CSVOutput{}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(r)
+		r.Close()
+		if string(got) != tt.expect {
+			t.Errorf("Expected records: %q. Actual: %q", tt.expect, got)
+		}
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !bytes.Contains([]byte(err.Error()), []byte(tt.err))) {
+			t.Errorf("Expected error: %q. Actual: %v", tt.err, err)
+		}
+		if req.Expression != "SELECT * FROM S3Object" || req.ExpressionType != "SQL" ||
+			req.InputSerialization.CSV == nil || req.InputSerialization.CSV.FileHeaderInfo != "USE" ||
+			req.InputSerialization.JSON != nil || req.OutputSerialization.CSV == nil {
+			t.Errorf("unexpected select request: %+v", req)
+		}
+	}
+
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		w.Write([]byte(`<Error><Code>InvalidExpression</Code><Message>bad expression</Message></Error>`))
+	}))
+	_, err := b.Select("key.csv", SelectRequest{Expression: "SELEC"})
+	var rerr *RespError
+	if !errors.As(err, &rerr) || rerr.Code != "InvalidExpression" {
+		t.Errorf("Expected InvalidExpression error. Actual: %v", err)
+	}
+	if _, err := b.Select("key.csv", SelectRequest{}); err == nil {
+		t.Error("Expected error for empty expression")
+	}
+}
@@ -0,0 +1,91 @@
+package s3gof3r
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const defaultProfile = "default"
+
+// SharedKeys reads the AWS keys for profile from the shared credentials file
+// used by the AWS command line tools and SDKs.
+//
+// The file is read from AWS_SHARED_CREDENTIALS_FILE, defaulting to ~/.aws/credentials.
+// If profile is empty, AWS_PROFILE is used, defaulting to the "default" profile.
+func SharedKeys(profile string) (keys *Keys, err error) {
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	if profile == "" {
+		profile = defaultProfile
+	}
+	path, err := sharedCredentialsPath()
+	if err != nil {
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("shared credentials file: %s", err)
+	}
+	defer checkClose(f, err)
+
+	sections, err := parseIni(f)
+	if err != nil {
+		return nil, fmt.Errorf("shared credentials file %s: %s", path, err)
+	}
+	s, ok := sections[profile]
+	if !ok {
+		return nil, fmt.Errorf("profile %q not found in shared credentials file %s", profile, path)
+	}
+	keys = &Keys{
+		accessKeyID:     s["aws_access_key_id"],
+		secretAccessKey: s["aws_secret_access_key"],
+		sessionToken:    s["aws_session_token"],
+	}
+	if keys.accessKeyID == "" || keys.secretAccessKey == "" {
+		return nil, fmt.Errorf("keys not set in profile %q of %s: aws_access_key_id, aws_secret_access_key", profile, path)
+	}
+	return
+}
+
+func sharedCredentialsPath() (string, error) {
+	if p := os.Getenv("AWS_SHARED_CREDENTIALS_FILE"); p != "" {
+		return p, nil
+	}
+	h, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(h, ".aws", "credentials"), nil
+}
+
+// parseIni parses the sections of an ini file into a map of keys to values for each section.
+func parseIni(r io.Reader) (map[string]map[string]string, error) {
+	sections := make(map[string]map[string]string)
+	var section map[string]string
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+			continue
+		case line[0] == '[' && line[len(line)-1] == ']':
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if sections[name] == nil {
+				sections[name] = make(map[string]string)
+			}
+			section = sections[name]
+		default:
+			kv := strings.SplitN(line, "=", 2)
+			if len(kv) != 2 || section == nil {
+				return nil, fmt.Errorf("line %d: invalid syntax", n)
+			}
+			section[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+	return sections, s.Err()
+}
@@ -0,0 +1,68 @@
+package s3gof3r
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testCredentialsFile = `
+# comment
+[default]
+aws_access_key_id = AKIDDEFAULT
+aws_secret_access_key = defaultsecret
+
+[dev]
+aws_access_key_id=AKIDDEV
+aws_secret_access_key=devsecret
+aws_session_token=devtoken
+
+[empty]
+region = us-west-2
+`
+
+func TestSharedKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	if err := ioutil.WriteFile(path, []byte(testCredentialsFile), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", path)
+
+	var sharedTests = []struct {
+		profile    string
+		envProfile string
+		id         string
+		token      string
+		err        string
+	}{
+		{"", "", "AKIDDEFAULT", "", ""},
+		{"dev", "", "AKIDDEV", "devtoken", ""},
+		{"", "dev", "AKIDDEV", "devtoken", ""},
+		{"default", "dev", "AKIDDEFAULT", "", ""},
+		{"missing", "", "", "", `profile "missing" not found`},
+		{"empty", "", "", "", `keys not set in profile "empty"`},
+	}
+	for _, tt := range sharedTests {
+		t.Setenv("AWS_PROFILE", tt.envProfile)
+		k, err := SharedKeys(tt.profile)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("profile %q: expected error %q, got %v", tt.profile, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("profile %q: %v", tt.profile, err)
+			continue
+		}
+		if k.AccessKeyID() != tt.id || k.SessionToken() != tt.token {
+			t.Errorf("profile %q: unexpected keys %s %s", tt.profile, k.AccessKeyID(), k.SessionToken())
+		}
+	}
+
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "missing"))
+	if _, err := SharedKeys(""); err == nil {
+		t.Error("expected error for missing credentials file")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -14,9 +15,10 @@ import (
 )
 
 const (
-	prefix    = "AWS4-HMAC-SHA256"
-	isoFormat = "20060102T150405Z"
-	shortDate = "20060102"
+	prefix          = "AWS4-HMAC-SHA256"
+	isoFormat       = "20060102T150405Z"
+	shortDate       = "20060102"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
 )
 
 var ignoredHeaders = map[string]bool{
@@ -26,10 +28,32 @@ var ignoredHeaders = map[string]bool{
 	"User-Agent":     true,
 }
 
+// presignIgnoredHeaders are not signed in presigned urls. All other headers of a
+// presigned request, including Content-Type, must be sent by the client.
+var presignIgnoredHeaders = map[string]bool{
+	"Authorization": true,
+	"User-Agent":    true,
+}
+
+// credentialer is implemented by credential sources that provide a consistent
+// snapshot of their keys, such as Keys.
+type credentialer interface {
+	Credentials() (accessKeyID, secretAccessKey, sessionToken string)
+}
+
 type signer struct {
 	Time     time.Time
 	Request  *http.Request
 	S3Config S3ConfigSource
+	Region   string // signing region, the region of S3Config if empty
+	Service  string // signing service, "s3" if empty
+
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	unsignedPayload bool // sign with UNSIGNED-PAYLOAD instead of the body hash
+	streaming       bool // sign the Content-Length of a streaming payload
+	presigned       bool // sign the url query instead of the Authorization header
 
 	credentialString string
 	signedHeaders    string
@@ -41,6 +65,7 @@ type signer struct {
 }
 
 func (s *signer) sign() {
+	s.loadCredentials()
 	s.addSessionToken()
 	s.buildTime()
 	s.buildCredentialString()
@@ -49,16 +74,62 @@ func (s *signer) sign() {
 	s.buildStringToSign()
 	s.buildSignature()
 	parts := []string{
-		prefix + " Credential=" + s.S3Config.AccessKeyID() + "/" + s.credentialString,
+		prefix + " Credential=" + s.accessKeyID + "/" + s.credentialString,
 		"SignedHeaders=" + s.signedHeaders,
 		"Signature=" + s.signature,
 	}
 	s.Request.Header.Set("Authorization", strings.Join(parts, ","))
 }
 
+// loadCredentials reads the keys once so that a request is signed with a
+// consistent set even if they are renewed concurrently.
+func (s *signer) loadCredentials() {
+	s.accessKeyID, s.secretAccessKey, s.sessionToken = credentials(s.S3Config)
+}
+
+// credentials returns the keys of c, as a consistent snapshot if c is a credentialer
+func credentials(c S3ConfigSource) (accessKeyID, secretAccessKey, sessionToken string) {
+	if c, ok := c.(credentialer); ok {
+		return c.Credentials()
+	}
+	return c.AccessKeyID(), c.SecretAccessKey(), c.SessionToken()
+}
+
+// checkCredentials returns ErrNoCredentials if the access key id or secret access
+// key of the bucket is empty, as requests signed with them would be rejected by S3.
+func (b *Bucket) checkCredentials() error {
+	accessKeyID, secretAccessKey, _ := credentials(b.S3)
+	if accessKeyID == "" || secretAccessKey == "" {
+		return ErrNoCredentials
+	}
+	return nil
+}
+
+func (s *signer) service() string {
+	if s.Service != "" {
+		return s.Service
+	}
+	return "s3"
+}
+
+func (s *signer) region() string {
+	if s.Region != "" {
+		return s.Region
+	}
+	return s.S3Config.Region()
+}
+
+// host returns the Host header sent for the request, including any port
+func (s *signer) host() string {
+	if s.Request.Host != "" {
+		return s.Request.Host
+	}
+	return s.Request.URL.Host
+}
+
 func (s *signer) addSessionToken() {
-	if sessionToken := s.S3Config.SessionToken(); sessionToken != "" {
-		s.Request.Header.Set("X-Amz-Security-Token", sessionToken)
+	if s.sessionToken != "" {
+		s.Request.Header.Set("X-Amz-Security-Token", s.sessionToken)
 	}
 
 }
@@ -70,8 +141,8 @@ func (s *signer) buildTime() {
 func (s *signer) buildCredentialString() {
 	s.credentialString = strings.Join([]string{
 		s.Time.UTC().Format(shortDate),
-		s.S3Config.Region(),
-		"s3",
+		s.region(),
+		s.service(),
 		"aws4_request",
 	}, "/")
 }
@@ -79,8 +150,12 @@ func (s *signer) buildCredentialString() {
 func (s *signer) buildCanonicalHeaders() {
 	var headers []string
 	headers = append(headers, "host")
+	ignored := ignoredHeaders
+	if s.presigned {
+		ignored = presignIgnoredHeaders
+	}
 	for k := range s.Request.Header {
-		if _, ok := ignoredHeaders[http.CanonicalHeaderKey(k)]; ok {
+		if _, ok := ignored[http.CanonicalHeaderKey(k)]; ok && !(s.streaming && http.CanonicalHeaderKey(k) == "Content-Length") {
 			continue
 		}
 		headers = append(headers, strings.ToLower(k))
@@ -92,7 +167,7 @@ func (s *signer) buildCanonicalHeaders() {
 	headerValues := make([]string, len(headers))
 	for i, k := range headers {
 		if k == "host" {
-			headerValues[i] = "host:" + s.Request.URL.Host
+			headerValues[i] = "host:" + s.host()
 		} else {
 			headerValues[i] = k + ":" +
 				strings.Join(s.Request.Header[http.CanonicalHeaderKey(k)], ",")
@@ -108,15 +183,12 @@ func (s *signer) buildCanonicalString() {
 	if uri != "" {
 		uri = "/" + strings.Join(strings.Split(uri, "/")[3:], "/")
 	} else {
-		uri = s.Request.URL.EscapedPath()
+		uri = escapePath(s.Request.URL.Path)
 	}
 	if uri == "" {
 		uri = "/"
 	}
 
-	uri = strings.Replace(uri, "@", "%40", -1)
-	uri = strings.Replace(uri, ":", "%3A", -1)
-
 	s.canonicalString = strings.Join([]string{
 		s.Request.Method,
 		uri,
@@ -137,16 +209,22 @@ func (s *signer) buildStringToSign() {
 }
 
 func (s *signer) buildSignature() {
-	secret := s.S3Config.SecretAccessKey()
-	date := hmacSign([]byte("AWS4"+secret), []byte(s.Time.UTC().Format(shortDate)))
-	region := hmacSign(date, []byte(s.S3Config.Region()))
-	service := hmacSign(region, []byte("s3"))
-	credentials := hmacSign(service, []byte("aws4_request"))
-	signature := hmacSign(credentials, []byte(s.stringToSign))
+	signature := hmacSign(s.signingKey(), []byte(s.stringToSign))
 	s.signature = hex.EncodeToString(signature)
 }
 
+// signingKey returns the key derived from the secret key for the date, region and service
+func (s *signer) signingKey() []byte {
+	date := hmacSign([]byte("AWS4"+s.secretAccessKey), []byte(s.Time.UTC().Format(shortDate)))
+	region := hmacSign(date, []byte(s.region()))
+	service := hmacSign(region, []byte(s.service()))
+	return hmacSign(service, []byte("aws4_request"))
+}
+
 func (s *signer) bodyDigest() string {
+	if s.unsignedPayload {
+		return unsignedPayload
+	}
 	hash := s.Request.Header.Get("X-Amz-Content-Sha256")
 	if hash == "" {
 		if s.Request.Body == nil {
@@ -161,6 +239,23 @@ func (s *signer) bodyDigest() string {
 	return hash
 }
 
+// escapePath encodes the path p as S3 does for the canonical URI of a request,
+// encoding each byte other than '/' and the unreserved characters of RFC 3986.
+// The path of a request may be sent with this encoding by setting it as the RawPath.
+func escapePath(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '.' || c == '_' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
 func hmacSign(key []byte, data []byte) []byte {
 	hash := hmac.New(sha256.New, key)
 	hash.Write(data)
@@ -0,0 +1,458 @@
+package s3gof3r
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sigV4Verifier serves requests with h once their AWS Signature Version 4 is verified,
+// for the keys, region and service ("s3" if empty) given, written independently of the signer following
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html.
+// Requests with an invalid signature get a 403 SignatureDoesNotMatch error.
+type sigV4Verifier struct {
+	h       http.Handler
+	keys    *Keys
+	region  string
+	service string
+}
+
+func (v *sigV4Verifier) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := v.verify(r); err != nil {
+		w.WriteHeader(403)
+		fmt.Fprintf(w, `<Error><Code>SignatureDoesNotMatch</Code><Message>%s</Message></Error>`, err)
+		return
+	}
+	v.h.ServeHTTP(w, r)
+}
+
+func (v *sigV4Verifier) verify(r *http.Request) error {
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+	fields := make(map[string]string)
+	for _, f := range strings.Split(auth, ",") {
+		if kv := strings.SplitN(strings.TrimSpace(f), "=", 2); len(kv) == 2 {
+			fields[kv[0]] = kv[1]
+		}
+	}
+	service := v.service
+	if service == "" {
+		service = "s3"
+	}
+	scope := strings.Split(fields["Credential"], "/")
+	if len(scope) != 5 || scope[0] != v.keys.accessKeyID || scope[2] != v.region || scope[3] != service || scope[4] != "aws4_request" {
+		return fmt.Errorf("invalid credential %q", fields["Credential"])
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body = ioutil.NopCloser(strings.NewReader(string(body)))
+	payload := r.Header.Get("X-Amz-Content-Sha256")
+	streaming := payload == "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	if sum := sha256.Sum256(body); payload != hex.EncodeToString(sum[:]) && payload != "UNSIGNED-PAYLOAD" && !streaming {
+		return fmt.Errorf("invalid payload hash %q", payload)
+	}
+
+	signed := strings.Split(fields["SignedHeaders"], ";")
+	var headers []string
+	for _, k := range signed {
+		val := strings.Join(r.Header.Values(k), ",")
+		if k == "host" {
+			val = r.Host
+		}
+		headers = append(headers, k+":"+strings.TrimSpace(val)+"\n")
+	}
+	var query []string
+	for k, vs := range r.URL.Query() {
+		for _, val := range vs {
+			query = append(query, uriEncode(k, true)+"="+uriEncode(val, true))
+		}
+	}
+	sort.Strings(query)
+	canonical := strings.Join([]string{
+		r.Method,
+		uriEncode(r.URL.Path, false),
+		strings.Join(query, "&"),
+		strings.Join(headers, ""),
+		fields["SignedHeaders"],
+		payload,
+	}, "\n")
+
+	date := r.Header.Get("X-Amz-Date")
+	sum := sha256.Sum256([]byte(canonical))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", date, strings.Join(scope[1:], "/"), hex.EncodeToString(sum[:])}, "\n")
+	key := []byte("AWS4" + v.keys.secretAccessKey)
+	for _, s := range scope[1:] {
+		key = hmacSHA256(key, s)
+	}
+	sig := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	if sig != fields["Signature"] {
+		return fmt.Errorf("expected signature %s of canonical request %q", sig, canonical)
+	}
+	if !streaming {
+		return nil
+	}
+	data, err := decodeChunks(body, key, date, strings.Join(scope[1:], "/"), sig)
+	if err != nil {
+		return err
+	}
+	if d := r.Header.Get("X-Amz-Decoded-Content-Length"); d != fmt.Sprint(len(data)) {
+		return fmt.Errorf("decoded content length %s of %d bytes", d, len(data))
+	}
+	r.Body = ioutil.NopCloser(strings.NewReader(string(data)))
+	r.ContentLength = int64(len(data))
+	r.Header.Set("Content-Length", fmt.Sprint(len(data)))
+	if ce := strings.TrimPrefix(strings.TrimPrefix(r.Header.Get("Content-Encoding"), "aws-chunked"), ","); ce != "" {
+		r.Header.Set("Content-Encoding", ce)
+	} else {
+		r.Header.Del("Content-Encoding")
+	}
+	return nil
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
+
+// decodeChunks decodes body in the aws-chunked encoding, verifying the signature of
+// each chunk chained from the seed signature of the request
+func decodeChunks(body, key []byte, date, scope, seed string) ([]byte, error) {
+	var data []byte
+	prev := seed
+	for {
+		i := strings.Index(string(body), "\r\n")
+		if i < 0 {
+			return nil, fmt.Errorf("truncated chunk header")
+		}
+		var n int
+		var chunkSig string
+		if _, err := fmt.Sscanf(string(body[:i]), "%x;chunk-signature=%s", &n, &chunkSig); err != nil {
+			return nil, fmt.Errorf("invalid chunk header %q", body[:i])
+		}
+		body = body[i+2:]
+		if len(body) < n+2 || string(body[n:n+2]) != "\r\n" {
+			return nil, fmt.Errorf("truncated chunk of %d bytes", n)
+		}
+		chunk := body[:n]
+		body = body[n+2:]
+		empty, sum := sha256.Sum256(nil), sha256.Sum256(chunk)
+		stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256-PAYLOAD", date, scope, prev, hex.EncodeToString(empty[:]), hex.EncodeToString(sum[:])}, "\n")
+		prev = hex.EncodeToString(hmacSHA256(key, stringToSign))
+		if prev != chunkSig {
+			return nil, fmt.Errorf("expected signature %s of chunk of %d bytes", prev, n)
+		}
+		data = append(data, chunk...)
+		if n == 0 {
+			if len(body) != 0 {
+				return nil, fmt.Errorf("%d bytes after the final chunk", len(body))
+			}
+			return data, nil
+		}
+	}
+}
+
+// uriEncode encodes s as specified for the canonical requests of SigV4, encoding '/' only if slash
+func uriEncode(s string, slash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', strings.IndexByte("-._~", c) >= 0:
+			b.WriteByte(c)
+		case c == '/' && !slash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// TestPathStyleSignature puts, gets, lists and deletes objects of a path style bucket of an
+// S3-compatible service at a custom domain, checking their signatures for the explicit region.
+func TestPathStyleSignature(t *testing.T) {
+	t.Setenv("AWS_REGION", "us-west-2") // not used for an explicit region
+	keys := &Keys{
+		accessKeyID:     "AKIDEXAMPLE",
+		secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	s := newFakeS3()
+	srv := httptest.NewServer(&sigV4Verifier{h: s, keys: keys, region: "minio-local"})
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	b, err := NewBucket(NewWithRegion(u.Host, "minio-local", keys), "bucket", &Config{
+		Concurrency: 2,
+		PartSize:    5 * mb,
+		NTry:        1,
+		Scheme:      "http",
+		PathStyle:   true,
+		Md5Check:    true,
+		Client:      ClientWithTimeout(5 * time.Second),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyNames := []string{"plain", "dir/a b+c=d&e.txt", "dir/semi;colon:at@,x!$'()*"}
+	for _, key := range keyNames {
+		w, err := b.PutWriter(key, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(key)); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("put %s: %v", key, err)
+		}
+		r, _, err := b.GetReader(key)
+		if err != nil {
+			t.Fatalf("get %s: %v", key, err)
+		}
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Close(); err != nil {
+			t.Fatalf("get %s: %v", key, err)
+		}
+		if string(data) != key {
+			t.Errorf("Expected data: %s. Actual: %s", key, data)
+		}
+	}
+	l, err := b.ListObjects([]string{"dir/"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listed []string
+	for l.Next() {
+		listed = append(listed, l.Value()...)
+	}
+	if err := l.Error(); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(listed) != 2 {
+		t.Errorf("Expected 2 keys listed. Actual: %v", listed)
+	}
+	for _, key := range keyNames {
+		if err := b.Delete(key); err != nil {
+			t.Fatalf("delete %s: %v", key, err)
+		}
+	}
+	if len(s.objects) != 0 {
+		t.Errorf("Expected all objects deleted. Actual: %d remaining", len(s.objects))
+	}
+}
+
+// TestSigningRegionService puts and gets an object of a bucket with a signing region
+// and service of its config, as expected by a gateway, instead of the bucket's region.
+func TestSigningRegionService(t *testing.T) {
+	keys := &Keys{
+		accessKeyID:     "AKIDEXAMPLE",
+		secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	srv := httptest.NewServer(&sigV4Verifier{h: newFakeS3(), keys: keys, region: "us-east-1", service: "storage"})
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	b, err := NewBucket(NewWithRegion(u.Host, "eu-west-3", keys), "bucket", &Config{
+		Concurrency:    2,
+		PartSize:       5 * mb,
+		NTry:           1,
+		Scheme:         "http",
+		PathStyle:      true,
+		Client:         ClientWithTimeout(5 * time.Second),
+		SigningRegion:  "us-east-1",
+		SigningService: "storage",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := b.PutWriter("key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	r, _, err := b.GetReader("key")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if data, err := ioutil.ReadAll(r); err != nil || string(data) != "data" {
+		t.Errorf("Expected data: data. Actual: %q %v", data, err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := b.PresignGet("key", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pu, _ := url.Parse(p)
+	if c := pu.Query().Get("X-Amz-Credential"); !strings.Contains(c, "/us-east-1/storage/aws4_request") {
+		t.Errorf("Expected presigned credential for us-east-1 and storage. Actual: %s", c)
+	}
+}
+
+// newTLSBucket returns a path style bucket of a service serving h over HTTPS, signing
+// the payload of requests if signed
+func newTLSBucket(t testing.TB, h http.Handler, signed bool) *Bucket {
+	t.Helper()
+	srv := httptest.NewTLSServer(h)
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	tlsConfig := srv.Client().Transport.(*http.Transport).TLSClientConfig
+	b, err := NewBucket(NewWithRegion(u.Host, "us-east-1", testKeys), "bucket", &Config{
+		Concurrency:     2,
+		PartSize:        5 * mb,
+		NTry:            1,
+		Scheme:          "https",
+		PathStyle:       true,
+		Md5Check:        true,
+		Client:          ClientWithTransport(&http.Transport{TLSClientConfig: tlsConfig}, 5*time.Second),
+		UnsignedPayload: !signed,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+var testKeys = &Keys{
+	accessKeyID:     "AKIDEXAMPLE",
+	secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+}
+
+// TestUnsignedPayload puts and gets objects over HTTPS with signed and unsigned payloads
+func TestUnsignedPayload(t *testing.T) {
+	for _, signed := range []bool{true, false} {
+		var mu sync.Mutex
+		payloads := make(map[string]bool)
+		s := newFakeS3()
+		b := newTLSBucket(t, &sigV4Verifier{h: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			payloads[r.Header.Get("X-Amz-Content-Sha256")] = true
+			mu.Unlock()
+			s.ServeHTTP(w, r)
+		}), keys: testKeys, region: "us-east-1"}, signed)
+		data := bytes.Repeat([]byte("abcdefgh"), 12<<20/8)
+		if err := b.PutBytes("key", data, nil); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+		got, _, err := b.GetBytes("key")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("Expected %d bytes. Actual: %d", len(data), len(got))
+		}
+		if signed && payloads["UNSIGNED-PAYLOAD"] || !signed && (len(payloads) != 1 || !payloads["UNSIGNED-PAYLOAD"]) {
+			t.Errorf("Expected unsigned payloads: %v. Actual: %v", !signed, payloads)
+		}
+	}
+}
+
+// BenchmarkUnsignedPayload uploads objects of 8 parts over HTTPS with signed and
+// unsigned payloads, reporting the CPU time saved by not hashing the parts.
+func BenchmarkUnsignedPayload(b *testing.B) {
+	for _, signed := range []bool{true, false} {
+		b.Run(fmt.Sprintf("signed-%v", signed), func(b *testing.B) {
+			bucket := newTLSBucket(b, discardS3, signed)
+			data := bytes.Repeat([]byte("a"), int(bucket.Config.PartSize))
+			b.SetBytes(8 * int64(len(data)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				w, err := bucket.PutWriter("key", nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for j := 0; j < 8; j++ {
+					if _, err := w.Write(data); err != nil {
+						b.Fatal(err)
+					}
+				}
+				if err := w.Close(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestEndpoint puts, gets, lists and deletes objects of a bucket at an endpoint with a
+// path prefix, checking their signatures for the host and path of the endpoint.
+func TestEndpoint(t *testing.T) {
+	s := newFakeS3()
+	var mu sync.Mutex
+	var paths []string
+	srv := httptest.NewServer(&sigV4Verifier{h: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		http.StripPrefix("/gateway", s).ServeHTTP(w, r)
+	}), keys: testKeys, region: "us-east-1"})
+	defer srv.Close()
+	b, err := NewBucket(NewWithRegion("s3.amazonaws.com", "us-east-1", testKeys), "bucket", &Config{
+		Concurrency: 2,
+		PartSize:    5 * mb,
+		NTry:        1,
+		Scheme:      "https", // of the endpoint instead
+		Md5Check:    true,
+		Client:      ClientWithTimeout(5 * time.Second),
+		Endpoint:    srv.URL + "/gateway/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("abcdefgh"), 6<<20/8)
+	if err := b.PutBytes("dir/key", data, nil); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	got, _, err := b.GetBytes("dir/key")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("Expected %d bytes. Actual: %d", len(data), len(got))
+	}
+	if _, ok := s.objects["/bucket/.md5/dir/key.md5"]; !ok {
+		t.Error("Expected md5 file of the object")
+	}
+	l, err := b.ListObjects([]string{"dir/"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listed []string
+	for l.Next() {
+		listed = append(listed, l.Value()...)
+	}
+	if err := l.Error(); err != nil || len(listed) != 1 || listed[0] != "dir/key" {
+		t.Errorf("Expected dir/key listed. Actual: %v %v", listed, err)
+	}
+	if err := b.Delete("dir/key"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	for _, p := range paths {
+		if p != "/gateway/bucket" && !strings.HasPrefix(p, "/gateway/bucket/") {
+			t.Errorf("Expected path under the endpoint. Actual: %s", p)
+		}
+	}
+
+	b.Config.Endpoint = "gateway.example.com"
+	if _, err := b.PutWriter("key", nil); err == nil || !strings.Contains(err.Error(), "invalid endpoint") {
+		t.Errorf("Expected invalid endpoint error. Actual: %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package s3gof3r
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// headers of server-side encryption with customer-provided keys (SSE-C)
+// See https://docs.aws.amazon.com/AmazonS3/latest/userguide/ServerSideEncryptionCustomerKeys.html
+const (
+	sseCustomerAlgorithmHeader = "X-Amz-Server-Side-Encryption-Customer-Algorithm"
+	sseCustomerKeyHeader       = "X-Amz-Server-Side-Encryption-Customer-Key"
+	sseCustomerKeyMD5Header    = "X-Amz-Server-Side-Encryption-Customer-Key-Md5"
+	sseCustomerAlgorithm       = "AES256"
+	sseCustomerKeySize         = 32
+)
+
+// sseCustomerHeader returns the SSE-C headers for key, or the SSE-C headers
+// in h if key is empty. The headers must be sent with every request for the
+// data of an SSE-C object, including each part upload and ranged get.
+func sseCustomerHeader(key []byte, h http.Header) (http.Header, error) {
+	sh := make(http.Header)
+	if len(key) == 0 {
+		for _, k := range []string{sseCustomerAlgorithmHeader, sseCustomerKeyHeader, sseCustomerKeyMD5Header} {
+			if v := h.Get(k); v != "" {
+				sh.Set(k, v)
+			}
+		}
+		return sh, nil
+	}
+	if len(key) != sseCustomerKeySize {
+		return nil, fmt.Errorf("SSE-C key must be %d bytes, got %d", sseCustomerKeySize, len(key))
+	}
+	sum := md5.Sum(key)
+	sh.Set(sseCustomerAlgorithmHeader, sseCustomerAlgorithm)
+	sh.Set(sseCustomerKeyHeader, base64.StdEncoding.EncodeToString(key))
+	sh.Set(sseCustomerKeyMD5Header, base64.StdEncoding.EncodeToString(sum[:]))
+	return sh, nil
+}
+
+// copySourceSSECustomerHeader returns the x-amz-copy-source-server-side-encryption-customer-*
+// headers of the SSE-C key of the source of copies, if any.
+func copySourceSSECustomerHeader(key []byte) (http.Header, error) {
+	sh, err := sseCustomerHeader(key, nil)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(http.Header)
+	for k, v := range sh {
+		ch["X-Amz-Copy-Source-"+strings.TrimPrefix(k, "X-Amz-")] = v
+	}
+	return ch, nil
+}
+
+// headers of server-side encryption with keys managed by S3 (SSE-S3) or KMS (SSE-KMS)
+// See https://docs.aws.amazon.com/AmazonS3/latest/userguide/UsingKMSEncryption.html
+const (
+	sseHeader                 = "X-Amz-Server-Side-Encryption"
+	sseKMSKeyIDHeader         = "X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"
+	sseBucketKeyEnabledHeader = "X-Amz-Server-Side-Encryption-Bucket-Key-Enabled"
+)
+
+// Algorithms of server-side encryption
+const (
+	SSEAlgorithmAES256  = "AES256"       // SSE-S3, with keys managed by S3
+	SSEAlgorithmKMS     = "aws:kms"      // SSE-KMS, with a KMS key
+	SSEAlgorithmKMSDSSE = "aws:kms:dsse" // DSSE-KMS, dual-layer encryption with a KMS key
+)
+
+var sseAlgorithms = map[string]bool{
+	SSEAlgorithmAES256:  true,
+	SSEAlgorithmKMS:     true,
+	SSEAlgorithmKMSDSSE: true,
+}
+
+// setServerSideEncryption sets the server-side encryption headers of h from c, unless
+// h already has them, and validates the resulting headers: the KMS key ID and bucket
+// key are only valid with a KMS algorithm, which can't be combined with SSE-C.
+func setServerSideEncryption(h http.Header, c *Config) error {
+	if h.Get(sseHeader) == "" && c.SSEAlgorithm != "" {
+		h.Set(sseHeader, c.SSEAlgorithm)
+		if c.SSEKMSKeyID != "" && h.Get(sseKMSKeyIDHeader) == "" {
+			h.Set(sseKMSKeyIDHeader, c.SSEKMSKeyID)
+		}
+		if c.SSEBucketKeyEnabled && h.Get(sseBucketKeyEnabledHeader) == "" {
+			h.Set(sseBucketKeyEnabledHeader, "true")
+		}
+	}
+	alg := h.Get(sseHeader)
+	if alg != "" && !sseAlgorithms[alg] {
+		return fmt.Errorf("invalid server-side encryption algorithm: %q", alg)
+	}
+	if !isKMS(alg) {
+		if h.Get(sseKMSKeyIDHeader) != "" || c.SSEKMSKeyID != "" {
+			return fmt.Errorf("KMS key ID is only valid with server-side encryption %s, not %q", SSEAlgorithmKMS, alg)
+		}
+		if h.Get(sseBucketKeyEnabledHeader) != "" || c.SSEBucketKeyEnabled {
+			return fmt.Errorf("bucket key is only valid with server-side encryption %s, not %q", SSEAlgorithmKMS, alg)
+		}
+	}
+	if alg != "" && h.Get(sseCustomerAlgorithmHeader) != "" {
+		return fmt.Errorf("server-side encryption %s can't be combined with SSE-C", alg)
+	}
+	return nil
+}
+
+// isKMS reports whether alg is a server-side encryption algorithm with KMS keys
+func isKMS(alg string) bool {
+	return strings.HasPrefix(alg, SSEAlgorithmKMS)
+}
+
+// setHeaders sets each header of src in dst, replacing any existing values.
+func setHeaders(dst, src http.Header) {
+	for k, v := range src {
+		dst[k] = v
+	}
+}
@@ -0,0 +1,162 @@
+package s3gof3r
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSSECustomerKey(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), 32)
+	want, err := sseCustomerHeader(key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var missing []string
+	s := newFakeS3()
+	s.check = func(r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/bucket/.md5/") ||
+			(r.Method == "POST" && r.URL.Query().Get("uploadId") != "") ||
+			r.Method == "DELETE" {
+			return // the md5 file and completion are not encrypted
+		}
+		for k := range want {
+			if r.Header.Get(k) != want.Get(k) {
+				mu.Lock()
+				missing = append(missing, r.Method+" "+r.URL.String()+" "+k)
+				mu.Unlock()
+			}
+		}
+	}
+	b := newTestBucket(t, s)
+	b.Config.SSECustomerKey = key
+	b.Config.Md5Check = true
+
+	data := bytes.Repeat([]byte("abcdefgh"), int(12*mb/8))
+	w, err := b.PutWriter("key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, _, err := b.GetReader("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("got data differs from put data")
+	}
+	for _, m := range missing {
+		t.Errorf("SSE-C header missing from %s", m)
+	}
+
+	b.Config.SSECustomerKey = []byte("short")
+	if _, err := b.PutWriter("key", nil); err == nil {
+		t.Error("expected error for invalid SSE-C key size")
+	}
+}
+
+var sseTests = []struct {
+	algorithm, keyID string
+	bucketKey        bool
+	header           http.Header
+	expect           http.Header
+	valid            bool
+}{
+	{"", "", false, nil, http.Header{}, true},
+	{SSEAlgorithmAES256, "", false, nil, http.Header{"X-Amz-Server-Side-Encryption": {"AES256"}}, true},
+	{SSEAlgorithmKMS, "key-id", true, nil, http.Header{
+		"X-Amz-Server-Side-Encryption":                    {"aws:kms"},
+		"X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id":     {"key-id"},
+		"X-Amz-Server-Side-Encryption-Bucket-Key-Enabled": {"true"},
+	}, true},
+	{SSEAlgorithmKMS, "key-id", false, http.Header{"X-Amz-Server-Side-Encryption": {"aws:kms:dsse"}, "X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id": {"other"}},
+		http.Header{"X-Amz-Server-Side-Encryption": {"aws:kms:dsse"}, "X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id": {"other"}}, true},
+	{"aws:kms2", "", false, nil, nil, false},
+	{SSEAlgorithmAES256, "key-id", false, nil, nil, false},
+	{"", "", true, nil, nil, false},
+	{"", "", false, http.Header{"X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id": {"key-id"}}, nil, false},
+	{SSEAlgorithmKMS, "", false, http.Header{sseCustomerAlgorithmHeader: {"AES256"}}, nil, false},
+}
+
+func TestServerSideEncryption(t *testing.T) {
+	s := newFakeS3()
+	b := newTestBucket(t, s)
+	for _, tt := range sseTests {
+		b.Config.SSEAlgorithm, b.Config.SSEKMSKeyID, b.Config.SSEBucketKeyEnabled = tt.algorithm, tt.keyID, tt.bucketKey
+		w, err := b.PutWriter("key", tt.header)
+		if !tt.valid {
+			if err == nil {
+				t.Errorf("expected error for %q %q %v %v", tt.algorithm, tt.keyID, tt.bucketKey, tt.header)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		s.mu.Lock()
+		h := s.headers["/bucket/key"]
+		s.mu.Unlock()
+		for _, k := range []string{sseHeader, sseKMSKeyIDHeader, sseBucketKeyEnabledHeader} {
+			if h.Get(k) != tt.expect.Get(k) {
+				t.Errorf("Expected %s: %q. Actual: %q", k, tt.expect.Get(k), h.Get(k))
+			}
+		}
+	}
+}
+
+// TestSSEKMSMultipart puts an SSE-KMS object whose ETags, as with S3, are not md5s.
+func TestSSEKMSMultipart(t *testing.T) {
+	s := newFakeS3()
+	var initiated http.Header
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" && r.URL.Query().Has("uploads") {
+			initiated = r.Header.Clone()
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, r)
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		if etag := w.Header().Get("ETag"); etag != "" {
+			w.Header().Set("ETag", `"kms-`+strings.Trim(etag, `"`)+`"`)
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(regexp.MustCompile(`<ETag>"?([0-9a-f]+)`).ReplaceAll(rec.Body.Bytes(), []byte(`<ETag>"0123$1`)))
+	}))
+	b.Config.SSEAlgorithm, b.Config.SSEKMSKeyID = SSEAlgorithmKMS, "key-id"
+	w, err := b.PutWriter("key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(bytes.Repeat([]byte("a"), int(6*mb))); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if initiated.Get(sseHeader) != "aws:kms" || initiated.Get(sseKMSKeyIDHeader) != "key-id" {
+		t.Errorf("Expected KMS headers on initiation. Actual: %v", initiated)
+	}
+}
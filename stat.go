@@ -0,0 +1,66 @@
+package s3gof3r
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const metaPrefix = "X-Amz-Meta-"
+
+// ObjectInfo is the metadata of an S3 object.
+type ObjectInfo struct {
+	Size         int64
+	ETag         string // without surrounding quotes
+	LastModified time.Time
+	ContentType  string
+	StorageClass string
+	VersionID    string
+	Restore      string            // the x-amz-restore header of archived objects restored, if any
+	Metadata     map[string]string // user metadata, keyed by lower-case name without the x-amz-meta- prefix
+}
+
+// Stat returns the metadata of the object at path without downloading it.
+//
+// Errors are as for Head.
+func (b *Bucket) Stat(path string) (*ObjectInfo, error) {
+	h, err := b.Head(path)
+	if err != nil {
+		return nil, err
+	}
+	return newObjectInfo(h)
+}
+
+// newObjectInfo parses the object metadata in the response headers h
+func newObjectInfo(h http.Header) (*ObjectInfo, error) {
+	info := &ObjectInfo{
+		ETag:         strings.Trim(h.Get("ETag"), `"`),
+		ContentType:  h.Get("Content-Type"),
+		StorageClass: h.Get("x-amz-storage-class"),
+		VersionID:    h.Get("x-amz-version-id"),
+		Restore:      h.Get("x-amz-restore"),
+		Metadata:     make(map[string]string),
+	}
+	if info.StorageClass == "" {
+		info.StorageClass = "STANDARD" // not returned for standard storage
+	}
+	var err error
+	if cl := h.Get("Content-Length"); cl != "" {
+		if info.Size, err = strconv.ParseInt(cl, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid content-length: %s", err)
+		}
+	}
+	if lm := h.Get("Last-Modified"); lm != "" {
+		if info.LastModified, err = http.ParseTime(lm); err != nil {
+			return nil, fmt.Errorf("invalid last-modified: %s", err)
+		}
+	}
+	for k := range h {
+		if strings.HasPrefix(k, metaPrefix) {
+			info.Metadata[strings.ToLower(k[len(metaPrefix):])] = h.Get(k)
+		}
+	}
+	return info, nil
+}
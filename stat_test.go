@@ -0,0 +1,41 @@
+package s3gof3r
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStat(t *testing.T) {
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1234")
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("ETag", `"abc"`)
+		w.Header().Set("Last-Modified", "Wed, 12 Oct 2009 17:50:00 GMT")
+		w.Header().Set("x-amz-storage-class", "STANDARD_IA")
+		w.Header().Set("x-amz-version-id", "v1")
+		w.Header().Set("x-amz-restore", `ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`)
+		w.Header().Set("x-amz-meta-Foo-Bar", "baz")
+	}))
+	info, err := b.Stat("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := &ObjectInfo{
+		Size:         1234,
+		ETag:         "abc",
+		LastModified: time.Date(2009, 10, 12, 17, 50, 0, 0, time.UTC),
+		ContentType:  "text/plain",
+		StorageClass: "STANDARD_IA",
+		VersionID:    "v1",
+		Restore:      `ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`,
+		Metadata:     map[string]string{"foo-bar": "baz"},
+	}
+	if !reflect.DeepEqual(info, expect) {
+		t.Errorf("expected %+v, got %+v", expect, info)
+	}
+	if !info.Restored() {
+		t.Error("expected restored object")
+	}
+}
@@ -0,0 +1,98 @@
+package s3gof3r
+
+import (
+	"sync"
+	"time"
+)
+
+// Stats of a get or put. The readers returned by GetReader and the writers returned
+// by PutWriter have a Stats method returning them, for metrics of transfers:
+//
+//	if s, ok := w.(interface{ Stats() s3gof3r.Stats }); ok {
+//		bytesTotal.Add(float64(s.Stats().Bytes))
+//	}
+type Stats struct {
+	Bytes           int64         // bytes of the parts transferred
+	Parts           int           // parts transferred
+	Retries         int           // retries of part requests
+	PartRetries     map[int]int   // retries of each retried part, by part number (from 1)
+	Duration        time.Duration // since the get or put started, until Close
+	PeakConcurrency int           // most part requests in flight at once
+}
+
+// stats accumulates the Stats of a get or put
+type stats struct {
+	mu         sync.Mutex
+	start, end time.Time
+	bytes      int64
+	parts      int
+	retries    map[int]int // by part number
+	active     int         // part requests in flight
+	peak       int
+}
+
+func newStats() *stats {
+	return &stats{start: time.Now(), retries: make(map[int]int)}
+}
+
+// begin records the start of a part request
+func (s *stats) begin() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active++
+	s.peak = max(s.peak, s.active)
+}
+
+// done records the end of a part request
+func (s *stats) done() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active--
+}
+
+// part records a part of n bytes transferred
+func (s *stats) part(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.parts++
+	s.bytes += n
+}
+
+// retry records a retry of part number n
+func (s *stats) retry(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retries[n]++
+}
+
+// close records the end of the transfer, once
+func (s *stats) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.end.IsZero() {
+		s.end = time.Now()
+	}
+}
+
+func (s *stats) stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	end := s.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+	var total int
+	retries := make(map[int]int, len(s.retries))
+	for n, r := range s.retries {
+		retries[n] = r
+		total += r
+	}
+	return Stats{
+		Bytes:           s.bytes,
+		Parts:           s.parts,
+		Retries:         total,
+		PartRetries:     retries,
+		Duration:        end.Sub(s.start),
+		PeakConcurrency: s.peak,
+	}
+}
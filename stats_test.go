@@ -0,0 +1,68 @@
+package s3gof3r
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+type statser interface {
+	Stats() Stats
+}
+
+func TestStats(t *testing.T) {
+	s := newFakeS3()
+	var failed int32
+	s.check = func(r *http.Request) {
+		// fail the first attempt of the second part
+		if r.URL.Query().Get("partNumber") == "2" && atomic.CompareAndSwapInt32(&failed, 0, 1) {
+			panic(http.ErrAbortHandler)
+		}
+	}
+	b := newTestBucket(t, s)
+	b.Config.Md5Check = false
+
+	w, err := b.PutWriter("key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(make([]byte, 11*mb)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	ps := w.(statser).Stats()
+	if ps.Bytes != 11*mb || ps.Parts != 3 {
+		t.Errorf("Expected 3 parts of %d bytes. Actual: %d parts of %d bytes", 11*mb, ps.Parts, ps.Bytes)
+	}
+	if ps.Retries != 1 || ps.PartRetries[2] != 1 {
+		t.Errorf("Expected 1 retry of part 2. Actual: %d retries, %v", ps.Retries, ps.PartRetries)
+	}
+	if ps.PeakConcurrency < 1 || ps.PeakConcurrency > b.Config.Concurrency {
+		t.Errorf("Expected peak concurrency between 1 and %d. Actual: %d", b.Config.Concurrency, ps.PeakConcurrency)
+	}
+	if ps.Duration <= 0 {
+		t.Errorf("Expected positive duration. Actual: %v", ps.Duration)
+	}
+	if d := w.(statser).Stats().Duration; d != ps.Duration {
+		t.Errorf("Expected duration to stop at Close: %v. Actual: %v", ps.Duration, d)
+	}
+
+	r, _, err := b.GetReader("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	gs := r.(statser).Stats()
+	if gs.Bytes != 11*mb || gs.Parts != 3 || gs.Retries != 0 {
+		t.Errorf("Expected 3 parts of %d bytes without retries. Actual: %+v", 11*mb, gs)
+	}
+}
@@ -0,0 +1,44 @@
+package s3gof3r
+
+import (
+	"fmt"
+	"net/http"
+)
+
+const storageClassHeader = "X-Amz-Storage-Class"
+
+// Storage classes of objects
+// See https://docs.aws.amazon.com/AmazonS3/latest/userguide/storage-class-intro.html
+const (
+	StorageClassStandard           = "STANDARD"
+	StorageClassReducedRedundancy  = "REDUCED_REDUNDANCY"
+	StorageClassStandardIA         = "STANDARD_IA"
+	StorageClassOneZoneIA          = "ONEZONE_IA"
+	StorageClassIntelligentTiering = "INTELLIGENT_TIERING"
+	StorageClassGlacier            = "GLACIER"
+	StorageClassGlacierIR          = "GLACIER_IR"
+	StorageClassDeepArchive        = "DEEP_ARCHIVE"
+)
+
+var storageClasses = map[string]bool{
+	StorageClassStandard:           true,
+	StorageClassReducedRedundancy:  true,
+	StorageClassStandardIA:         true,
+	StorageClassOneZoneIA:          true,
+	StorageClassIntelligentTiering: true,
+	StorageClassGlacier:            true,
+	StorageClassGlacierIR:          true,
+	StorageClassDeepArchive:        true,
+}
+
+// setStorageClass sets the storage class header of h to class, unless h
+// already has one, and validates the resulting storage class.
+func setStorageClass(h http.Header, class string) error {
+	if h.Get(storageClassHeader) == "" && class != "" {
+		h.Set(storageClassHeader, class)
+	}
+	if c := h.Get(storageClassHeader); c != "" && !storageClasses[c] {
+		return fmt.Errorf("invalid storage class: %q", c)
+	}
+	return nil
+}
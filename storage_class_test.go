@@ -0,0 +1,50 @@
+package s3gof3r
+
+import (
+	"net/http"
+	"testing"
+)
+
+var storageClassTests = []struct {
+	config string
+	header string
+	expect string
+	valid  bool
+}{
+	{"", "", "", true},
+	{StorageClassStandardIA, "", "STANDARD_IA", true},
+	{StorageClassGlacier, "DEEP_ARCHIVE", "DEEP_ARCHIVE", true},
+	{"COLD", "", "", false},
+	{"", "standard", "", false},
+}
+
+func TestStorageClass(t *testing.T) {
+	s := newFakeS3()
+	b := newTestBucket(t, s)
+	for _, tt := range storageClassTests {
+		b.Config.StorageClass = tt.config
+		h := http.Header{}
+		if tt.header != "" {
+			h.Set(storageClassHeader, tt.header)
+		}
+		w, err := b.PutWriter("key", h)
+		if !tt.valid {
+			if err == nil {
+				t.Errorf("expected error for storage class %q %q", tt.config, tt.header)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		s.mu.Lock()
+		got := s.headers["/bucket/key"].Get(storageClassHeader)
+		s.mu.Unlock()
+		if got != tt.expect {
+			t.Errorf("Expected storage class: %q. Actual: %q", tt.expect, got)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package s3gof3r
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	streamingPayload       = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	streamingChunkPrefix   = "AWS4-HMAC-SHA256-PAYLOAD"
+	decodedLengthHeader    = "X-Amz-Decoded-Content-Length"
+	awsChunkedEncoding     = "aws-chunked"
+	streamingChunkSize     = 64 * kb
+	chunkSignatureLen      = 64 // hex sha256
+	chunkSignatureStartLen = len(";chunk-signature=")
+)
+
+// signStreaming signs req, whose body of n bytes is sent in the aws-chunked encoding
+// with STREAMING-AWS4-HMAC-SHA256-PAYLOAD: the headers are signed without hashing the
+// body, and each chunk of the body is signed as it's read, chaining the signature of
+// the previous chunk from the signature of the request.
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-streaming.html
+func (b *Bucket) signStreaming(req *http.Request, n int64) {
+	b.prepare(req)
+	b.signer(req).signStreaming(n, streamingChunkSize)
+}
+
+// signStreaming signs the request of s, of n bytes sent in chunks of size bytes
+func (s *signer) signStreaming(n, size int64) {
+	req := s.Request
+	encoding := awsChunkedEncoding
+	if ce := req.Header.Get("Content-Encoding"); ce != "" {
+		encoding += "," + ce // of the object, e.g. gzip
+	}
+	req.Header.Set("Content-Encoding", encoding)
+	req.Header.Set(decodedLengthHeader, strconv.FormatInt(n, 10))
+	req.Header.Set(sha256Header, streamingPayload)
+	req.ContentLength = awsChunkedLength(n, size)
+	req.Header.Set("Content-Length", strconv.FormatInt(req.ContentLength, 10)) // signed, not sent from the header
+	s.streaming = true
+	s.sign()
+	body := req.Body
+	if body == nil {
+		body = http.NoBody
+	}
+	req.Body = ioutil.NopCloser(newChunkedReader(body, s.chunkSigner(), size))
+	req.GetBody = nil
+}
+
+// awsChunkedLength returns the length of n bytes in the aws-chunked encoding with
+// chunks of size bytes, including the final empty chunk.
+func awsChunkedLength(n, size int64) int64 {
+	chunk := func(l int64) int64 {
+		return int64(len(strconv.FormatInt(l, 16))+chunkSignatureStartLen+chunkSignatureLen+2) + l + 2
+	}
+	total := n / size * chunk(size)
+	if r := n % size; r > 0 {
+		total += chunk(r)
+	}
+	return total + chunk(0)
+}
+
+// chunkSigner signs the chunks of a streaming payload
+type chunkSigner struct {
+	key   []byte
+	time  string
+	scope string
+	prev  string // signature of the previous chunk, or of the request
+}
+
+// chunkSigner returns the signer of the chunks of the payload of the request of s,
+// once signed
+func (s *signer) chunkSigner() *chunkSigner {
+	return &chunkSigner{
+		key:   s.signingKey(),
+		time:  s.Time.UTC().Format(isoFormat),
+		scope: s.credentialString,
+		prev:  s.signature,
+	}
+}
+
+var emptySHA256 = hex.EncodeToString(sha(nil))
+
+// sign returns the signature of the next chunk
+func (c *chunkSigner) sign(chunk []byte) string {
+	stringToSign := strings.Join([]string{
+		streamingChunkPrefix,
+		c.time,
+		c.scope,
+		c.prev,
+		emptySHA256,
+		hex.EncodeToString(sha(chunk)),
+	}, "\n")
+	c.prev = hex.EncodeToString(hmacSign(c.key, []byte(stringToSign)))
+	return c.prev
+}
+
+// chunkedReader encodes the data of r in the aws-chunked encoding: chunks of size bytes,
+// the last one shorter, each preceded by its length and signature, then an empty chunk.
+type chunkedReader struct {
+	r      io.Reader
+	signer *chunkSigner
+	buf    []byte
+	frame  bytes.Buffer // of the chunk being read
+	done   bool
+}
+
+func newChunkedReader(r io.Reader, signer *chunkSigner, size int64) *chunkedReader {
+	return &chunkedReader{r: r, signer: signer, buf: make([]byte, size)}
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	for c.frame.Len() == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+		n, err := io.ReadFull(c.r, c.buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+		chunk := c.buf[:n]
+		c.frame.Reset()
+		fmt.Fprintf(&c.frame, "%x;chunk-signature=%s\r\n", n, c.signer.sign(chunk))
+		c.frame.Write(chunk)
+		c.frame.WriteString("\r\n")
+		c.done = n == 0
+	}
+	return c.frame.Read(p)
+}
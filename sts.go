@@ -0,0 +1,177 @@
+package s3gof3r
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const stsVersion = "2011-06-15"
+
+// stsEndpoint returns the url of the STS service in region, or of the global
+// service if region is empty.
+var stsEndpoint = func(region string) string {
+	if region == "" {
+		return "https://sts.amazonaws.com/"
+	}
+	return "https://sts." + region + ".amazonaws.com/"
+}
+
+// AssumeRoleOptions are the optional parameters of AssumeRoleKeysWithOptions.
+type AssumeRoleOptions struct {
+	// ExternalID is required by the trust policies of some roles assumed by other accounts
+	ExternalID string
+	// Region of the STS endpoint, the global endpoint if empty
+	Region string
+}
+
+// AssumeRoleKeys returns temporary keys for the role with roleARN, such as a role of another
+// account, from an STS AssumeRole request signed with baseKeys. The keys are valid for
+// duration, or one hour if duration is zero, and are automatically renewed with a new
+// session shortly before they expire.
+func AssumeRoleKeys(baseKeys *Keys, roleARN, sessionName string, duration time.Duration) (*Keys, error) {
+	return AssumeRoleKeysWithOptions(baseKeys, roleARN, sessionName, duration, AssumeRoleOptions{})
+}
+
+// AssumeRoleKeysWithOptions is like AssumeRoleKeys, with an external ID or regional STS endpoint.
+func AssumeRoleKeysWithOptions(baseKeys *Keys, roleARN, sessionName string, duration time.Duration, opts AssumeRoleOptions) (keys *Keys, err error) {
+	v := url.Values{}
+	v.Set("Action", "AssumeRole")
+	v.Set("RoleArn", roleARN)
+	v.Set("RoleSessionName", sessionName)
+	if duration > 0 {
+		v.Set("DurationSeconds", strconv.Itoa(int(duration/time.Second)))
+	}
+	if opts.ExternalID != "" {
+		v.Set("ExternalId", opts.ExternalID)
+	}
+	assumeRole := func() (*Keys, error) {
+		return stsKeys(v, baseKeys, opts.Region)
+	}
+	if keys, err = assumeRole(); err != nil {
+		return nil, err
+	}
+	keys.refresh = assumeRole
+	return keys, nil
+}
+
+type stsCredentials struct {
+	AccessKeyID     string `xml:"AccessKeyId"`
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+}
+
+// stsKeys sends the STS request with the parameters in v to the endpoint of region and
+// returns the keys of its result. The request is signed with keys, if not nil.
+func stsKeys(v url.Values, keys *Keys, region string) (*Keys, error) {
+	v.Set("Version", stsVersion)
+	req, err := http.NewRequest("POST", stsEndpoint(region), strings.NewReader(v.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", userAgent)
+	if keys != nil {
+		if region == "" {
+			region = "us-east-1" // of the global endpoint
+		}
+		s := &signer{Time: time.Now(), Request: req, S3Config: &S3{Keys: keys}, Region: region, Service: "sts"}
+		s.sign()
+	}
+	resp, err := ClientWithTimeout(defaultClientTimeout).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer checkClose(resp.Body, err)
+	if resp.StatusCode != 200 {
+		return nil, newSTSError(resp)
+	}
+	var res struct {
+		Result struct {
+			Credentials stsCredentials
+		} `xml:",any"` // AssumeRoleResult, or the result of the action
+	}
+	if err = xml.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	c := res.Result.Credentials
+	if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		return nil, errors.New("no credentials in STS response")
+	}
+	return &Keys{
+		accessKeyID:     c.AccessKeyID,
+		secretAccessKey: c.SecretAccessKey,
+		sessionToken:    c.SessionToken,
+		expiration:      c.Expiration,
+	}, nil
+}
+
+// newSTSError returns the error of an STS response, whose code and message are nested
+// in an ErrorResponse unlike those of S3 errors.
+func newSTSError(r *http.Response) *RespError {
+	var res struct {
+		Error struct {
+			Code    string
+			Message string
+		}
+		RequestID string `xml:"RequestId"`
+	}
+	b, _ := ioutil.ReadAll(r.Body)
+	xml.NewDecoder(bytes.NewReader(b)).Decode(&res)
+	e := &RespError{
+		StatusCode: r.StatusCode,
+		Code:       res.Error.Code,
+		Message:    res.Error.Message,
+		RequestID:  res.RequestID,
+	}
+	if e.Message == "" {
+		e.Message = http.StatusText(e.StatusCode)
+	}
+	return e
+}
+
+// WebIdentityKeys returns temporary keys for the role in AWS_ROLE_ARN from an STS
+// AssumeRoleWithWebIdentity request with the token in AWS_WEB_IDENTITY_TOKEN_FILE, as
+// for IAM roles for service accounts on EKS. The session is named AWS_ROLE_SESSION_NAME,
+// if set, and the regional STS endpoint of AWS_REGION is used if set.
+//
+// The keys are automatically renewed shortly before they expire, reading the token
+// file again as the token is rotated.
+func WebIdentityKeys() (keys *Keys, err error) {
+	keys, err = webIdentityKeys()
+	if err != nil {
+		return
+	}
+	keys.refresh = webIdentityKeys
+	return
+}
+
+func webIdentityKeys() (*Keys, error) {
+	roleARN, tokenFile := os.Getenv("AWS_ROLE_ARN"), os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+	if roleARN == "" || tokenFile == "" {
+		return nil, errors.New("web identity not set in environment: AWS_ROLE_ARN, AWS_WEB_IDENTITY_TOKEN_FILE")
+	}
+	token, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return nil, err
+	}
+	session := os.Getenv("AWS_ROLE_SESSION_NAME")
+	if session == "" {
+		session = fmt.Sprintf("s3gof3r-%d", time.Now().UnixNano())
+	}
+	v := url.Values{}
+	v.Set("Action", "AssumeRoleWithWebIdentity")
+	v.Set("RoleArn", roleARN)
+	v.Set("RoleSessionName", session)
+	v.Set("WebIdentityToken", strings.TrimSpace(string(token)))
+	return stsKeys(v, nil, os.Getenv("AWS_REGION")) // the token authenticates the request
+}
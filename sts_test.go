@@ -0,0 +1,129 @@
+package s3gof3r
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestSTS serves STS requests with h, verifying the signatures of requests signed
+// with keys for region, until the test completes.
+func newTestSTS(t *testing.T, keys *Keys, region string, h http.HandlerFunc) {
+	t.Helper()
+	var handler http.Handler = h
+	if keys != nil {
+		handler = &sigV4Verifier{h: h, keys: keys, region: region, service: "sts"}
+	}
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	endpoint := stsEndpoint
+	stsEndpoint = func(string) string { return srv.URL + "/" }
+	t.Cleanup(func() { stsEndpoint = endpoint })
+}
+
+const stsCredentialsXML = `<Credentials><AccessKeyId>ASIA%d</AccessKeyId><SecretAccessKey>secret</SecretAccessKey>` +
+	`<SessionToken>token</SessionToken><Expiration>%s</Expiration></Credentials>`
+
+func TestAssumeRoleKeys(t *testing.T) {
+	base := &Keys{accessKeyID: "AKIDEXAMPLE", secretAccessKey: "base-secret"}
+	var mu sync.Mutex
+	var n int
+	newTestSTS(t, base, "eu-west-1", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		r.ParseForm()
+		expect := map[string]string{
+			"Action": "AssumeRole", "Version": stsVersion, "RoleArn": "arn:aws:iam::123456789012:role/demo",
+			"RoleSessionName": "session", "DurationSeconds": "900", "ExternalId": "ext",
+		}
+		for k, v := range expect {
+			if r.PostForm.Get(k) != v {
+				t.Errorf("Expected %s: %s. Actual: %s", k, v, r.PostForm.Get(k))
+			}
+		}
+		n++
+		// expiring within the refresh window, so the keys are renewed when used
+		exp := time.Now().Add(time.Minute).UTC().Format(time.RFC3339)
+		fmt.Fprintf(w, `<AssumeRoleResponse><AssumeRoleResult>`+stsCredentialsXML+`</AssumeRoleResult></AssumeRoleResponse>`, n, exp)
+	})
+
+	keys, err := AssumeRoleKeysWithOptions(base, "arn:aws:iam::123456789012:role/demo", "session", 15*time.Minute,
+		AssumeRoleOptions{ExternalID: "ext", Region: "eu-west-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys.AccessKeyID() != "ASIA1" || keys.SessionToken() != "token" || keys.Expiration().IsZero() {
+		t.Errorf("Unexpected keys: %s %s %v", keys.AccessKeyID(), keys.SessionToken(), keys.Expiration())
+	}
+	if id, _, _ := keys.Credentials(); id != "ASIA2" {
+		t.Errorf("Expected renewed keys: ASIA2. Actual: %s", id)
+	}
+}
+
+func TestAssumeRoleKeysError(t *testing.T) {
+	base := &Keys{accessKeyID: "AKIDEXAMPLE", secretAccessKey: "base-secret"}
+	newTestSTS(t, base, "us-east-1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(403)
+		fmt.Fprint(w, `<ErrorResponse><Error><Type>Sender</Type><Code>AccessDenied</Code>`+
+			`<Message>not authorized to perform: sts:AssumeRole</Message></Error><RequestId>req-id</RequestId></ErrorResponse>`)
+	})
+	_, err := AssumeRoleKeys(base, "arn:aws:iam::123456789012:role/demo", "session", 0)
+	rerr, ok := err.(*RespError)
+	if !ok || rerr.Code != "AccessDenied" || rerr.RequestID != "req-id" {
+		t.Errorf("Expected AccessDenied error. Actual: %v", err)
+	}
+}
+
+func TestWebIdentityKeys(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("jwt-1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_ROLE_ARN", "arn:aws:iam::123456789012:role/irsa")
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", tokenFile)
+	t.Setenv("AWS_ROLE_SESSION_NAME", "pod")
+	t.Setenv("AWS_REGION", "us-west-2")
+	var tokens []string
+	newTestSTS(t, nil, "", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Error("Expected unsigned request")
+		}
+		r.ParseForm()
+		if r.PostForm.Get("Action") != "AssumeRoleWithWebIdentity" || r.PostForm.Get("RoleArn") != "arn:aws:iam::123456789012:role/irsa" ||
+			r.PostForm.Get("RoleSessionName") != "pod" {
+			t.Errorf("Unexpected request: %v", r.PostForm)
+		}
+		tokens = append(tokens, r.PostForm.Get("WebIdentityToken"))
+		exp := time.Now().Add(time.Minute).UTC().Format(time.RFC3339)
+		fmt.Fprintf(w, `<AssumeRoleWithWebIdentityResponse><AssumeRoleWithWebIdentityResult>`+stsCredentialsXML+
+			`</AssumeRoleWithWebIdentityResult></AssumeRoleWithWebIdentityResponse>`, len(tokens), exp)
+	})
+
+	keys, err := WebIdentityKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys.AccessKeyID() != "ASIA1" {
+		t.Errorf("Expected access key ID: ASIA1. Actual: %s", keys.AccessKeyID())
+	}
+	// the rotated token is read when the keys are renewed
+	if err := ioutil.WriteFile(tokenFile, []byte("jwt-2"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if id, _, _ := keys.Credentials(); id != "ASIA2" {
+		t.Errorf("Expected renewed keys: ASIA2. Actual: %s", id)
+	}
+	if fmt.Sprint(tokens) != "[jwt-1 jwt-2]" {
+		t.Errorf("Expected tokens: [jwt-1 jwt-2]. Actual: %v", tokens)
+	}
+
+	t.Setenv("AWS_ROLE_ARN", "")
+	if _, err := WebIdentityKeys(); err == nil {
+		t.Error("Expected error without AWS_ROLE_ARN")
+	}
+}
@@ -0,0 +1,138 @@
+package s3gof3r
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"unicode/utf8"
+)
+
+// limits of object tags, defined by amazon
+const (
+	maxTags           = 10
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+	taggingHeader     = "X-Amz-Tagging"
+)
+
+type tag struct {
+	Key   string
+	Value string
+}
+
+type tagging struct {
+	XMLName xml.Name `xml:"Tagging"`
+	TagSet  []tag    `xml:"TagSet>Tag"`
+}
+
+// validateTags checks tags against the limits of S3 object tagging.
+func validateTags(tags map[string]string) error {
+	if len(tags) > maxTags {
+		return fmt.Errorf("too many tags: %d, the maximum is %d", len(tags), maxTags)
+	}
+	for k, v := range tags {
+		if k == "" || utf8.RuneCountInString(k) > maxTagKeyLength {
+			return fmt.Errorf("tag key %q must be 1 to %d characters", k, maxTagKeyLength)
+		}
+		if utf8.RuneCountInString(v) > maxTagValueLength {
+			return fmt.Errorf("value of tag %q must be at most %d characters", k, maxTagValueLength)
+		}
+	}
+	return nil
+}
+
+// encodeTags returns tags encoded for the x-amz-tagging header, as url query parameters.
+func encodeTags(tags map[string]string) string {
+	v := make(url.Values, len(tags))
+	for k, t := range tags {
+		v.Set(k, t)
+	}
+	return v.Encode()
+}
+
+// PutWriterWithTags is like PutWriter but tags the object with tags on upload.
+// At most 10 tags are allowed, with keys of up to 128 and values of up to 256 characters.
+func (b *Bucket) PutWriterWithTags(path string, h http.Header, tags map[string]string) (w io.WriteCloser, err error) {
+	if err = validateTags(tags); err != nil {
+		return nil, err
+	}
+	h = cloneHeader(h)
+	if len(tags) > 0 {
+		h.Set(taggingHeader, encodeTags(tags))
+	}
+	return b.PutWriter(path, h)
+}
+
+// taggingURL returns the url of the tagging subresource of the object at path
+func (b *Bucket) taggingURL(path string) (*url.URL, error) {
+	u, err := b.url(path)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("tagging", "")
+	u.RawQuery = q.Encode()
+	return u, nil
+}
+
+// GetObjectTagging returns the tags of the object at path.
+// As with GetReader, a version ID may be included in the path as a url parameter.
+func (b *Bucket) GetObjectTagging(path string) (tags map[string]string, err error) {
+	u, err := b.taggingURL(path)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.retryRequest("GET", u, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer checkClose(resp.Body, err)
+	if resp.StatusCode != 200 {
+		return nil, newRespError(resp)
+	}
+	var t tagging
+	if err = xml.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return nil, err
+	}
+	tags = make(map[string]string, len(t.TagSet))
+	for _, tg := range t.TagSet {
+		tags[tg.Key] = tg.Value
+	}
+	return tags, nil
+}
+
+// PutObjectTagging replaces the tags of the object at path with tags.
+// As with GetReader, a version ID may be included in the path as a url parameter.
+func (b *Bucket) PutObjectTagging(path string, tags map[string]string) (err error) {
+	if err = validateTags(tags); err != nil {
+		return err
+	}
+	u, err := b.taggingURL(path)
+	if err != nil {
+		return err
+	}
+	t := tagging{TagSet: make([]tag, 0, len(tags))}
+	for k, v := range tags {
+		t.TagSet = append(t.TagSet, tag{k, v})
+	}
+	body, err := xml.Marshal(t)
+	if err != nil {
+		return err
+	}
+	sum := md5.Sum(body)
+	h := http.Header{}
+	h.Set(md5Header, base64.StdEncoding.EncodeToString(sum[:]))
+	resp, err := b.retryRequest("PUT", u, body, h)
+	if err != nil {
+		return err
+	}
+	defer checkClose(resp.Body, err)
+	if resp.StatusCode != 200 {
+		return newRespError(resp)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package s3gof3r
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var validateTagsTests = []struct {
+	tags  map[string]string
+	valid bool
+}{
+	{nil, true},
+	{map[string]string{"project": "s3gof3r", "cost center": "a&b=c"}, true},
+	{map[string]string{"": "empty"}, false},
+	{map[string]string{strings.Repeat("k", 128): strings.Repeat("v", 256)}, true},
+	{map[string]string{strings.Repeat("k", 129): "v"}, false},
+	{map[string]string{"k": strings.Repeat("v", 257)}, false},
+	{map[string]string{"1": "", "2": "", "3": "", "4": "", "5": "", "6": "", "7": "", "8": "", "9": "", "10": "", "11": ""}, false},
+}
+
+func TestValidateTags(t *testing.T) {
+	for _, tt := range validateTagsTests {
+		err := validateTags(tt.tags)
+		if (err == nil) != tt.valid {
+			t.Errorf("validateTags(%v): expected valid %v, got error %v", tt.tags, tt.valid, err)
+		}
+	}
+}
+
+func TestObjectTagging(t *testing.T) {
+	s := newFakeS3()
+	b := newTestBucket(t, s)
+	tags := map[string]string{"project": "s3gof3r", "cost center": "a&b=c"}
+
+	w, err := b.PutWriterWithTags("key", nil, tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	v, err := url.ParseQuery(s.headers["/bucket/key"].Get(taggingHeader))
+	s.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Get("project") != "s3gof3r" || v.Get("cost center") != "a&b=c" {
+		t.Errorf("unexpected tagging header: %v", v)
+	}
+
+	tags["project"] = "gof3r"
+	if err := b.PutObjectTagging("key", tags); err != nil {
+		t.Fatal(err)
+	}
+	got, err := b.GetObjectTagging("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, tags) {
+		t.Errorf("Expected tags: %v. Actual: %v", tags, got)
+	}
+
+	if _, err := b.GetObjectTagging("missing"); err == nil {
+		t.Error("expected error for tags of missing object")
+	}
+	if _, err := b.PutWriterWithTags("key", nil, map[string]string{"": "v"}); err == nil {
+		t.Error("expected error for invalid tags")
+	}
+}
@@ -0,0 +1,358 @@
+package s3gof3r
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestBucket returns a path style bucket named "bucket" for a fake S3 service
+// served by h. The server is closed when the test completes.
+func newTestBucket(t testing.TB, h http.Handler) *Bucket {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	t.Setenv("AWS_REGION", "us-east-1")
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := &Keys{
+		accessKeyID:     "AKIDEXAMPLE",
+		secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	b, _ := NewBucket(New(u.Host, keys), "bucket", &Config{
+		Concurrency: 2,
+		PartSize:    5 * mb,
+		NTry:        2,
+		Scheme:      "http",
+		PathStyle:   true,
+		Client:      ClientWithTimeout(5 * time.Second),
+	})
+	return b
+}
+
+// fakeS3 is an in-memory S3 service supporting the object and multipart upload
+// requests made by getters and putters of path style buckets.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte         // keyed by path
+	headers map[string]http.Header    // request headers of the put of each object
+	uploads map[string]map[int][]byte // parts keyed by upload ID
+	tagging map[string][]byte         // tagging documents keyed by path
+	nextID  int
+
+	// checksums of each part of uploads and of each object, for checksum algorithms
+	algs      map[string]string // checksum algorithm of uploads
+	partSums  map[string]map[int]string
+	checksums map[string]fakeChecksum
+
+	// check, if set, is called with each request before it is served
+	check func(r *http.Request)
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{
+		objects: make(map[string][]byte),
+		headers: make(map[string]http.Header),
+		uploads: make(map[string]map[int][]byte),
+		tagging: make(map[string][]byte),
+
+		algs:      make(map[string]string),
+		partSums:  make(map[string]map[int]string),
+		checksums: make(map[string]fakeChecksum),
+	}
+}
+
+type fakeChecksum struct {
+	alg   string
+	value string // composite of the parts of multipart objects
+	parts []objectPart
+}
+
+func (s *fakeS3) object(path string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.objects[path]
+	return b, ok
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.check != nil {
+		s.check(r)
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	// objects are served after unlocking, as the client may not read them to the end
+	if obj, ok := s.handle(w, r, body); ok {
+		// ServeContent omits the Content-Length of responses with a Content-Encoding
+		ew := &encodingWriter{ResponseWriter: w, encoding: w.Header().Get("Content-Encoding")}
+		w.Header().Del("Content-Encoding")
+		http.ServeContent(ew, r, r.URL.Path, time.Time{}, bytes.NewReader(obj))
+	}
+}
+
+// encodingWriter sets the Content-Encoding of the response when its header is written
+type encodingWriter struct {
+	http.ResponseWriter
+	encoding string
+}
+
+func (w *encodingWriter) WriteHeader(status int) {
+	if w.encoding != "" {
+		w.Header().Set("Content-Encoding", w.encoding)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// handle serves r, or returns the object to serve for gets
+func (s *fakeS3) handle(w http.ResponseWriter, r *http.Request, body []byte) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := r.URL.Query()
+	p := r.URL.Path
+	switch {
+	case r.Method == "PUT" && q.Has("tagging"):
+		s.tagging[p] = body
+	case r.Method == "GET" && q.Has("tagging"):
+		if _, ok := s.objects[p]; !ok {
+			http.Error(w, "NoSuchKey", 404)
+			return nil, false
+		}
+		w.Write(s.tagging[p])
+	case r.Method == "POST" && q.Has("delete"):
+		var req deleteRequest
+		if err := xml.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), 400)
+			return nil, false
+		}
+		if len(req.Objects) > maxDeleteKeys {
+			http.Error(w, "MalformedXML", 400)
+			return nil, false
+		}
+		var res DeleteResult
+		for _, o := range req.Objects {
+			delete(s.objects, p+"/"+o.Key)
+			res.Deleted = append(res.Deleted, DeletedObject{Key: o.Key})
+		}
+		xml.NewEncoder(w).Encode(res)
+	case r.Method == "POST" && q.Has("uploads"):
+		s.nextID++
+		id := fmt.Sprintf("upload-%d", s.nextID)
+		s.uploads[id] = make(map[int][]byte)
+		s.headers[p] = r.Header
+		s.algs[id] = r.Header.Get(checksumAlgorithmHeader)
+		s.partSums[id] = make(map[int]string)
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, id)
+	case r.Method == "PUT" && q.Get("partNumber") != "":
+		parts, ok := s.uploads[q.Get("uploadId")]
+		if !ok {
+			http.Error(w, "NoSuchUpload", 404)
+			return nil, false
+		}
+		n, _ := strconv.Atoi(q.Get("partNumber"))
+		sum, ok := checkChecksum(w, r, body)
+		if !ok {
+			return nil, false
+		}
+		parts[n] = body
+		s.partSums[q.Get("uploadId")][n] = sum
+		w.Header().Set("ETag", fmt.Sprintf(`"%s"`, s.etag(r, body)))
+	case r.Method == "GET" && q.Get("uploadId") != "":
+		parts, ok := s.uploads[q.Get("uploadId")]
+		if !ok {
+			http.Error(w, "NoSuchUpload", 404)
+			return nil, false
+		}
+		fmt.Fprint(w, `<ListPartsResult><IsTruncated>false</IsTruncated>`)
+		for i := 1; i <= len(parts); i++ {
+			fmt.Fprintf(w, `<Part><PartNumber>%d</PartNumber><ETag>"%s"</ETag><Size>%d</Size></Part>`,
+				i, s.etag(r, parts[i]), len(parts[i]))
+		}
+		fmt.Fprint(w, `</ListPartsResult>`)
+	case r.Method == "POST" && q.Get("uploadId") != "":
+		parts, ok := s.uploads[q.Get("uploadId")]
+		if !ok {
+			http.Error(w, "NoSuchUpload", 404)
+			return nil, false
+		}
+		var obj, sums []byte
+		for i := 1; i <= len(parts); i++ {
+			obj = append(obj, parts[i]...)
+			sum := md5.Sum(parts[i])
+			sums = append(sums, sum[:]...)
+		}
+		if alg := s.algs[q.Get("uploadId")]; alg != "" {
+			h, _ := newChecksumHash(alg)
+			c := fakeChecksum{alg: alg}
+			for i := 1; i <= len(parts); i++ {
+				sum := s.partSums[q.Get("uploadId")][i]
+				b, _ := base64.StdEncoding.DecodeString(sum)
+				h.Write(b)
+				op := objectPart{PartNumber: i, Size: int64(len(parts[i]))}
+				switch alg {
+				case ChecksumCRC32:
+					op.ChecksumCRC32 = sum
+				case ChecksumCRC32C:
+					op.ChecksumCRC32C = sum
+				case ChecksumSHA1:
+					op.ChecksumSHA1 = sum
+				default:
+					op.ChecksumSHA256 = sum
+				}
+				c.parts = append(c.parts, op)
+			}
+			c.value = fmt.Sprintf("%s-%d", base64.StdEncoding.EncodeToString(h.Sum(nil)), len(parts))
+			s.checksums[p] = c
+		}
+		if !s.precondition(w, r, p) {
+			return nil, false
+		}
+		delete(s.uploads, q.Get("uploadId"))
+		s.objects[p] = obj
+		s.nextID++
+		w.Header().Set(versionIDHeader, fmt.Sprintf("version-%d", s.nextID))
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><ETag>"%s-%d"</ETag></CompleteMultipartUploadResult>`,
+			s.etag(r, sums), len(parts))
+	case r.Method == "DELETE" && q.Get("uploadId") != "":
+		delete(s.uploads, q.Get("uploadId"))
+		w.WriteHeader(204)
+	case r.Method == "PUT" && r.Header.Get(copySourceHeader) != "":
+		src, _ := url.PathUnescape(strings.Split(r.Header.Get(copySourceHeader), "?")[0])
+		obj, ok := s.objects[src]
+		if !ok {
+			http.Error(w, "NoSuchKey", 404)
+			return nil, false
+		}
+		s.objects[p] = obj
+		s.headers[p] = s.headers[src]
+		fmt.Fprintf(w, `<CopyObjectResult><ETag>"%x"</ETag></CopyObjectResult>`, md5.Sum(obj))
+	case r.Method == "PUT":
+		sum, ok := checkChecksum(w, r, body)
+		if !ok || !s.precondition(w, r, p) {
+			return nil, false
+		}
+		for _, alg := range []string{ChecksumCRC32, ChecksumCRC32C, ChecksumSHA1, ChecksumSHA256} {
+			if r.Header.Get(checksumHeader(alg)) != "" {
+				s.checksums[p] = fakeChecksum{alg: alg, value: sum}
+			}
+		}
+		s.objects[p] = body
+		s.headers[p] = r.Header
+		s.nextID++
+		w.Header().Set(versionIDHeader, fmt.Sprintf("version-%d", s.nextID))
+		w.Header().Set("ETag", fmt.Sprintf(`"%s"`, s.etag(r, body)))
+	case r.Method == "GET" && q.Get("list-type") == "2":
+		s.list(w, p, q)
+	case r.Method == "GET" && q.Has("attributes"):
+		c, ok := s.checksums[p]
+		if !ok {
+			http.Error(w, "NoSuchKey", 404)
+			return nil, false
+		}
+		var attrs objectAttributes
+		attrs.ObjectParts.Parts = c.parts
+		xml.NewEncoder(w).Encode(struct {
+			XMLName xml.Name `xml:"GetObjectAttributesResponse"`
+			objectAttributes
+		}{objectAttributes: attrs})
+	case r.Method == "GET" || r.Method == "HEAD":
+		obj, ok := s.objects[p]
+		if !ok {
+			http.Error(w, "NoSuchKey", 404)
+			return nil, false
+		}
+		if c, ok := s.checksums[p]; ok && r.Header.Get(checksumModeHeader) == "ENABLED" && r.Header.Get("Range") == "" {
+			w.Header().Set(checksumHeader(c.alg), c.value)
+		}
+		w.Header().Set("ETag", fmt.Sprintf(`"%x"`, md5.Sum(obj)))
+		copyMetadata(w.Header(), s.headers[p]) // the content headers and metadata of the put
+		return obj, true
+	case r.Method == "DELETE":
+		delete(s.objects, p)
+		w.WriteHeader(204)
+	default:
+		http.Error(w, "unexpected request", 400)
+	}
+	return nil, false
+}
+
+// list writes a ListObjectsV2 result for the bucket at path, with the key following
+// the last one listed as the continuation token.
+func (s *fakeS3) list(w http.ResponseWriter, path string, q url.Values) {
+	var keys []string
+	for k := range s.objects {
+		if key := strings.TrimPrefix(k, path+"/"); strings.HasPrefix(key, q.Get("prefix")) && key >= q.Get("continuation-token") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var res listBucketResult
+	if n, _ := strconv.Atoi(q.Get("max-keys")); n > 0 && len(keys) > n {
+		res.IsTruncated, res.NextContinuationToken = true, keys[n]
+		keys = keys[:n]
+	}
+	for _, key := range keys {
+		res.Contents = append(res.Contents, listBucketContents{Key: key, Size: int64(len(s.objects[path+"/"+key]))})
+	}
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"ListBucketResult"`
+		listBucketResult
+	}{listBucketResult: res})
+}
+
+// precondition checks the If-Match and If-None-Match headers of a write of the object at
+// path. A PreconditionFailed error is written if they are not met.
+func (s *fakeS3) precondition(w http.ResponseWriter, r *http.Request, path string) bool {
+	obj, ok := s.objects[path]
+	m, nm := r.Header.Get("If-Match"), r.Header.Get("If-None-Match")
+	if (nm == "*" && ok) || (m != "" && (!ok || m != fmt.Sprintf(`"%x"`, md5.Sum(obj)))) {
+		w.WriteHeader(412)
+		fmt.Fprint(w, `<Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message></Error>`)
+		return false
+	}
+	return true
+}
+
+// checkChecksum verifies the checksum header of r for body, if any, and returns it.
+// A BadDigest error is written for a mismatch.
+func checkChecksum(w http.ResponseWriter, r *http.Request, body []byte) (sum string, ok bool) {
+	for _, alg := range []string{ChecksumCRC32, ChecksumCRC32C, ChecksumSHA1, ChecksumSHA256} {
+		v := r.Header.Get(checksumHeader(alg))
+		if v == "" {
+			continue
+		}
+		h, _ := newChecksumHash(alg)
+		h.Write(body)
+		if base64.StdEncoding.EncodeToString(h.Sum(nil)) != v {
+			http.Error(w, "BadDigest", 400)
+			return "", false
+		}
+		return v, true
+	}
+	return "", true
+}
+
+// etag returns the hex md5 of b, or a digest of the key for SSE-C requests as
+// their ETags are not the md5 of the data, like S3.
+func (s *fakeS3) etag(r *http.Request, b []byte) string {
+	if key := r.Header.Get(sseCustomerKeyHeader); key != "" {
+		return fmt.Sprintf("%x", md5.Sum(append([]byte(key), b...)))
+	}
+	return fmt.Sprintf("%x", md5.Sum(b))
+}
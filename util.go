@@ -2,12 +2,14 @@ package s3gof3r
 
 import (
 	"bytes"
+	"context"
 
 	"encoding/xml"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"time"
 )
 
 // convenience multipliers
@@ -52,12 +54,21 @@ func max64(a, b int64) int64 {
 
 // RespError representbs an http error response
 // http://docs.aws.amazon.com/AmazonS3/latest/API/ErrorResponses.html
+//
+// Errors returned by s3gof3r may wrap a *RespError, use errors.As to get it:
+//
+//	var rerr *s3gof3r.RespError
+//	if errors.As(err, &rerr) && rerr.Code == "SlowDown" {
 type RespError struct {
 	Code       string
 	Message    string
 	Resource   string
 	RequestID  string `xml:"RequestId"`
+	HostID     string `xml:"HostId"`
 	StatusCode int
+
+	retryAfter          time.Duration // of the Retry-After header, if any
+	expectedBucketOwner bool          // the request had an expected bucket owner
 }
 
 func newRespError(r *http.Response) *RespError {
@@ -66,15 +77,29 @@ func newRespError(r *http.Response) *RespError {
 	b, _ := ioutil.ReadAll(r.Body)
 	xml.NewDecoder(bytes.NewReader(b)).Decode(e) // parse error from response
 	r.Body.Close()
+	if e.Message == "" { // e.g. HEAD responses have no body
+		e.Message = http.StatusText(e.StatusCode)
+	}
+	if e.RequestID == "" {
+		e.RequestID = r.Header.Get("X-Amz-Request-Id")
+	}
+	if e.HostID == "" {
+		e.HostID = r.Header.Get("X-Amz-Id-2")
+	}
+	e.retryAfter = parseRetryAfter(r.Header.Get("Retry-After"), time.Now())
+	e.expectedBucketOwner = r.Request != nil && r.Request.Header.Get(expectedBucketOwnerHeader) != ""
 	return e
 }
 
 func (e *RespError) Error() string {
-	return fmt.Sprintf(
-		"%d: %q",
-		e.StatusCode,
-		e.Message,
-	)
+	s := fmt.Sprintf("%d: %q", e.StatusCode, e.Message)
+	if e.Code != "" {
+		s = fmt.Sprintf("%d %s: %q", e.StatusCode, e.Code, e.Message)
+	}
+	if e.RequestID != "" {
+		s += fmt.Sprintf(" (request id: %s)", e.RequestID)
+	}
+	return s
 }
 
 func checkClose(c io.Closer, err error) {
@@ -86,3 +111,13 @@ func checkClose(c io.Closer, err error) {
 	}
 
 }
+
+// sleep pauses for d or until ctx is done, whichever comes first.
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,119 @@
+package s3gof3r
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Versioning statuses of buckets
+const (
+	VersioningEnabled   = "Enabled"
+	VersioningSuspended = "Suspended"
+)
+
+// GetVersioningStatus returns the versioning status of the bucket: VersioningEnabled,
+// VersioningSuspended, or "" if versioning was never enabled on the bucket. Objects
+// deleted from a bucket with versioning enabled or suspended get a delete marker, and
+// their versions are kept until deleted with DeleteVersion.
+func (b *Bucket) GetVersioningStatus() (string, error) {
+	var v struct {
+		XMLName xml.Name `xml:"VersioningConfiguration"`
+		Status  string
+	}
+	if err := b.getSubresource("", "versioning", &v); err != nil {
+		return "", err
+	}
+	return v.Status, nil
+}
+
+// ObjectVersion is a version of an object, or a delete marker, in a versioned bucket.
+type ObjectVersion struct {
+	Key          string
+	VersionID    string `xml:"VersionId"`
+	IsLatest     bool
+	LastModified time.Time
+	ETag         string // without surrounding quotes
+	Size         int64
+	StorageClass string
+
+	// DeleteMarker is set for the delete markers of deleted objects, which have no
+	// ETag, size or storage class.
+	DeleteMarker bool `xml:"-"`
+}
+
+// versionEntry is a Version or DeleteMarker element of a version list
+type versionEntry struct {
+	XMLName xml.Name
+	ObjectVersion
+}
+
+type listVersionsResult struct {
+	XMLName             xml.Name `xml:"ListVersionsResult"`
+	IsTruncated         bool
+	NextKeyMarker       string
+	NextVersionIDMarker string `xml:"NextVersionIdMarker"`
+	// the versions and delete markers, in the order listed, with the other elements of the result
+	Entries []versionEntry `xml:",any"`
+}
+
+// ListVersions returns the versions and delete markers of objects with keys beginning
+// with prefix, following continuations until every version is listed. They are ordered
+// by key, and from the latest to the oldest version of each key.
+//
+// A version may be got with GetReader, or deleted with DeleteVersion, by its VersionID.
+func (b *Bucket) ListVersions(prefix string) (versions []ObjectVersion, err error) {
+	u, err := b.url("")
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{}
+	q.Set("versions", "")
+	if prefix != "" {
+		q.Set("prefix", prefix)
+	}
+	for {
+		u.RawQuery = q.Encode()
+		res, err := b.listVersions(u)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range res.Entries {
+			switch e.XMLName.Local {
+			case "Version":
+			case "DeleteMarker":
+				e.DeleteMarker = true
+			default:
+				continue
+			}
+			e.ETag = strings.Trim(e.ETag, `"`)
+			versions = append(versions, e.ObjectVersion)
+		}
+		if !res.IsTruncated {
+			return versions, nil
+		}
+		if res.NextKeyMarker == "" && res.NextVersionIDMarker == "" {
+			return nil, errors.New("truncated version list without a next marker")
+		}
+		q.Set("key-marker", res.NextKeyMarker)
+		q.Set("version-id-marker", res.NextVersionIDMarker)
+	}
+}
+
+func (b *Bucket) listVersions(u *url.URL) (res *listVersionsResult, err error) {
+	resp, err := b.retryRequest("GET", u, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer checkClose(resp.Body, err)
+	if resp.StatusCode != 200 {
+		return nil, newRespError(resp)
+	}
+	res = new(listVersionsResult)
+	if err = xml.NewDecoder(resp.Body).Decode(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
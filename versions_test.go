@@ -0,0 +1,121 @@
+package s3gof3r
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestListVersions(t *testing.T) {
+	pages := []string{
+		`<ListVersionsResult>
+			<Name>bucket</Name><Prefix>a/</Prefix><KeyMarker></KeyMarker><MaxKeys>1000</MaxKeys>
+			<IsTruncated>true</IsTruncated>
+			<NextKeyMarker>a/1</NextKeyMarker>
+			<NextVersionIdMarker>v2</NextVersionIdMarker>
+			<DeleteMarker><Key>a/1</Key><VersionId>v3</VersionId><IsLatest>true</IsLatest><LastModified>2024-01-03T03:04:05.000Z</LastModified></DeleteMarker>
+			<Version><Key>a/1</Key><VersionId>v2</VersionId><IsLatest>false</IsLatest><LastModified>2024-01-02T03:04:05.000Z</LastModified><ETag>"abc"</ETag><Size>10</Size><StorageClass>STANDARD</StorageClass></Version>
+		</ListVersionsResult>`,
+		`<ListVersionsResult>
+			<IsTruncated>false</IsTruncated>
+			<Version><Key>a/1</Key><VersionId>v1</VersionId><IsLatest>false</IsLatest><LastModified>2024-01-01T03:04:05.000Z</LastModified><ETag>"def"</ETag><Size>5</Size></Version>
+		</ListVersionsResult>`,
+	}
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.Method != "GET" || r.URL.Path != "/bucket" || !q.Has("versions") {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			return
+		}
+		if q.Get("prefix") != "a/" {
+			t.Errorf("expected prefix a/, got %q", q.Get("prefix"))
+		}
+		if q.Get("key-marker") == "" {
+			fmt.Fprint(w, pages[0])
+			return
+		}
+		if q.Get("key-marker") != "a/1" || q.Get("version-id-marker") != "v2" {
+			t.Errorf("unexpected markers: %v", q)
+		}
+		fmt.Fprint(w, pages[1])
+	}))
+
+	versions, err := b.ListVersions("a/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []ObjectVersion{
+		{Key: "a/1", VersionID: "v3", IsLatest: true, LastModified: time.Date(2024, 1, 3, 3, 4, 5, 0, time.UTC), DeleteMarker: true},
+		{Key: "a/1", VersionID: "v2", LastModified: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), ETag: "abc", Size: 10, StorageClass: "STANDARD"},
+		{Key: "a/1", VersionID: "v1", LastModified: time.Date(2024, 1, 1, 3, 4, 5, 0, time.UTC), ETag: "def", Size: 5},
+	}
+	if len(versions) != len(expect) {
+		t.Fatalf("expected %d versions, got %d: %+v", len(expect), len(versions), versions)
+	}
+	for i, v := range versions {
+		if v != expect[i] {
+			t.Errorf("version %d: expected %+v, got %+v", i, expect[i], v)
+		}
+	}
+}
+
+func TestDeleteVersion(t *testing.T) {
+	var deletes []string
+	b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "DELETE" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			return
+		}
+		deletes = append(deletes, r.URL.RequestURI())
+		w.Header().Set("x-amz-version-id", r.URL.Query().Get("versionId"))
+		w.Header().Set("x-amz-delete-marker", "true")
+		w.WriteHeader(204)
+	}))
+	b.Config.Md5Check = true
+
+	d, err := b.DeleteVersion("a/1", "v3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d != (DeletedObject{Key: "a/1", VersionId: "v3", DeleteMarker: true}) {
+		t.Errorf("unexpected result: %+v", d)
+	}
+	if err := b.Delete("a/1?versionId=v2"); err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"/bucket/a/1?versionId=v3", "/bucket/a/1?versionId=v2"}
+	if fmt.Sprint(deletes) != fmt.Sprint(expect) {
+		t.Errorf("expected deletes %v, got %v", expect, deletes)
+	}
+	if _, err := b.DeleteVersion("a/1", ""); err == nil {
+		t.Error("expected error for empty version ID")
+	}
+}
+
+var versioningStatusTests = []struct {
+	body   string
+	expect string
+}{
+	{`<VersioningConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Status>Enabled</Status><MfaDelete>Disabled</MfaDelete></VersioningConfiguration>`, VersioningEnabled},
+	{`<VersioningConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Status>Suspended</Status></VersioningConfiguration>`, VersioningSuspended},
+	{`<VersioningConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"/>`, ""},
+}
+
+func TestGetVersioningStatus(t *testing.T) {
+	for _, tt := range versioningStatusTests {
+		b := newTestBucket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "GET" || r.URL.Path != "/bucket" || !r.URL.Query().Has("versioning") {
+				t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			}
+			fmt.Fprint(w, tt.body)
+		}))
+		status, err := b.GetVersioningStatus()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status != tt.expect {
+			t.Errorf("Expected status: %q. Actual: %q", tt.expect, status)
+		}
+	}
+}